@@ -0,0 +1,237 @@
+package gameboy
+
+// Audio Processing Unit. Produces mono samples at SAMPLE_RATE from the four
+// channels: two square waves, the custom wave and noise.
+// https://gbdev.io/pandocs/Audio.html
+//
+// Not implemented: channel 1's frequency sweep, and stereo panning (a channel
+// is heard if NR51 routes it to either side).
+
+const SAMPLE_RATE = 44100
+
+const (
+	ADDR_NR10 = 0xFF10 // first of the sound registers, through 0xFF26
+	ADDR_NR50 = 0xFF24 // master volume
+	ADDR_NR51 = 0xFF25 // panning
+	ADDR_NR52 = 0xFF26 // master control
+	ADDR_WAVE = 0xFF30 // wave pattern RAM, 16 bytes
+)
+
+// Samples are dropped once this many are waiting to be read
+const MAX_SAMPLES = SAMPLE_RATE
+
+var dutyCycles = [4][8]uint8{
+	{0, 0, 0, 0, 0, 0, 0, 1}, // 12.5%
+	{1, 0, 0, 0, 0, 0, 0, 1}, // 25%
+	{1, 0, 0, 0, 0, 1, 1, 1}, // 50%
+	{0, 1, 1, 1, 1, 1, 1, 0}, // 75%
+}
+
+var noiseDivisors = [8]int{8, 16, 32, 48, 64, 80, 96, 112}
+
+type channel struct {
+	enabled  bool
+	timer    int // cycles until the waveform advances
+	pos      int // position in the waveform
+	volume   uint8
+	envTimer int
+	length   int
+	lfsr     uint16
+}
+
+type APU struct {
+	prev int // cpu cycles at the previous step
+
+	// the frame sequencer clocks length counters at 256Hz and envelopes at
+	// 64Hz, and ticks every 8192 cycles (512Hz)
+	seqTimer int
+	seqStep  int
+
+	// cycles times SAMPLE_RATE since the last sample
+	sampleTimer int
+
+	ch      [4]channel
+	samples []int16
+}
+
+// Returns and clears the samples produced so far.
+func (a *APU) Samples() []int16 {
+	s := a.samples
+	a.samples = nil
+	return s
+}
+
+// register n (0-4) of channel i, e.g. reg(mem, 1, 2) is NR22
+func reg(mem *Memory, i, n int) uint8 {
+	return mem.data[ADDR_NR10+i*5+n]
+}
+
+func (a *APU) Step(cpu *CPU) {
+	mem := cpu.Mem
+	delta := cpu.Cycles - a.prev
+	a.prev = cpu.Cycles
+
+	for i := range a.ch {
+		if mem.apuTrigger&(1<<i) != 0 {
+			a.trigger(mem, i)
+		}
+	}
+	mem.apuTrigger = 0
+
+	if mem.data[ADDR_NR52]&0x80 == 0 { // powered off
+		for i := range a.ch {
+			a.ch[i].enabled = false
+		}
+	}
+
+	for a.seqTimer += delta; a.seqTimer >= 8192; a.seqTimer -= 8192 {
+		a.frameSequencer(mem)
+	}
+	for i := range a.ch {
+		a.clock(mem, i, delta)
+	}
+
+	// channel status bits in NR52
+	status := mem.data[ADDR_NR52] & 0xF0
+	for i, ch := range a.ch {
+		if ch.enabled {
+			status |= 1 << i
+		}
+	}
+	mem.data[ADDR_NR52] = status
+
+	for a.sampleTimer += delta * SAMPLE_RATE; a.sampleTimer >= CPU_FREQUENCY; a.sampleTimer -= CPU_FREQUENCY {
+		a.samples = append(a.samples, a.mix(mem))
+		if len(a.samples) > MAX_SAMPLES {
+			a.samples = a.samples[len(a.samples)-MAX_SAMPLES:]
+		}
+	}
+}
+
+// Cycles between waveform steps
+func (a *APU) period(mem *Memory, i int) int {
+	freq := int(reg(mem, i, 3)) | int(reg(mem, i, 4)&0x07)<<8
+	switch i {
+	case 0, 1:
+		return (2048 - freq) * 4
+	case 2:
+		return (2048 - freq) * 2
+	default:
+		nr43 := reg(mem, 3, 3)
+		return noiseDivisors[nr43&0x07] << (nr43 >> 4)
+	}
+}
+
+// Whether the DAC of channel i is on. A channel with its DAC off is silent.
+func dacOn(mem *Memory, i int) bool {
+	if i == 2 {
+		return reg(mem, 2, 0)&0x80 != 0
+	}
+	return reg(mem, i, 2)&0xF8 != 0
+}
+
+// Writing bit 7 of NRx4 (re)starts a channel.
+func (a *APU) trigger(mem *Memory, i int) {
+	ch := &a.ch[i]
+	ch.enabled = dacOn(mem, i)
+	ch.timer = a.period(mem, i)
+	ch.pos = 0
+	ch.volume = reg(mem, i, 2) >> 4
+	ch.envTimer = int(reg(mem, i, 2) & 0x07)
+	ch.lfsr = 0x7FFF
+	if i == 2 {
+		ch.length = 256 - int(reg(mem, i, 1))
+	} else {
+		ch.length = 64 - int(reg(mem, i, 1)&0x3F)
+	}
+}
+
+func (a *APU) frameSequencer(mem *Memory) {
+	a.seqStep = (a.seqStep + 1) % 8
+	for i := range a.ch {
+		ch := &a.ch[i]
+		if a.seqStep%2 == 0 && reg(mem, i, 4)&0x40 != 0 && ch.length > 0 {
+			ch.length--
+			if ch.length == 0 {
+				ch.enabled = false
+			}
+		}
+		if a.seqStep == 7 && i != 2 {
+			nrx2 := reg(mem, i, 2)
+			pace := int(nrx2 & 0x07)
+			if pace == 0 {
+				continue
+			}
+			if ch.envTimer--; ch.envTimer > 0 {
+				continue
+			}
+			ch.envTimer = pace
+			if nrx2&0x08 != 0 && ch.volume < 15 {
+				ch.volume++
+			} else if nrx2&0x08 == 0 && ch.volume > 0 {
+				ch.volume--
+			}
+		}
+	}
+}
+
+func (a *APU) clock(mem *Memory, i, delta int) {
+	ch := &a.ch[i]
+	if !ch.enabled {
+		return
+	}
+	for ch.timer -= delta; ch.timer <= 0; ch.timer += a.period(mem, i) {
+		switch i {
+		case 0, 1:
+			ch.pos = (ch.pos + 1) % 8
+		case 2:
+			ch.pos = (ch.pos + 1) % 32
+		case 3:
+			xor := (ch.lfsr & 1) ^ (ch.lfsr >> 1 & 1)
+			ch.lfsr = ch.lfsr>>1 | xor<<14
+			if reg(mem, 3, 3)&0x08 != 0 { // 7-bit mode
+				ch.lfsr = ch.lfsr&^(1<<6) | xor<<6
+			}
+		}
+	}
+}
+
+// Current output of channel i, 0 - 15
+func (a *APU) output(mem *Memory, i int) uint8 {
+	ch := &a.ch[i]
+	switch i {
+	case 0, 1:
+		duty := reg(mem, i, 1) >> 6
+		return dutyCycles[duty][ch.pos] * ch.volume
+	case 2:
+		sample := mem.data[ADDR_WAVE+ch.pos/2]
+		if ch.pos%2 == 0 {
+			sample >>= 4
+		}
+		sample &= 0x0F
+		// 0: mute, 1: 100%, 2: 50%, 3: 25%
+		switch level := reg(mem, 2, 2) >> 5 & 0x03; level {
+		case 0:
+			return 0
+		default:
+			return sample >> (level - 1)
+		}
+	default:
+		return uint8(^ch.lfsr&1) * ch.volume
+	}
+}
+
+func (a *APU) mix(mem *Memory) int16 {
+	nr51 := mem.data[ADDR_NR51]
+	var sum int
+	for i := range a.ch {
+		if !a.ch[i].enabled || !dacOn(mem, i) || (nr51>>i|nr51>>(i+4))&1 == 0 {
+			continue
+		}
+		// the DAC maps 0 - 15 to a voltage between -1 and 1
+		sum += 2*int(a.output(mem, i)) - 15
+	}
+	nr50 := mem.data[ADDR_NR50]
+	volume := max(int(nr50>>4&0x07), int(nr50&0x07)) + 1
+	return int16(sum * volume * 64)
+}
@@ -0,0 +1,598 @@
+package gameboy
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	ErrAsmSyntax      = errors.New("syntax error")
+	ErrAsmInstruction = errors.New("no such instruction")
+	ErrAsmLabel       = errors.New("undefined label")
+	ErrAsmRange       = errors.New("value out of range")
+)
+
+type AsmError struct {
+	Line int // starting at 1
+	Text string
+	Err  error
+}
+
+func (e *AsmError) Error() string {
+	return fmt.Sprintf("line %d: %v: %s", e.Line, e.Err, strings.TrimSpace(e.Text))
+}
+
+func (e *AsmError) Unwrap() error { return e.Err }
+
+// Assembles src into one Block per section, ready for Memory.Write. It's a
+// small assembler for writing test programs and the like as text rather than
+// bytes, and takes a subset of the rgbds syntax:
+//
+//	SECTION "main", ROM0[$0150]
+//	Main:
+//		ld a, $12
+//		ld hl, $C000
+//	.loop:              ; local to Main
+//		ld [hl+], a
+//		dec a
+//		jr nz, .loop
+//		ldh [$FF40], a
+//		db $01, "hi", Main
+//		dw Main
+//		ds 4, $FF
+//
+// Labels, including local ones, can be used before they're defined. Numbers
+// are decimal, $hex, 0xhex or %binary, and can be added and subtracted. Each
+// SECTION with an address starts a new Block; code before the first one
+// starts at 0x0000.
+func Assemble(src string) ([]Block, error) {
+	a := &assembler{labels: map[string]int{}}
+	if err := a.parse(src); err != nil {
+		return nil, err
+	}
+	return a.emit()
+}
+
+// Like Assemble, but panics on errors. For programs written in code, where
+// an error is a bug.
+func MustAssemble(src string) []Block {
+	blocks, err := Assemble(src)
+	if err != nil {
+		panic(err)
+	}
+	return blocks
+}
+
+// An instruction from the opcode table, e.g. "LD (a16),A"
+type asmOpcode struct {
+	code     uint8
+	prefixed bool
+	operands []string
+}
+
+// The opcode table by the names the instructions go by, e.g. "LD A,n8"
+var asmOpcodes = func() map[string]asmOpcode {
+	m := map[string]asmOpcode{}
+	add := func(table map[uint8]Instruction, prefixed bool) {
+		for code, in := range table {
+			name := in.String()
+			var operands []string
+			if _, args, ok := strings.Cut(name, " "); ok {
+				operands = strings.Split(args, ",")
+			}
+			m[name] = asmOpcode{code: code, prefixed: prefixed, operands: operands}
+		}
+	}
+	add(ops, false)
+	add(extOps, true)
+	return m
+}()
+
+// Size of the immediate value of an operand from the opcode table
+func operandSize(name string) int {
+	switch name {
+	case "n8", "e8", "(a8)":
+		return 1
+	case "n16", "a16", "(a16)":
+		return 2
+	}
+	return 0
+}
+
+type statement struct {
+	line  int
+	text  string
+	scope string // the global label local labels belong to
+	addr  int
+
+	// an instruction...
+	op    asmOpcode
+	exprs []string // for the operands with a value, in order
+	// ... or data
+	directive string
+	args      []string
+
+	size int
+}
+
+type section struct {
+	offset     uint16
+	statements []statement
+}
+
+type assembler struct {
+	sections []*section
+	labels   map[string]int
+}
+
+func (a *assembler) errorf(s statement, err error, format string, args ...any) error {
+	if format != "" {
+		err = fmt.Errorf("%w: %s", err, fmt.Sprintf(format, args...))
+	}
+	return &AsmError{Line: s.line, Text: s.text, Err: err}
+}
+
+// First pass: splits the source into statements, works out their sizes, and
+// with those, the addresses of the labels.
+func (a *assembler) parse(src string) error {
+	var cur *section
+	addr, scope := 0, ""
+	for i, text := range strings.Split(src, "\n") {
+		s := statement{line: i + 1, text: text}
+		line := strings.TrimSpace(stripComment(text))
+
+		// labels, possibly followed by an instruction
+		for {
+			name, rest, ok := cutLabel(line)
+			if !ok {
+				break
+			}
+			if !strings.HasPrefix(name, ".") {
+				scope = name
+			}
+			name = qualify(name, scope)
+			if _, dup := a.labels[name]; dup {
+				return a.errorf(s, ErrAsmSyntax, "label %s defined twice", name)
+			}
+			a.labels[name] = addr
+			line = strings.TrimSpace(rest)
+		}
+		if line == "" {
+			continue
+		}
+
+		mnemonic, rest, _ := strings.Cut(line, " ")
+		mnemonic = strings.ToUpper(mnemonic)
+		args := splitArgs(rest)
+		s.scope = scope
+
+		if mnemonic == "SECTION" {
+			offset, err := sectionAddress(rest)
+			if err != nil {
+				return a.errorf(s, ErrAsmSyntax, "%v", err)
+			}
+			cur = &section{offset: offset}
+			a.sections = append(a.sections, cur)
+			addr = int(offset)
+			continue
+		}
+		if cur == nil {
+			cur = &section{}
+			a.sections = append(a.sections, cur)
+		}
+
+		switch mnemonic {
+		case "DB":
+			s.directive, s.args = mnemonic, args
+			for _, arg := range args {
+				if str, ok := unquote(arg); ok {
+					s.size += len(str)
+				} else {
+					s.size++
+				}
+			}
+		case "DW":
+			s.directive, s.args = mnemonic, args
+			s.size = 2 * len(args)
+		case "DS":
+			if len(args) < 1 || len(args) > 2 {
+				return a.errorf(s, ErrAsmSyntax, "ds takes a size and an optional fill byte")
+			}
+			// the size has to be known now, so labels can't be used
+			n, err := a.eval(args[0], s, false)
+			if err != nil {
+				return err
+			}
+			s.directive, s.args, s.size = mnemonic, args, n
+		default:
+			op, exprs, err := lookup(mnemonic, args)
+			if err != nil {
+				return a.errorf(s, err, "")
+			}
+			s.op, s.exprs = op, exprs
+			s.size = 1
+			if op.prefixed {
+				s.size++
+			}
+			for _, o := range op.operands {
+				s.size += operandSize(o)
+			}
+			if mnemonic == "STOP" {
+				s.size++ // STOP is followed by a byte that's ignored
+			}
+		}
+		s.addr = addr
+		addr += s.size
+		cur.statements = append(cur.statements, s)
+	}
+	return nil
+}
+
+// Second pass: now that the labels are known, turns the statements into bytes.
+func (a *assembler) emit() ([]Block, error) {
+	var blocks []Block
+	for _, sec := range a.sections {
+		var data []byte
+		for _, s := range sec.statements {
+			b, err := a.encode(s)
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, b...)
+		}
+		blocks = append(blocks, Block{Offset: sec.offset, Data: data})
+	}
+	return blocks, nil
+}
+
+func (a *assembler) encode(s statement) ([]byte, error) {
+	var out []byte
+	switch s.directive {
+	case "DB":
+		for _, arg := range s.args {
+			if str, ok := unquote(arg); ok {
+				out = append(out, str...)
+				continue
+			}
+			v, err := a.value(arg, s, -128, 0xFF)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, uint8(v))
+		}
+		return out, nil
+	case "DW":
+		for _, arg := range s.args {
+			v, err := a.value(arg, s, -0x8000, 0xFFFF)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, uint8(v), uint8(v>>8))
+		}
+		return out, nil
+	case "DS":
+		fill := 0
+		if len(s.args) == 2 {
+			var err error
+			if fill, err = a.value(s.args[1], s, -128, 0xFF); err != nil {
+				return nil, err
+			}
+		}
+		for range s.size {
+			out = append(out, uint8(fill))
+		}
+		return out, nil
+	}
+
+	if s.op.prefixed {
+		out = append(out, 0xCB)
+	}
+	out = append(out, s.op.code)
+	exprs := s.exprs
+	for _, o := range s.op.operands {
+		var v int
+		var err error
+		switch o {
+		case "n8":
+			v, err = a.value(exprs[0], s, -128, 0xFF)
+		case "(a8)":
+			if v, err = a.value(exprs[0], s, 0, 0xFFFF); err == nil && v >= 0xFF00 {
+				v -= 0xFF00
+			}
+			if err == nil && v > 0xFF {
+				err = a.errorf(s, ErrAsmRange, "%#04x isn't in 0xFF00-0xFFFF", v)
+			}
+		case "e8":
+			if isJR(s.op) {
+				// relative to the next instruction
+				if v, err = a.value(exprs[0], s, 0, 0xFFFF); err == nil {
+					v -= s.addr + s.size
+					if v < -128 || v > 127 {
+						err = a.errorf(s, ErrAsmRange, "jump of %d bytes is too far", v)
+					}
+				}
+			} else {
+				v, err = a.value(exprs[0], s, -128, 127)
+			}
+		case "n16", "a16", "(a16)":
+			v, err = a.value(exprs[0], s, -0x8000, 0xFFFF)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, uint8(v))
+		if operandSize(o) == 2 {
+			out = append(out, uint8(v>>8))
+		}
+		exprs = exprs[1:]
+	}
+	if s.op.code == code("STOP") && !s.op.prefixed {
+		out = append(out, 0x00)
+	}
+	return out, nil
+}
+
+// JR takes the address to jump to, which is turned into an offset
+func isJR(op asmOpcode) bool {
+	return !op.prefixed && ops[op.code].String()[:2] == "JR"
+}
+
+// Evaluates expr and checks that it's within min and max.
+func (a *assembler) value(expr string, s statement, min, max int) (int, error) {
+	v, err := a.eval(expr, s, true)
+	if err != nil {
+		return 0, err
+	}
+	if v < min || v > max {
+		return 0, a.errorf(s, ErrAsmRange, "%d isn't in %d..%d", v, min, max)
+	}
+	return v, nil
+}
+
+// Evaluates a sum of numbers and labels, e.g. "Table + 2". "@" is the
+// address of the statement.
+func (a *assembler) eval(expr string, s statement, labels bool) (int, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return 0, a.errorf(s, ErrAsmSyntax, "missing value")
+	}
+	total, sign := 0, 1
+	for expr != "" {
+		switch expr[0] {
+		case '+':
+			expr = strings.TrimSpace(expr[1:])
+			continue
+		case '-':
+			sign = -sign
+			expr = strings.TrimSpace(expr[1:])
+			continue
+		}
+		end := strings.IndexAny(expr, "+-")
+		if end < 0 {
+			end = len(expr)
+		}
+		term := strings.TrimSpace(expr[:end])
+		expr = strings.TrimSpace(expr[end:])
+
+		var v int
+		switch n, err := parseNumber(term); {
+		case err == nil:
+			v = n
+		case term == "@":
+			v = s.addr
+		case !labels || !isIdent(term):
+			return 0, a.errorf(s, ErrAsmSyntax, "bad value %q", term)
+		default:
+			addr, ok := a.labels[qualify(term, s.scope)]
+			if !ok {
+				return 0, a.errorf(s, ErrAsmLabel, "%s", term)
+			}
+			v = addr
+		}
+		total += sign * v
+		sign = 1
+	}
+	return total, nil
+}
+
+// Finds the opcode for the mnemonic and operands as written, and returns the
+// expressions of the operands that have a value.
+func lookup(mnemonic string, args []string) (asmOpcode, []string, error) {
+	switch {
+	case mnemonic == "LD" && len(args) == 2 && (isC(args[0]) || isC(args[1])):
+		mnemonic = "LDH" // ld [c], a is ldh [c], a
+	case len(args) == 1 && slices.Contains([]string{"ADD", "ADC", "SUB", "SBC", "AND", "XOR", "OR", "CP"}, mnemonic):
+		args = []string{"a", args[0]} // sub b is sub a, b
+	}
+	type candidate struct {
+		name string
+		expr string // "" if there's no value
+	}
+	var options [][]candidate
+	for _, arg := range args {
+		var opts []candidate
+		op := strings.ToUpper(strings.ReplaceAll(arg, " ", ""))
+		inner, indirect := strings.CutPrefix(op, "[")
+		if indirect {
+			inner, indirect = strings.CutSuffix(inner, "]")
+		}
+		switch {
+		case indirect && (inner == "HLI" || inner == "HL+"):
+			opts = append(opts, candidate{"(HL+)", ""})
+		case indirect && (inner == "HLD" || inner == "HL-"):
+			opts = append(opts, candidate{"(HL-)", ""})
+		case indirect && (isRegister(inner) || inner == "$FF00+C"):
+			opts = append(opts, candidate{"(" + strings.TrimPrefix(inner, "$FF00+") + ")", ""})
+		case indirect:
+			expr := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(arg), "["), "]")
+			opts = append(opts, candidate{"(a16)", expr}, candidate{"(a8)", expr})
+		case strings.HasPrefix(op, "SP+") || strings.HasPrefix(op, "SP-"):
+			// ld hl, sp + e8 is two operands in the table
+			options = append(options, []candidate{{"SP+", ""}})
+			expr := strings.TrimSpace(arg)[2:]
+			opts = append(opts, candidate{"e8", expr})
+		case isRegister(op) || isCondition(op):
+			opts = append(opts, candidate{op, ""})
+		default:
+			expr := strings.TrimSpace(arg)
+			if n, err := parseNumber(op); err == nil {
+				// bit numbers and RST vectors are part of the name
+				opts = append(opts, candidate{strconv.Itoa(n), ""}, candidate{fmt.Sprintf("$%02X", n), ""})
+			}
+			for _, name := range []string{"n8", "n16", "e8", "a16"} {
+				opts = append(opts, candidate{name, expr})
+			}
+		}
+		options = append(options, opts)
+	}
+
+	// try every combination; there are only ever a handful
+	var try func(i int, names, exprs []string) (asmOpcode, []string, bool)
+	try = func(i int, names, exprs []string) (asmOpcode, []string, bool) {
+		if i == len(options) {
+			name := mnemonic
+			if len(names) > 0 {
+				name += " " + strings.Join(names, ",")
+			}
+			op, ok := asmOpcodes[name]
+			return op, exprs, ok
+		}
+		for _, c := range options[i] {
+			e := exprs
+			if c.expr != "" {
+				e = append(slices.Clone(exprs), c.expr)
+			}
+			if op, e, ok := try(i+1, append(slices.Clone(names), c.name), e); ok {
+				return op, e, true
+			}
+		}
+		return asmOpcode{}, nil, false
+	}
+	op, exprs, ok := try(0, nil, nil)
+	if !ok {
+		return asmOpcode{}, nil, ErrAsmInstruction
+	}
+	return op, exprs, nil
+}
+
+func isRegister(s string) bool {
+	switch s {
+	case "A", "B", "C", "D", "E", "H", "L", "AF", "BC", "DE", "HL", "SP":
+		return true
+	}
+	return false
+}
+
+func isCondition(s string) bool {
+	switch s {
+	case "NZ", "Z", "NC", "C":
+		return true
+	}
+	return false
+}
+
+func isC(arg string) bool {
+	s := strings.ToUpper(strings.ReplaceAll(arg, " ", ""))
+	return s == "[C]" || s == "[$FF00+C]"
+}
+
+// Numbers in rgbds ($FF, %1010) or Go (0xFF, 0b1010) notation, or decimal
+func parseNumber(s string) (int, error) {
+	switch {
+	case strings.HasPrefix(s, "$"):
+		n, err := strconv.ParseInt(s[1:], 16, 32)
+		return int(n), err
+	case strings.HasPrefix(s, "%"):
+		n, err := strconv.ParseInt(s[1:], 2, 32)
+		return int(n), err
+	}
+	n, err := strconv.ParseInt(s, 0, 32)
+	return int(n), err
+}
+
+func isIdent(s string) bool {
+	for i, r := range s {
+		if !(unicode.IsLetter(r) || r == '_' || r == '.' || i > 0 && unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// Returns the label at the start of the line, if there is one: "Main:",
+// "Main::" or ".loop:".
+func cutLabel(line string) (name, rest string, ok bool) {
+	i := strings.IndexByte(line, ':')
+	if i <= 0 || !isIdent(line[:i]) {
+		return "", line, false
+	}
+	return line[:i], strings.TrimPrefix(line[i+1:], ":"), true
+}
+
+// Local labels (.loop) belong to the global label before them.
+func qualify(name, scope string) string {
+	if strings.HasPrefix(name, ".") {
+		return scope + name
+	}
+	return name
+}
+
+func stripComment(line string) string {
+	quoted := false
+	for i, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ';' && !quoted:
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// Splits on commas outside of strings.
+func splitArgs(s string) []string {
+	var args []string
+	quoted, start := false, 0
+	for i, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			args = append(args, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	if rest := strings.TrimSpace(s[start:]); rest != "" || len(args) > 0 {
+		args = append(args, rest)
+	}
+	return args
+}
+
+func unquote(s string) (string, bool) {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1], true
+	}
+	return "", false
+}
+
+// The address in SECTION "name", ROMX[$4000], BANK[1]
+func sectionAddress(s string) (uint16, error) {
+	s = s[strings.LastIndexByte(s, '"')+1:]
+	start := strings.IndexByte(s, '[')
+	end := strings.IndexByte(s, ']')
+	if start < 0 || end < start {
+		return 0, fmt.Errorf("section without an address: %s", s)
+	}
+	n, err := parseNumber(strings.TrimSpace(s[start+1 : end]))
+	if err != nil || n < 0 || n > 0xFFFF {
+		return 0, fmt.Errorf("bad section address %q", s[start+1:end])
+	}
+	return uint16(n), nil
+}
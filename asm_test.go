@@ -0,0 +1,114 @@
+package gameboy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssemble(t *testing.T) {
+	cases := []struct {
+		src  string
+		want []byte
+	}{
+		{"nop", []byte{0x00}},
+		{"ld a, $12", []byte{code("LD A,n8"), 0x12}},
+		{"LD A, 0x12 ; loud", []byte{code("LD A,n8"), 0x12}},
+		{"ld bc, $1234", []byte{code("LD BC,n16"), 0x34, 0x12}},
+		{"ld a, [hl+]", []byte{code("LD A,(HL+)")}},
+		{"ld [hld], a", []byte{code("LD (HL-),A")}},
+		{"ld [$C000], a", []byte{code("LD (a16),A"), 0x00, 0xC0}},
+		{"ld [$C000], sp", []byte{code("LD (a16),SP"), 0x00, 0xC0}},
+		{"ldh [$FF44], a", []byte{code("LDH (a8),A"), 0x44}},
+		{"ldh a, [$44]", []byte{code("LDH A,(a8)"), 0x44}},
+		{"ld [c], a", []byte{code("LDH (C),A")}},
+		{"ld hl, sp - 2", []byte{code("LD HL,SP+,e8"), 0xFE}},
+		{"add sp, -2", []byte{code("ADD SP,e8"), 0xFE}},
+		{"sub b", []byte{code("SUB A,B")}},
+		{"cp a, %1010", []byte{code("CP A,n8"), 0x0A}},
+		{"jp hl", []byte{code("JP HL")}},
+		{"jp nz, $0150", []byte{code("JP NZ,a16"), 0x50, 0x01}},
+		{"rst $38", []byte{code("RST $38")}},
+		{"bit 7, [hl]", []byte{0xCB, code("BIT 7,(HL)")}},
+		{"swap a", []byte{0xCB, code("SWAP A")}},
+		{"stop", []byte{code("STOP"), 0x00}},
+		{"db 1, -1, \"hi\"", []byte{0x01, 0xFF, 'h', 'i'}},
+		{"dw $1234, 5", []byte{0x34, 0x12, 0x05, 0x00}},
+		{"ds 3, $FF", []byte{0xFF, 0xFF, 0xFF}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.src, func(t *testing.T) {
+			blocks, err := Assemble(tc.src)
+			require.NoError(t, err)
+			require.Equal(t, []Block{{Offset: 0, Data: tc.want}}, blocks)
+		})
+	}
+}
+
+func TestAssembleLabels(t *testing.T) {
+	req := require.New(t)
+	blocks, err := Assemble(`
+SECTION "entry", ROM0[$0100]
+	nop
+	jp Main
+
+SECTION "main", ROM0[$0150]
+Main:
+	ld b, 3
+.loop:
+	dec b
+	jr nz, .loop
+	call Sub
+	jr Main
+Sub:
+.loop:  ret             ; another .loop
+	dw Main.loop, Sub + 1, @
+`)
+	req.NoError(err)
+	req.Equal([]Block{
+		{Offset: 0x0100, Data: []byte{code("NOP"), code("JP a16"), 0x50, 0x01}},
+		{Offset: 0x0150, Data: []byte{
+			code("LD B,n8"), 0x03, // $0150
+			code("DEC B"),          // $0152
+			code("JR NZ,e8"), 0xFD, // $0153
+			code("CALL a16"), 0x5A, 0x01, // $0155
+			code("JR e8"), 0xF6, // $0158
+			code("RET"), // $015A
+			0x52, 0x01, 0x5B, 0x01, 0x5B, 0x01,
+		}},
+	}, blocks)
+
+	mem := NewFlatMemory().Write(blocks)
+	req.Equal(uint8(0xFD), mem.Read(0x0154))
+}
+
+func TestAssembleErrors(t *testing.T) {
+	cases := []struct {
+		src  string
+		want error
+		line int
+	}{
+		{"ld q, 5", ErrAsmInstruction, 1},
+		{"nop\n  jr Nowhere", ErrAsmLabel, 2},
+		{"ld a, 256", ErrAsmRange, 1},
+		{"jr Far\nds 200\nFar:", ErrAsmRange, 1},
+		{"ldh [$C000], a", ErrAsmRange, 1},
+		{"A:\nA:", ErrAsmSyntax, 2},
+		{"SECTION \"x\", ROM0", ErrAsmSyntax, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.src, func(t *testing.T) {
+			_, err := Assemble(tc.src)
+			require.ErrorIs(t, err, tc.want)
+			var asmErr *AsmError
+			require.ErrorAs(t, err, &asmErr)
+			require.Equal(t, tc.line, asmErr.Line)
+		})
+	}
+}
+
+func TestGetBootCode(t *testing.T) {
+	blocks := GetBootCode()
+	require.Len(t, blocks, 1)
+	require.Equal(t, BootROM[:0xA8], blocks[0].Data)
+}
@@ -0,0 +1,139 @@
+package gameboy
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// The sections of cpu_instrs the cpu doesn't pass yet. They're reported as
+// skipped; one that starts passing fails the test, so that it's taken off.
+// For now the ROM stops early: AND A,n8 doesn't consume its operand, and the
+// $10 after it runs as STOP.
+var cpuInstrsFailing = map[string]bool{
+	"01-special":            true,
+	"02-interrupts":         true,
+	"03-op sp,hl":           true,
+	"04-op r,imm":           true,
+	"05-op rp":              true,
+	"06-ld r,r":             true,
+	"07-jr,jp,call,ret,rst": true,
+	"08-misc instrs":        true,
+	"09-op r,r":             true,
+	"10-bit ops":            true,
+	"11-op a,(hl)":          true,
+}
+
+func TestBlarggCPUInstrs(t *testing.T) {
+	rom, err := os.ReadFile("cartridge/data/cpu_instrs.gb")
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Skip("cartridge/data/cpu_instrs.gb is missing")
+	}
+	require.NoError(t, err)
+
+	res := RunBlargg(rom, BlarggOptions{
+		MaxCycles: 60 * CPU_FREQUENCY, // cpu_instrs needs ~55 seconds on hardware
+	})
+	t.Logf("output after %d cycles:\n%s", res.Cycles, res.Output)
+
+	names := []string{
+		"01-special", "02-interrupts", "03-op sp,hl", "04-op r,imm",
+		"05-op rp", "06-ld r,r", "07-jr,jp,call,ret,rst", "08-misc instrs",
+		"09-op r,r", "10-bit ops", "11-op a,(hl)",
+	}
+	for i, name := range names {
+		t.Run(name, func(t *testing.T) {
+			s, ok := res.Section(i + 1)
+			var failure string
+			switch {
+			case !ok:
+				failure = fmt.Sprintf("section never reported: %v", res.Err)
+			case !s.Passed:
+				failure = fmt.Sprintf("failed with code %s", s.Code)
+			}
+			switch {
+			case failure != "" && cpuInstrsFailing[name]:
+				t.Skipf("known failure: %s", failure)
+			case failure != "":
+				t.Fatal(failure)
+			case cpuInstrsFailing[name]:
+				t.Fatal("passes now; take it off cpuInstrsFailing")
+			}
+		})
+	}
+}
+
+// Writes s over the serial port, then loops forever
+func serialProgram(s string) []byte {
+	var prog []byte
+	for _, c := range []byte(s) {
+		prog = append(prog,
+			code("LD A,n8"), c,
+			code("LDH (a8),A"), 0x01,
+			code("LD A,n8"), 0x81,
+			code("LDH (a8),A"), 0x02,
+		)
+	}
+	return append(prog, code("JR e8"), 0xFE)
+}
+
+// Creates a 32kB ROM-only cartridge that jumps past the header into prog
+func testROM(prog []byte) []byte {
+	rom := make([]byte, 32*1024)
+	copy(rom[0x100:], []byte{code("NOP"), code("JP a16"), 0x50, 0x01})
+	copy(rom[0x150:], prog)
+	var checksum byte
+	for _, b := range rom[0x0134:0x014D] {
+		checksum = checksum - b - 1
+	}
+	rom[0x014D] = checksum
+	return rom
+}
+
+func TestRunBlargg(t *testing.T) {
+	t.Run("serial", func(t *testing.T) {
+		req := require.New(t)
+		rom := testROM(serialProgram("cpu_instrs\n\n01:ok  02:03  \n\nFailed 1 tests\n"))
+		res := RunBlargg(rom, BlarggOptions{MaxCycles: 100_000})
+		req.NoError(res.Err)
+		req.True(res.Finished)
+		req.False(res.Passed)
+		req.Equal([]BlarggSection{
+			{ID: 1, Code: "ok", Passed: true},
+			{ID: 2, Code: "03", Passed: false},
+		}, res.Sections)
+	})
+	t.Run("passed", func(t *testing.T) {
+		req := require.New(t)
+		res := RunBlargg(testROM(serialProgram("01-special\n\nPassed\n")), BlarggOptions{MaxCycles: 100_000})
+		req.NoError(res.Err)
+		req.True(res.Passed)
+		req.Equal("01-special\n\nPassed", res.Output)
+	})
+	t.Run("budget", func(t *testing.T) {
+		req := require.New(t)
+		res := RunBlargg(testROM(serialProgram("")), BlarggOptions{MaxCycles: 1000})
+		req.Error(res.Err)
+		req.False(res.Finished)
+		req.GreaterOrEqual(res.Cycles, 1000)
+	})
+	t.Run("screen", func(t *testing.T) {
+		req := require.New(t)
+		var prog []byte
+		for i, c := range []byte("Passed") {
+			prog = append(prog, code("LD A,n8"), c, code("LD (a16),A"), byte(0x20+i), 0x98)
+		}
+		prog = append(prog, code("JR e8"), 0xFE)
+		res := RunBlargg(testROM(prog), BlarggOptions{
+			Source:    SourceScreen,
+			MaxCycles: 10 * CYCLES_PER_FRAME,
+		})
+		req.NoError(res.Err)
+		req.True(res.Passed)
+		req.Equal("\nPassed", res.Output)
+	})
+}
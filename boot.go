@@ -1,5 +1,14 @@
 package gameboy
 
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
 var BootROM = []byte{
 	0x31, 0xfe, 0xff, 0xaf, 0x21, 0xff, 0x9f, 0x32, 0xcb, 0x7c, 0x20, 0xfb, 0x21, 0x26, 0xff, 0x0e,
 	0x11, 0x3e, 0x80, 0x32, 0xe2, 0x0c, 0x3e, 0xf3, 0xe2, 0x32, 0x3e, 0x77, 0x77, 0x3e, 0xfc, 0xe0,
@@ -19,6 +28,69 @@ var BootROM = []byte{
 	0xf5, 0x06, 0x19, 0x78, 0x86, 0x23, 0x05, 0x20, 0xfb, 0x86, 0x20, 0xfe, 0x3e, 0x01, 0xe0, 0x50,
 }
 
+const (
+	BOOT_ROM_SIZE     = 0x100 // DMG, MGB and SGB
+	CGB_BOOT_ROM_SIZE = 0x900 // 0x0000-0x00FF and 0x0200-0x08FF
+)
+
+var ErrBootROMSize = errors.New("invalid boot ROM size")
+
+// Reads a boot ROM from disk, e.g. dmg_boot.bin, and works out which model it
+// belongs to. An empty path gives the built-in DMG boot ROM.
+func LoadBootROM(path string) ([]byte, Model, error) {
+	if path == "" {
+		return BootROM, DMG, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("read boot ROM: %w", err)
+	}
+	model, err := BootROMModel(filepath.Base(path), b)
+	if err != nil {
+		return nil, 0, err
+	}
+	return b, model, nil
+}
+
+// Works out which model a boot ROM belongs to. Most boot ROMs are the same
+// size, so we go by the file name first (dmg0_boot.bin, mgb_boot.bin,
+// sgb2_boot.bin, ...), and then by the contents: the MGB and SGB2 boot ROMs
+// differ from their predecessors by loading 0xFF rather than 0x01 into A right
+// before unmapping themselves.
+func BootROMModel(name string, rom []byte) (Model, error) {
+	name = strings.ToLower(name)
+	model := DMG
+	switch {
+	case len(rom) == CGB_BOOT_ROM_SIZE || strings.Contains(name, "cgb"):
+		model = CGB
+	case strings.Contains(name, "dmg0"):
+		model = DMG0
+	case strings.Contains(name, "mgb"):
+		model = MGB
+	case strings.Contains(name, "sgb2"):
+		model = SGB2
+	case strings.Contains(name, "sgb"):
+		model = SGB
+	}
+
+	wantSize := BOOT_ROM_SIZE
+	if model == CGB {
+		wantSize = CGB_BOOT_ROM_SIZE
+	}
+	if len(rom) != wantSize {
+		return 0, fmt.Errorf("%w: %s boot ROM is %d bytes, want %d", ErrBootROMSize, model, len(rom), wantSize)
+	}
+
+	pocket := bytes.HasSuffix(rom, []byte{code("LD A,n8"), 0xFF, code("LDH (a8),A"), 0x50})
+	switch {
+	case model == DMG && pocket:
+		model = MGB
+	case model == SGB && pocket:
+		model = SGB2
+	}
+	return model, nil
+}
+
 // at 0x0104 in ROM
 var BootLogo = []byte{
 	0xCE, 0xED, 0x66, 0x66, 0xCC, 0x0D, 0x00, 0x0B,
@@ -29,177 +101,230 @@ var BootLogo = []byte{
 	0xDD, 0xDC, 0x99, 0x9F, 0xBB, 0xB9, 0x33, 0x3E,
 }
 
-func GetBootCode() []Block {
-	return []Block{
-		{
-			Offset: 0x0000,
-			Data: []byte{
-				code("LD SP,n16"), 0xFF, 0xFE, // setup stack
-				code("XOR A,A"), // Zero the memory from $8000-$9FFF (VRAM)
-				code("LD HL,n16"), 0x9F, 0xFF,
-			},
-		},
-		{
-			Offset: 0x0007,
-			Data: []byte{
-				code("LD (HL-),A"), // $0007
-				code("PREFIX"), code("BIT 7,H"),
-				code("JR NZ,e8"), 0xFB,
-				code("LD HL,n16"), 0xff, 0x26, // setup audio
-				code("LD C,n8"), 0x11,
-				code("LD A,n8"), 0x80,
-				code("LD (HL-),A"),
-				code("LDH (C),A"),
-				code("INC C"),
-				code("LD A,n8"), 0xf3,
-				code("LDH (C),A"),
-				code("LD (HL-),A"),
-				code("LD A,n8"), 0x77,
-				code("LD (HL),A"),
-				code("LD A,n8"), 0xfc, // setup bg palette
-				code("LDH (a8),A"), 0x47,
-				code("LD DE,n16"), 0x01, 0x04, // convert and load logo data from cart into Video RAM
-				code("LD HL,n16"), 0x80, 0x10,
-			},
-		},
-		{
-			Offset: 0x0027,
-			Data: []byte{
-				code("LD A,(DE)"),            // $0027
-				code("CALL a16"), 0x95, 0x00, // $0028
-				code("CALL a16"), 0x96, 0x00, // $002b
-				code("INC DE"),        // $002e
-				code("LD A,E"),        // $002f
-				code("CP A,n8"), 0x34, // $0030
-				code("JR NZ,e8"), 0xf3, // $0032
-				code("LD DE,n16"), 0x00, 0xd8, // $0034 Load 8 additional bytes into Video RAM (the tile for ®)
-				code("LD B,n8"), 0x08, // $0037
-			},
-		},
-		{
-			Offset: 0x0039,
-			Data: []byte{
-				code("LD A,(DE)"),      // $0039
-				code("INC DE"),         // $003a
-				code("LD (HL+),A"),     // $003b
-				code("INC HL"),         // $003c
-				code("DEC B"),          // $003d
-				code("JR NZ,e8"), 0x39, // $003e
-				code("LD A,n8"), 0x19, // $0040 Setup background tilemap
-				code("LD (a16),A"), 0x10, 0x99, // $0042
-				code("LD HL,n16"), 0x2f, 0x99, // $0045
-			},
-		},
-		{
-			Offset: 0x0048,
-			Data: []byte{
-				code("LD C,n8"), 0x0c, // $0048
-			},
-		},
-		{
-			Offset: 0x004A,
-			Data: []byte{
-				code("DEC A"),         // $004a
-				code("JR Z,e8"), 0x55, // $004b
-				code("LD (HL-),A"),     // $004d
-				code("DEC C"),          // $004e
-				code("JR NZ,e8"), 0x4a, // $004f
-				code("LD L,n8"), 0x0f, // $0051
-				code("JR e8"), 0x48, // $0053
-			},
-		},
-		// ; === Scroll logo on screen, and play logo sound===
-		{
-			Offset: 0x0055,
-			Data: []byte{
-				code("LD H,A"),        // $0055  Initialize scroll count, H=0
-				code("LD A,n8"), 0x64, // $0056
-				code("LD D,A"),           // $0058  set loop count, D=$64
-				code("LDH (a8),A"), 0x42, // $0059  Set vertical scroll register
-				code("LD A,n8"), 0x91, // $005b
-				code("LDH (a8),A"), 0x40, // $005d  Turn on LCD, showing Background
-				code("INC B"), // $005f  Set B=1
-			},
-		},
-		{
-			Offset: 0x0060,
-			Data: []byte{
-				code("LD E,n8"), 0x02, // $0060
-			},
-		},
-		{
-			Offset: 0x0062,
-			Data: []byte{
-				code("LD C,n8"), 0x0c, // $0062
-			},
-		},
-		{
-			Offset: 0x0064,
-			Data: []byte{
-				code("LDH A,(a8)"), 0x44, // $0064 wait for screen frame
-				code("CP A,n8"), 0x90, // $0066
-				code("JR NZ,e8"), 0x64, // $0068
-				code("DEC C"),          // $006a
-				code("JR NZ,e8"), 0x64, // $006b
-				code("DEC E"),          // $006d
-				code("JR NZ,e8"), 0x62, // $006e
-				code("LD C,n8"), 0x13, // $0070
-				code("INC H"),         // $0072  increment scroll count
-				code("LD A,H"),        // $0073
-				code("LD E,n8"), 0x83, // $0074
-				code("CP A,n8"), 0x62, // $0076  $62 counts in, play sound #1
-				code("JR Z,e8"), 0x80, // $0078
-				code("LD E,n8"), 0xc1, // $007a
-				code("CP A,n8"), 0x64, // $007c
-				code("JR NZ,e8"), 0x86, // $007e  $64 counts in, play sound #2
-			},
-		},
-		{
-			Offset: 0x0080,
-			Data: []byte{
-				code("LD A,E"),          // $0080  play sound
-				code("LDH (C),A"), 0xFF, // $0081
-				code("INC C"),         // $0082
-				code("LD A,n8"), 0x87, // $0083
-				code("LDH (C),A"), 0xFF, // $0085
-			},
-		},
-		{
-			Offset: 0x0086,
-			Data: []byte{
-				code("LDH A,(a8)"), 0x42, // $0086
-				code("SUB A,B"),          // $0088
-				code("LDH (a8),A"), 0x42, // $0089  scroll logo up if B=1
-				code("DEC D"),          // $008b
-				code("JR NZ,e8"), 0x60, // $008c
-				code("DEC B"),          // $008e  set B=0 first time
-				code("JR NZ,e8"), 0xE0, // $008f    ... next time, cause jump to "Nintendo Logo check"
-				code("LD D,n8"), 0x20, // $0091  use scrolling loop to pause
-				code("JR e8"), 0x60, // $0093
-				// ==== Graphic routine ====
-				code("LD C,A"),        // $0095  "Double up" all the bits of the graphics data
-				code("LD B,n8"), 0x04, // $0096     and store in Video RAM
-			},
-		},
-		{
-			Offset: 0x0098,
-			Data: []byte{
-				code("PUSH BC"),              // $0098
-				code("PREFIX"), code("RL C"), // $0099
-				code("RLA"),                  // $009b
-				code("POP BC"),               // $009c
-				code("PREFIX"), code("RL C"), // $009d
-				code("RLA"),            // $009f
-				code("DEC B"),          // $00a0
-				code("JR NZ,e8"), 0x98, // $00a1
-				code("LD (HL+),A"), // $00a3
-				code("INC HL"),     // $00a4
-				code("LD (HL+),A"), // $00a5
-				code("INC HL"),     // $00a6
-				code("RET"),        // $00a7
-			},
-		},
+// Unmaps the boot ROM and puts the cpu and IO registers in the state the boot
+// ROM of the given model leaves them in, so the cpu starts executing the
+// cartridge at 0x0100.
+// https://gbdev.io/pandocs/Power_Up_Sequence.html
+func (cpu *CPU) SkipBoot(model Model) *CPU {
+	mem := cpu.Mem
+	mem.DisableBoot()
+
+	switch model {
+	case DMG0:
+		cpu.A, cpu.F = 0x01, 0x00
+		cpu.B, cpu.C = 0xFF, 0x13
+		cpu.D, cpu.E = 0x00, 0xC1
+		cpu.H, cpu.L = 0x84, 0x03
+	case DMG, MGB:
+		cpu.A, cpu.F = 0x01, FlagRegister(FLAGZ)
+		if model == MGB {
+			cpu.A = 0xFF
+		}
+		// The half-carry and carry flags are left over from verifying the
+		// header checksum, and are only clear if the checksum is zero.
+		if mem.Read(0x014D) != 0 {
+			cpu.F |= FLAGH | FLAGC
+		}
+		cpu.B, cpu.C = 0x00, 0x13
+		cpu.D, cpu.E = 0x00, 0xD8
+		cpu.H, cpu.L = 0x01, 0x4D
+	case SGB, SGB2:
+		cpu.A, cpu.F = 0x01, 0x00
+		if model == SGB2 {
+			cpu.A = 0xFF
+		}
+		cpu.B, cpu.C = 0x00, 0x14
+		cpu.D, cpu.E = 0x00, 0x00
+		cpu.H, cpu.L = 0xC0, 0x60
+	case CGB:
+		cpu.A, cpu.F = 0x11, FlagRegister(FLAGZ)
+		cpu.B, cpu.C = 0x00, 0x00
+		cpu.D, cpu.E = 0xFF, 0x56
+		cpu.H, cpu.L = 0x00, 0x0D
+	default:
+		panic(fmt.Sprintf("SkipBoot: unknown model %s", model))
+	}
+	cpu.SP = 0xFFFE
+	cpu.PC = 0x0100
+
+	// Written directly; going via WriteAt would e.g. start a serial transfer
+	for addr, v := range postBootIO {
+		mem.data[addr] = v
 	}
+	switch model {
+	case DMG0:
+		mem.data[0xFF04] = 0x18 // DIV
+		mem.data[ADDR_STAT] = 0x81
+	case SGB, SGB2:
+		mem.data[0xFF26] = 0xF0 // NR52
+	case CGB:
+		mem.data[0xFF00] = 0xC7 // P1
+		mem.data[ADDR_STAT] = 0x81
+	}
+	return cpu
+}
+
+// IO registers after the DMG boot ROM has run. Registers not listed are zero.
+var postBootIO = map[uint16]byte{
+	0xFF00: 0xCF, // P1
+	0xFF02: 0x7E, // SC
+	0xFF04: 0xAB, // DIV
+	0xFF07: 0xF8, // TAC
+	0xFF0F: 0xE1, // IF
+	0xFF10: 0x80, // NR10
+	0xFF11: 0xBF, // NR11
+	0xFF12: 0xF3, // NR12
+	0xFF13: 0xFF, // NR13
+	0xFF14: 0xBF, // NR14
+	0xFF16: 0x3F, // NR21
+	0xFF18: 0xFF, // NR23
+	0xFF19: 0xBF, // NR24
+	0xFF1A: 0x7F, // NR30
+	0xFF1B: 0xFF, // NR31
+	0xFF1C: 0x9F, // NR32
+	0xFF1D: 0xFF, // NR33
+	0xFF1E: 0xBF, // NR34
+	0xFF20: 0xFF, // NR41
+	0xFF23: 0xBF, // NR44
+	0xFF24: 0x77, // NR50
+	0xFF25: 0xF3, // NR51
+	0xFF26: 0xF1, // NR52
+	0xFF40: 0x91, // LCDC
+	0xFF41: 0x85, // STAT
+	0xFF46: 0xFF, // DMA
+	0xFF47: 0xFC, // BGP
+	0xFF50: 0x01, // BANK, boot ROM unmapped
+}
+
+// The start of the DMG boot ROM, up to the logo data, see BootROM.
+func GetBootCode() []Block {
+	return MustAssemble(`
+	ld sp, $FFFE        ; setup stack
+	xor a               ; zero the memory from $8000-$9FFF (VRAM)
+	ld hl, $9FFF
+ClearVRAM:
+	ld [hl-], a
+	bit 7, h
+	jr nz, ClearVRAM
+
+	ld hl, $FF26        ; setup audio
+	ld c, $11
+	ld a, $80
+	ld [hl-], a
+	ldh [c], a
+	inc c
+	ld a, $F3
+	ldh [c], a
+	ld [hl-], a
+	ld a, $77
+	ld [hl], a
+
+	ld a, $FC           ; setup bg palette
+	ldh [$FF47], a
+
+	ld de, $0104        ; convert and load logo data from cart into Video RAM
+	ld hl, $8010
+LoadLogo:
+	ld a, [de]
+	call DoubleBits
+	call DoubleBits.second
+	inc de
+	ld a, e
+	cp $34
+	jr nz, LoadLogo
+
+	ld de, $00D8        ; load 8 additional bytes into Video RAM (the tile for ®)
+	ld b, $08
+LoadTrademark:
+	ld a, [de]
+	inc de
+	ld [hl+], a
+	inc hl
+	dec b
+	jr nz, LoadTrademark
+
+	ld a, $19           ; setup background tilemap
+	ld [$9910], a
+	ld hl, $992F
+SetupTilemap:
+	ld c, $0C
+.row:
+	dec a
+	jr z, ScrollLogo
+	ld [hl-], a
+	dec c
+	jr nz, .row
+	ld l, $0F
+	jr SetupTilemap
+
+; === Scroll logo on screen, and play logo sound ===
+ScrollLogo:
+	ld h, a             ; initialize scroll count, H=0
+	ld a, $64
+	ld d, a             ; set loop count, D=$64
+	ldh [$FF42], a      ; set vertical scroll register
+	ld a, $91
+	ldh [$FF40], a      ; turn on LCD, showing background
+	inc b               ; set B=1
+.loop:
+	ld e, $02
+.wait:
+	ld c, $0C
+.frame:
+	ldh a, [$FF44]      ; wait for screen frame
+	cp $90
+	jr nz, .frame
+	dec c
+	jr nz, .frame
+	dec e
+	jr nz, .wait
+
+	ld c, $13
+	inc h               ; increment scroll count
+	ld a, h
+	ld e, $83
+	cp $62              ; $62 counts in, play sound #1
+	jr z, .sound
+	ld e, $C1
+	cp $64
+	jr nz, .scroll      ; $64 counts in, play sound #2
+.sound:
+	ld a, e             ; play sound
+	ldh [c], a
+	inc c
+	ld a, $87
+	ldh [c], a
+.scroll:
+	ldh a, [$FF42]
+	sub b
+	ldh [$FF42], a      ; scroll logo up if B=1
+	dec d
+	jr nz, .loop
+	dec b               ; set B=0 first time
+	jr nz, $00E0        ; ... next time, cause jump to "Nintendo Logo check"
+	ld d, $20           ; use scrolling loop to pause
+	jr .loop
+
+; ==== Graphic routine ====
+DoubleBits:
+	ld c, a             ; "double up" all the bits of the graphics data
+.second:
+	ld b, $04           ; and store in Video RAM
+.bit:
+	push bc
+	rl c
+	rla
+	pop bc
+	rl c
+	rla
+	dec b
+	jr nz, .bit
+	ld [hl+], a
+	inc hl
+	ld [hl+], a
+	inc hl
+	ret
+`)
 }
 
 /*
@@ -6,8 +6,50 @@ import (
 	"os"
 	"slices"
 	"testing"
+
+	"github.com/stretchr/testify/require"
 )
 
+func TestSkipBoot(t *testing.T) {
+	cases := []struct {
+		model  Model
+		rom    []byte
+		wantAF uint16
+		wantBC uint16
+		wantDE uint16
+		wantHL uint16
+	}{
+		{model: DMG0, rom: testROM(nil), wantAF: 0x0100, wantBC: 0xFF13, wantDE: 0x00C1, wantHL: 0x8403},
+		{model: DMG, rom: testROM(nil), wantAF: 0x01B0, wantBC: 0x0013, wantDE: 0x00D8, wantHL: 0x014D},
+		{model: DMG, rom: nil, wantAF: 0x0180, wantBC: 0x0013, wantDE: 0x00D8, wantHL: 0x014D}, // header checksum is 0
+		{model: MGB, rom: testROM(nil), wantAF: 0xFFB0, wantBC: 0x0013, wantDE: 0x00D8, wantHL: 0x014D},
+		{model: SGB, rom: testROM(nil), wantAF: 0x0100, wantBC: 0x0014, wantDE: 0x0000, wantHL: 0xC060},
+		{model: SGB2, rom: testROM(nil), wantAF: 0xFF00, wantBC: 0x0014, wantDE: 0x0000, wantHL: 0xC060},
+	}
+	for _, tc := range cases {
+		t.Run(tc.model.String(), func(t *testing.T) {
+			req := require.New(t)
+			cpu := (&CPU{Mem: NewMemory(tc.rom)}).SkipBoot(tc.model)
+			req.Equal(tc.wantAF, cpu.AF())
+			req.Equal(tc.wantBC, cpu.BC())
+			req.Equal(tc.wantDE, cpu.DE())
+			req.Equal(tc.wantHL, cpu.HL())
+			req.Equal(uint16(0xFFFE), cpu.SP)
+			req.Equal(uint16(0x0100), cpu.PC)
+			req.False(cpu.Mem.BootActive())
+			req.Equal(uint8(0x91), cpu.Mem.Read(ADDR_LCDC))
+			req.Equal(uint8(0xFC), cpu.Mem.Read(0xFF47))
+		})
+	}
+
+	t.Run("NR52", func(t *testing.T) {
+		cpu := (&CPU{Mem: NewMemory(nil)}).SkipBoot(DMG)
+		require.Equal(t, uint8(0xF1), cpu.Mem.Read(0xFF26))
+		cpu = (&CPU{Mem: NewMemory(nil)}).SkipBoot(SGB)
+		require.Equal(t, uint8(0xF0), cpu.Mem.Read(0xFF26))
+	})
+}
+
 func TestBootLoader(t *testing.T) {
 	t.Skip()
 	// TODO: LD  ...
@@ -133,3 +175,52 @@ func TestBootLoader(t *testing.T) {
 // 	png.Encode(f, img)
 
 // }
+
+func TestBootROMModel(t *testing.T) {
+	pocket := slices.Clone(BootROM)
+	pocket[0xFD] = 0xFF
+
+	cases := []struct {
+		name    string
+		rom     []byte
+		want    Model
+		wantErr error
+	}{
+		{name: "dmg_boot.bin", rom: BootROM, want: DMG},
+		{name: "boot.bin", rom: BootROM, want: DMG},
+		{name: "dmg0_boot.bin", rom: BootROM, want: DMG0},
+		{name: "boot.bin", rom: pocket, want: MGB},
+		{name: "mgb_boot.bin", rom: pocket, want: MGB},
+		{name: "sgb_boot.bin", rom: BootROM, want: SGB},
+		{name: "sgb_boot.bin", rom: pocket, want: SGB2},
+		{name: "cgb_boot.bin", rom: make([]byte, CGB_BOOT_ROM_SIZE), want: CGB},
+		{name: "boot.bin", rom: make([]byte, CGB_BOOT_ROM_SIZE), want: CGB},
+		{name: "cgb_boot.bin", rom: BootROM, wantErr: ErrBootROMSize},
+		{name: "dmg_boot.bin", rom: BootROM[:0x80], wantErr: ErrBootROMSize},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := require.New(t)
+			got, err := BootROMModel(tc.name, tc.rom)
+			if tc.wantErr != nil {
+				req.ErrorIs(err, tc.wantErr)
+				return
+			}
+			req.NoError(err)
+			req.Equal(tc.want, got)
+		})
+	}
+}
+
+func TestCGBBootROMMapping(t *testing.T) {
+	req := require.New(t)
+	boot := make([]byte, CGB_BOOT_ROM_SIZE)
+	boot[0x0000] = 0x31
+	boot[0x0200] = 0xAA
+	mem := NewMemory(testROM(nil)).WithBootROM(boot, CGB)
+	req.Equal(uint8(0x31), mem.Read(0x0000))
+	req.Equal(uint8(0xC3), mem.Read(0x0101), "cartridge header is visible while booting")
+	req.Equal(uint8(0xAA), mem.Read(0x0200))
+	mem.DisableBoot()
+	req.Equal(uint8(0x00), mem.Read(0x0200))
+}
@@ -0,0 +1,95 @@
+package gameboy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A function the cpu is in, see CPU.CallStack.
+type CallFrame struct {
+	Func uint16 // the address called, or the interrupt vector
+	Bank int    // the ROM bank of Func
+	// The CALL or RST, or for an interrupt, the instruction it came before
+	Caller    uint16
+	SP        uint16 // where the return address is
+	Interrupt bool
+}
+
+// An error that stopped the cpu, see CPU.Err, with where it happened.
+type StepError struct {
+	Err error
+	PC  uint16 // the instruction that failed
+	// PC by its label, see CPU.Location
+	Location string
+	// The call stack at the time, as written by CPU.Backtrace
+	Backtrace string
+}
+
+func (e *StepError) Error() string {
+	return fmt.Sprintf("%v at %s", e.Err, e.Location)
+}
+
+func (e *StepError) Unwrap() error { return e.Err }
+
+// The functions the cpu is in, outermost first. The stack is kept next to
+// the real one: CALL, RST and interrupts push a frame, and a frame is popped
+// as soon as SP moves past its return address, whether that's by RET, RETI
+// or by code that pops the return address itself or resets SP. Calls made
+// before a state was loaded aren't known.
+func (cpu *CPU) CallStack() []CallFrame { return cpu.calls }
+
+// Updates the call stack after instr, at loc, ran; sp is SP from before.
+func (cpu *CPU) trackCall(loc uint16, instr Instruction, sp uint16) {
+	if cpu.SP == sp-2 && isCall(instr) {
+		cpu.calls = append(cpu.calls, CallFrame{Func: cpu.PC, Bank: cpu.Mem.Bank(cpu.PC), Caller: loc, SP: cpu.SP})
+	}
+	cpu.unwind()
+}
+
+// Pops the frames whose return address is no longer on the stack.
+func (cpu *CPU) unwind() {
+	for len(cpu.calls) > 0 && cpu.calls[len(cpu.calls)-1].SP < cpu.SP {
+		cpu.calls = cpu.calls[:len(cpu.calls)-1]
+	}
+}
+
+// Where the cpu is, one line per frame, innermost first: PC, then the
+// instruction each function was called from, e.g.
+//
+//	#0 00:0163 Nested+3
+//	#1 00:015C Store+3
+//	#2 00:0153 Main.loop+1
+func (cpu *CPU) Backtrace() string {
+	return cpu.backtrace(cpu.PC)
+}
+
+func (cpu *CPU) backtrace(pc uint16) string {
+	var b strings.Builder
+	line := func(n int, addr uint16, bank int, note string) {
+		fmt.Fprintf(&b, "#%d %02X:%04X", n, bank, addr)
+		if sym, ok := cpu.syms.Resolve(bank, addr); ok {
+			fmt.Fprintf(&b, " %s", sym.Name)
+		}
+		b.WriteString(note + "\n")
+	}
+	line(0, pc, cpu.Mem.Bank(pc), "")
+	for i := len(cpu.calls) - 1; i >= 0; i-- {
+		frame := cpu.calls[i]
+		// the caller is in the function of the frame below
+		bank := cpu.Mem.Bank(frame.Caller)
+		if i > 0 {
+			bank = cpu.calls[i-1].Bank
+		}
+		note := ""
+		if frame.Interrupt {
+			note = fmt.Sprintf(" (interrupted by $%04X)", frame.Func)
+		}
+		line(len(cpu.calls)-i, frame.Caller, bank, note)
+	}
+	return b.String()
+}
+
+func isCall(instr Instruction) bool {
+	name := instr.String()
+	return strings.HasPrefix(name, "CALL") || strings.HasPrefix(name, "RST")
+}
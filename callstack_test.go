@@ -0,0 +1,102 @@
+package gameboy
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallStack(t *testing.T) {
+	t.Run("calls", func(t *testing.T) {
+		req := require.New(t)
+		emu, dbg := newDebugger(debugProgram)
+		nested := dbg.AddBreakpoint(0x0163)
+		req.Equal(StopBreakpoint, dbg.Continue().Reason)
+		req.Equal([]CallFrame{
+			{Func: 0x0159, Caller: 0x0153, SP: 0xFFFC},
+			{Func: 0x0160, Caller: 0x015C, SP: 0xFFFA},
+		}, emu.CPU.CallStack())
+		req.Equal([]uint16{0x015F, 0x0156}, emu.CPU.Stack(), "the return addresses, innermost first")
+
+		dbg.Remove(nested.ID)
+		dbg.AddBreakpoint(0x0156)
+		req.Equal(StopBreakpoint, dbg.Continue().Reason)
+		req.Empty(emu.CPU.CallStack())
+		req.Empty(emu.CPU.Stack())
+	})
+
+	t.Run("interrupt", func(t *testing.T) {
+		req := require.New(t)
+		rom := testROM(MustAssemble(`
+SECTION "main", ROM0[$0150]
+	ld a, $01        ; vblank
+	ldh [$FF], a
+	ei
+.loop:
+	jr .loop         ; $0155
+`)[0].Data)
+		rom[0x0040] = code("RETI")
+		emu := NewEmulator(rom, EmulatorOptions{Model: DMG, SkipBoot: true})
+		dbg := NewDebugger(emu)
+		dbg.AddBreakpoint(0x0040)
+		req.Equal(StopBreakpoint, dbg.Continue().Reason)
+		req.Equal([]CallFrame{{Func: 0x0040, Caller: 0x0155, SP: 0xFFFC, Interrupt: true}}, emu.CPU.CallStack())
+		dbg.StepInto()
+		req.Empty(emu.CPU.CallStack())
+	})
+
+	t.Run("manual", func(t *testing.T) {
+		req := require.New(t)
+		emu, dbg := newDebugger(MustAssemble(`
+SECTION "main", ROM0[$0150]
+	call PopReturn   ; $0150
+	push bc          ; $0153
+	call Reset       ; $0154
+PopReturn:
+	pop hl           ; $0157
+	push hl          ; $0158
+	ret
+Reset:
+	ld sp, $FFFE     ; $015A
+	halt             ; $015D
+`)[0].Data)
+		dbg.AddBreakpoint(0x0158)
+		req.Equal(StopBreakpoint, dbg.Continue().Reason)
+		req.Empty(emu.CPU.CallStack(), "the return address was popped")
+
+		dbg.AddBreakpoint(0x015A)
+		req.Equal(StopBreakpoint, dbg.Continue().Reason)
+		req.Len(emu.CPU.CallStack(), 1, "pushing doesn't call")
+		dbg.StepInto()
+		req.Empty(emu.CPU.CallStack(), "SP was reset")
+	})
+}
+
+func TestBacktrace(t *testing.T) {
+	req := require.New(t)
+	prog := append([]byte{}, debugProgram...)
+	prog[0x0163-0x0150] = code("STOP")
+	emu := NewEmulator(testROM(prog), EmulatorOptions{Model: DMG, SkipBoot: true})
+	syms, err := ReadSymbols(strings.NewReader("00:0150 Main\n00:0152 Main.loop\n00:0159 Store\n00:0160 Nested\n"))
+	req.NoError(err)
+	emu.CPU.WithSymbols(syms)
+
+	err = emu.RunFrame()
+	var stepErr *StepError
+	req.True(errors.As(err, &stepErr))
+	req.Equal(uint16(0x0163), stepErr.PC)
+	req.ErrorIs(err, ErrNoMoreInstructions)
+	req.Equal("no more instructions at Nested+3", err.Error())
+	req.Equal(`#0 00:0163 Nested+3
+#1 00:015C Store+3
+#2 00:0153 Main.loop+1
+`, stepErr.Backtrace)
+	req.Same(stepErr, emu.CPU.Err(), "wrapped once")
+
+	emu = NewEmulator(testROM(prog), EmulatorOptions{Model: DMG, SkipBoot: true})
+	stop := NewDebugger(emu).Continue()
+	req.Equal(StopError, stop.Reason)
+	req.Equal(uint16(0x0163), stop.PC, "where it failed")
+}
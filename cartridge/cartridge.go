@@ -54,13 +54,47 @@ func New(data []byte, log ...*slog.Logger) Cartridge {
 	}
 }
 
-func (cart Cartridge) Write(addr uint16, data byte) {
-	cart.mbc.Write(cart.rom, cart.ram, addr, data)
+// Writes to ROM (0x0000-0x7FFF, i.e. the MBC registers) or RAM
+// (0xA000-0xBFFF). Writes the cartridge ignores return an error, see
+// errors.go; they are harmless on real hardware.
+func (cart Cartridge) Write(addr uint16, data byte) error {
+	return cart.mbc.Write(cart.rom, cart.ram, addr, data)
 }
+
+// Writes straight into the ROM image at offset, bypassing the MBC, like
+// flashing a cart. Used to load test programs.
+func (cart Cartridge) Poke(offset int, data byte) {
+	cart.rom[offset] = data
+}
+
 func (cart Cartridge) Read(addr uint16) byte {
 	return cart.mbc.Read(cart.rom, cart.ram, addr)
 }
 
+// The ROM bank currently mapped at 0x4000-0x7FFF.
+func (cart Cartridge) ROMBank() int {
+	return cart.mbc.ROMBank()
+}
+
+// The number of ROM banks in the image, counting a partial one.
+func (cart Cartridge) ROMBanks() int {
+	return (len(cart.rom) + ROM_BANK_SIZE - 1) / ROM_BANK_SIZE
+}
+
+// Reads ROM at addr as if bank was mapped at 0x4000-0x7FFF, without going
+// through the MBC; 0x0000-0x3FFF is always bank 0. Past the end of the image
+// it's 0xFF, like open bus.
+func (cart Cartridge) ReadROM(bank int, addr uint16) byte {
+	i := int(addr)
+	if addr >= ROM_BANK_SIZE {
+		i = bank*ROM_BANK_SIZE + int(addr)%ROM_BANK_SIZE
+	}
+	if i < 0 || i >= len(cart.rom) {
+		return 0xFF
+	}
+	return cart.rom[i]
+}
+
 // Mode of the memory bank controller
 type MBCType int
 
@@ -30,3 +30,35 @@ func TestTetris(t *testing.T) {
 	cart := New(TETRIS)
 	req.Equal("TETRIS", cart.rom.Title())
 }
+
+func TestMBC0(t *testing.T) {
+	req := require.New(t)
+	data := make([]byte, 32*kB)
+	data[0x0150] = 0x12
+	data[0x0149] = 0x02 // 8kB RAM
+	cart := New(data)
+
+	req.ErrorIs(cart.Write(0x0150, 0x34), ErrROMWrite)
+	req.Equal(uint8(0x12), cart.Read(0x0150), "ROM writes are ignored")
+	cart.Poke(0x0150, 0x34)
+	req.Equal(uint8(0x34), cart.Read(0x0150))
+
+	req.NoError(cart.Write(0xA000, 0x56))
+	req.Equal(uint8(0x56), cart.Read(0xA000))
+
+	noRAM := New(make([]byte, 32*kB))
+	req.ErrorIs(noRAM.Write(0xA000, 0x56), ErrNoRAM)
+	req.Equal(uint8(0xFF), noRAM.Read(0xA000))
+}
+
+func TestReadROM(t *testing.T) {
+	req := require.New(t)
+	cart := newTestCart(t, 5, 3)
+	req.Equal(64, cart.ROMBanks())
+	cart.WriteROMBankLow(2)
+
+	req.Equal(uint8(0x03), cart.ReadROM(7, 0x0147), "bank 0 is fixed")
+	req.Equal(uint8(0x07), cart.ReadROM(7, 0x4001))
+	req.Equal(uint8(0x02), cart.Read(0x4001), "the MBC is untouched")
+	req.Equal(uint8(0xFF), cart.ReadROM(64, 0x4000))
+}
@@ -0,0 +1,17 @@
+package cartridge
+
+import "errors"
+
+// Accesses the hardware shrugs off, but that are likely bugs in the game. The
+// write is ignored (or, for ErrBankingMode, masked) and one of these returned,
+// so callers can decide whether they care.
+var (
+	// MBC0 carts have nothing to write to in the ROM area
+	ErrROMWrite = errors.New("write to ROM without a memory bank controller")
+	// Access to 0xA000-0xBFFF on a cart without RAM
+	ErrNoRAM = errors.New("cartridge has no RAM")
+	// Write to RAM that hasn't been enabled through 0x0000-0x1FFF
+	ErrRAMDisabled = errors.New("cartridge RAM is disabled")
+	// Banking mode other than 0 or 1; only bit 0 is used
+	ErrBankingMode = errors.New("banking mode other than 0 or 1")
+)
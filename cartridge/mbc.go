@@ -1,14 +1,25 @@
 package cartridge
 
+import "encoding"
+
 // A Memory Bank Controller (MBC) manages access to memory on
 // the Cartridge.
 // The Game Boy only has 16-bit addressing, meaning the maximum
 // size of a game is 64kB without any tricks. The trick is to
 // use a MBC that "swaps" out memory segments.
 // Each bank is 16kB
+//
+// Neither method panics: reads from nowhere return 0xFF (open bus), and
+// writes that have no effect return one of the errors in errors.go.
 type MemoryBankController interface {
-	Write(rom cartridgeROM, ram cartridgeRAM, addr uint16, data byte)
+	Write(rom cartridgeROM, ram cartridgeRAM, addr uint16, data byte) error
 	Read(rom cartridgeROM, ram cartridgeRAM, addr uint16) byte
+	// The ROM bank currently mapped at 0x4000-0x7FFF
+	ROMBank() int
+
+	// The registers, for save states
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
 }
 
 const kB = 1024
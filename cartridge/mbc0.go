@@ -1,17 +1,21 @@
 package cartridge
 
-import "fmt"
-
+// No memory bank controller: 32kB of ROM mapped directly, and optionally up to
+// 8kB of RAM.
 type MBC0 struct {
 }
 
-func (mbc *MBC0) Write(rom cartridgeROM, ram cartridgeRAM, addr uint16, data byte) {
+func (mbc *MBC0) Write(rom cartridgeROM, ram cartridgeRAM, addr uint16, data byte) error {
 	switch {
-	case addr < 0x8000: // rom banks, 32k size
-		rom[addr] = data
-	default:
-		panic(fmt.Sprintf("MBC0: Cannot write to addr %#4x", addr))
+	case addr < 0x8000: // rom banks, 32k size; read only
+		return ErrROMWrite
+	case 0xA000 <= addr && addr < 0xC000:
+		if int(addr-0xA000) >= len(ram) {
+			return ErrNoRAM
+		}
+		ram[addr-0xA000] = data
 	}
+	return nil
 }
 
 func (mbc *MBC0) Read(rom cartridgeROM, ram cartridgeRAM, addr uint16) byte {
@@ -19,7 +23,12 @@ func (mbc *MBC0) Read(rom cartridgeROM, ram cartridgeRAM, addr uint16) byte {
 	case addr < 0x8000:
 		return rom[addr]
 	case 0xA000 <= addr && addr < 0xC000:
+		if int(addr-0xA000) >= len(ram) {
+			return 0xFF
+		}
 		return ram[addr-0xA000]
 	}
-	panic(fmt.Sprintf("MBC0: illegal memory access: %2x", addr))
+	return 0xFF
 }
+
+func (mbc *MBC0) ROMBank() int { return 1 }
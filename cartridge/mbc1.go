@@ -1,7 +1,6 @@
 package cartridge
 
 import (
-	"fmt"
 	"log/slog"
 )
 
@@ -40,7 +39,9 @@ func (mbc *MBC1) romIndex() int {
 	return mbc.romIdxLo + mbc.romIdxHi<<5
 }
 
-func (mbc *MBC1) Write(rom cartridgeROM, ram cartridgeRAM, addr uint16, data byte) {
+func (mbc *MBC1) ROMBank() int { return mbc.romIndex() }
+
+func (mbc *MBC1) Write(rom cartridgeROM, ram cartridgeRAM, addr uint16, data byte) error {
 	log := mbc.log
 
 	// https://gbdev.io/pandocs/MBC1.html#mbc1
@@ -67,25 +68,25 @@ func (mbc *MBC1) Write(rom cartridgeROM, ram cartridgeRAM, addr uint16, data byt
 		switch mbc.mode {
 		case modeSimple:
 			if index > rom.BankCount() {
-				return
+				return nil
 			}
 			mbc.ramIdx = index
 			log.Info("new RAM bank", slog.Int("index", mbc.romIndex()))
-			return
 		case modeAdvanced:
 			if (mbc.romIdxLo + (index << 5)) > rom.BankCount() {
-				return
+				return nil
 			}
 			mbc.romIdxHi = index
 			log.Info("new ROM bank", slog.Int("index", mbc.romIndex()))
-		default:
-			panic("Unknown mode")
 		}
 
 	case 0x6000 <= addr && addr < 0x8000: // Banking Mode Select
-		if data != 0 && data != 1 {
-			panic(fmt.Sprintf("write to memory - expected only 0 or 1, got %x", data))
+		// The register is a single bit; the rest is ignored
+		var err error
+		if data > 1 {
+			err = ErrBankingMode
 		}
+		data &= 0x01
 
 		// If the cart is not large enough to use the 2-bit register (≤ 8 KiB RAM and ≤ 512 KiB ROM) this mode select has no observable effect.
 		if mbc.mode == modeSimple && rom.RAMSize() <= 8*kB {
@@ -101,7 +102,18 @@ func (mbc *MBC1) Write(rom cartridgeROM, ram cartridgeRAM, addr uint16, data byt
 			mbc.mode = modeSimple
 		}
 		log.Info("Ram mode switch", slog.String("mode", mbc.mode.String()))
+		return err
+
+	case 0xA000 <= addr && addr < 0xC000: // Cartridge RAM
+		if len(ram) == 0 {
+			return ErrNoRAM
+		}
+		if !mbc.ramEnabled {
+			return ErrRAMDisabled
+		}
+		ram.Bank(mbc.ramIdx)[addr-0xA000] = data
 	}
+	return nil
 }
 
 func (mbc *MBC1) Read(rom cartridgeROM, ram cartridgeRAM, addr uint16) byte {
@@ -111,7 +123,7 @@ func (mbc *MBC1) Read(rom cartridgeROM, ram cartridgeRAM, addr uint16) byte {
 	case 0x4000 <= addr && addr < 0x8000: // Switchable ROM Bank
 		return rom.Bank(mbc.romIndex())[addr-0x4000]
 	case 0xA000 <= addr && addr < 0xC000: // Cartridge RAM
-		if !mbc.ramEnabled {
+		if !mbc.ramEnabled || len(ram) == 0 {
 			return 0xFF // any random value, really
 		}
 
@@ -119,5 +131,5 @@ func (mbc *MBC1) Read(rom cartridgeROM, ram cartridgeRAM, addr uint16) byte {
 		return bank[addr-0xA000]
 	}
 
-	panic(fmt.Sprintf("Not implemented: %#4x", addr))
+	return 0xFF
 }
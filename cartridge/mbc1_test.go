@@ -4,6 +4,8 @@ import (
 	"log/slog"
 	"os"
 	"testing"
+
+	"github.com/stretchr/testify/require"
 )
 
 // code RAM size  banks
@@ -96,6 +98,37 @@ func TestReadRAM(t *testing.T) {
 
 }
 
+func TestWriteRAM(t *testing.T) {
+	req := require.New(t)
+	cart := newTestCart(t, 5, 3)
+	req.ErrorIs(cart.Write(0xA000, 0x42), ErrRAMDisabled)
+	cart.EnableRAM()
+	req.NoError(cart.Write(0xA000, 0x42))
+	cart.ExpectRead(0xA000, 0x42)
+
+	cart.WriteRAMBank(1)
+	cart.ExpectRead(0xA000, 0x00)
+	req.NoError(cart.Write(0xA000, 0x43))
+	cart.WriteRAMBank(0)
+	cart.ExpectRead(0xA000, 0x42)
+
+	noRAM := newTestCart(t, 5, 0)
+	noRAM.EnableRAM()
+	req.ErrorIs(noRAM.Write(0xA000, 0x42), ErrNoRAM)
+	noRAM.ExpectRead(0xA000, 0xFF)
+}
+
+func TestBankingModeMask(t *testing.T) {
+	req := require.New(t)
+	cart := newTestCart(t, 5, 3)
+	req.ErrorIs(cart.Write(0x6000, 0x03), ErrBankingMode)
+	req.Equal(modeAdvanced, cart.mbc.(*MBC1).mode)
+	req.ErrorIs(cart.Write(0x6000, 0x02), ErrBankingMode)
+	req.Equal(modeSimple, cart.mbc.(*MBC1).mode)
+	req.NoError(cart.Write(0x6000, 0x01))
+	req.Equal(modeAdvanced, cart.mbc.(*MBC1).mode)
+}
+
 func TestRAMTrick(t *testing.T) {
 	// "Even with smaller ROMs that use less than 5 bits for bank selection, the full 5-bit register is still compared for the bank 00→01 translation logic. [...]"
 	cart := newTestCart(t, 3, 4)
@@ -168,3 +201,24 @@ func logger(debug ...bool) *slog.Logger {
 		},
 	}))
 }
+
+func TestMarshalBinary(t *testing.T) {
+	req := require.New(t)
+	cart := newTestCart(t, 5, 3)
+	cart.EnableRAM()
+	cart.WriteBankMode(modeAdvanced)
+	cart.WriteROMBankLow(3)
+	cart.WriteROMBankHigh(1)
+	req.NoError(cart.Write(0xA000, 0x42))
+
+	b, err := cart.MarshalBinary()
+	req.NoError(err)
+
+	other := newTestCart(t, 5, 3)
+	req.NoError(other.UnmarshalBinary(b))
+	other.ExpectROMIndex(0b_0010_0011)
+	other.ExpectRead(0xA000, 0x42)
+
+	req.ErrorIs(other.UnmarshalBinary(b[:10]), ErrState)
+	other.ExpectRead(0xA000, 0x42)
+}
@@ -4,6 +4,9 @@ type cartridgeRAM []byte
 
 const RAM_BANK_SIZE = 8 * kB
 
+// Banks past the end wrap around, as the cart only decodes the address lines
+// it needs.
 func (ram cartridgeRAM) Bank(i int) []byte {
+	i %= len(ram) / RAM_BANK_SIZE
 	return ram[i*RAM_BANK_SIZE : (i+1)*RAM_BANK_SIZE]
 }
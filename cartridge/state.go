@@ -0,0 +1,74 @@
+package cartridge
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+var ErrState = errors.New("invalid cartridge state")
+
+// Encodes the MBC registers and the cartridge RAM, i.e. everything but the
+// ROM. Used for save states.
+func (cart Cartridge) MarshalBinary() ([]byte, error) {
+	regs, err := cart.mbc.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	b := binary.LittleEndian.AppendUint32(nil, uint32(len(regs)))
+	b = append(b, regs...)
+	return append(b, cart.ram...), nil
+}
+
+// Restores what MarshalBinary encoded. The cartridge must have been created
+// from the same ROM.
+func (cart *Cartridge) UnmarshalBinary(b []byte) error {
+	if len(b) < 4 {
+		return ErrState
+	}
+	n := int(binary.LittleEndian.Uint32(b))
+	b = b[4:]
+	if len(b) != n+len(cart.ram) {
+		return fmt.Errorf("%w: got %d bytes, want %d", ErrState, len(b), n+len(cart.ram))
+	}
+	if err := cart.mbc.UnmarshalBinary(b[:n]); err != nil {
+		return err
+	}
+	copy(cart.ram, b[n:])
+	return nil
+}
+
+func (mbc *MBC0) MarshalBinary() ([]byte, error) { return nil, nil }
+
+func (mbc *MBC0) UnmarshalBinary(b []byte) error {
+	if len(b) != 0 {
+		return ErrState
+	}
+	return nil
+}
+
+func (mbc *MBC1) MarshalBinary() ([]byte, error) {
+	var enabled byte
+	if mbc.ramEnabled {
+		enabled = 1
+	}
+	return []byte{
+		byte(mbc.mode),
+		byte(mbc.romIdxLo),
+		byte(mbc.romIdxHi),
+		byte(mbc.ramIdx),
+		enabled,
+	}, nil
+}
+
+func (mbc *MBC1) UnmarshalBinary(b []byte) error {
+	if len(b) != 5 {
+		return ErrState
+	}
+	mbc.mode = bmode(b[0] & 0x01)
+	mbc.romIdxLo = int(b[1])
+	mbc.romIdxHi = int(b[2])
+	mbc.ramIdx = int(b[3])
+	mbc.ramEnabled = b[4] == 1
+	return nil
+}
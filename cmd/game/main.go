@@ -5,13 +5,22 @@ import (
 	"log"
 	"os"
 	"runtime/pprof"
+	"strings"
 
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/kvalv/gameboy"
 	"github.com/kvalv/gameboy/ui"
 )
 
 var cpuprofile = flag.String("cpuprofile", "", "write cpu profile to file")
 var file = flag.String("file", "", "gameboy file to run")
+var skipBoot = flag.Bool("skip-boot", false, "start at 0x0100 without running the boot ROM")
+var model = flag.String("model", "", "hardware model (DMG0, DMG, MGB, SGB, SGB2, CGB); defaults to the boot ROM's")
+var boot = flag.String("boot", "", "boot ROM file, e.g. dmg_boot.bin; defaults to the built-in DMG one")
+var record = flag.String("record", "", "record the input into this movie file, written when quitting with Q")
+var play = flag.String("play", "", "play back this movie file")
+var symFile = flag.String("sym", "", "rgblink .sym or .map file, to show labels and break at them")
+var breaks = flag.String("break", "", "comma-separated labels or addresses to pause at, e.g. Main.loop,02:4000")
 
 func main() {
 	flag.Parse()
@@ -29,7 +38,36 @@ func main() {
 		log.Fatal("file missing")
 	}
 
-	g := ui.NewGame(*file)
+	bootROM, m, err := gameboy.LoadBootROM(*boot)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *model != "" {
+		if m, err = gameboy.ParseModel(*model); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var syms *gameboy.Symbols
+	if *symFile != "" {
+		if syms, err = gameboy.LoadSymbols(*symFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	var breakpoints []string
+	if *breaks != "" {
+		breakpoints = strings.Split(*breaks, ",")
+	}
+
+	g := ui.NewGame(*file, ui.Options{
+		BootROM:     bootROM,
+		Model:       m,
+		SkipBoot:    *skipBoot,
+		Record:      *record,
+		Play:        *play,
+		Symbols:     syms,
+		Breakpoints: breakpoints,
+	})
 
 	// ebiten.SetWindowSize(200, 200)
 	ebiten.SetWindowTitle("Game Boy")
@@ -0,0 +1,59 @@
+// Runs a Debug Adapter Protocol server for editors such as VS Code, see
+// package dap. The ROM to debug is given by the client's launch request:
+//
+//	{"type": "gameboy", "request": "launch", "program": "game.gb", "sources": ["main.asm"]}
+//
+// By default it talks over stdin and stdout, as editors start it; with
+// -listen it waits for clients on a TCP address instead.
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"log/slog"
+	"net"
+	"os"
+
+	"github.com/kvalv/gameboy/dap"
+)
+
+var listen = flag.String("listen", "", "address to wait for clients on, instead of using stdio")
+var verbose = flag.Bool("v", false, "log the messages to stderr")
+
+func main() {
+	flag.Parse()
+	level := slog.LevelInfo
+	if *verbose {
+		level = slog.LevelDebug
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+
+	if *listen == "" {
+		stdio := struct {
+			io.Reader
+			io.Writer
+		}{os.Stdin, os.Stdout}
+		if err := dap.NewServer().WithLog(logger).Serve(stdio); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	l, err := net.Listen("tcp", *listen)
+	if err != nil {
+		log.Fatal(err)
+	}
+	logger.Info("waiting for clients", "addr", l.Addr())
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			log.Fatal(err)
+		}
+		logger.Info("client connected", "addr", c.RemoteAddr())
+		if err := dap.NewServer().WithLog(logger).Serve(c); err != nil {
+			logger.Warn("client failed", "err", err)
+		}
+		c.Close()
+	}
+}
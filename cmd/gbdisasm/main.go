@@ -0,0 +1,96 @@
+// Disassembles a ROM into rgbds syntax: either a range of addresses, or the
+// whole ROM as a project that reassembles to the same bytes.
+//
+//	gbdisasm -file game.gb -start 0x0150 -end 0x0200
+//	gbdisasm -file game.gb -bank 2 -start 0x4000 -end 0x4100
+//	gbdisasm -file game.gb -sym game.sym -start 0x0150 -end 0x0200
+//	gbdisasm -file game.gb -out game/ && make -C game/
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/kvalv/gameboy"
+	"github.com/kvalv/gameboy/disasm"
+)
+
+var file = flag.String("file", "", "gameboy file to disassemble")
+var out = flag.String("out", "", "write an rgbds project to this directory")
+var bank = flag.Int("bank", 0, "ROM bank of the range, for addresses 0x4000-0x7FFF")
+var start = flag.String("start", "0x0100", "first address of the range")
+var end = flag.String("end", "", "address after the range; defaults to the end of the bank")
+var symFile = flag.String("sym", "", "rgblink .sym or .map file, to name addresses by their labels")
+
+func main() {
+	flag.Parse()
+	if *file == "" {
+		log.Fatal("file missing")
+	}
+	rom, err := os.ReadFile(*file)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *out != "" {
+		p := disasm.Analyze(rom)
+		if err := p.WriteProject(*out); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("wrote %d banks to %s\n", p.Banks(), *out)
+		return
+	}
+
+	from, err := strconv.ParseUint(*start, 0, 16)
+	if err != nil {
+		log.Fatalf("-start: %v", err)
+	}
+	to := 2 * disasm.BANK_SIZE
+	if from < disasm.BANK_SIZE {
+		to = disasm.BANK_SIZE
+	}
+	if *end != "" {
+		n, err := strconv.ParseUint(*end, 0, 32)
+		if err != nil {
+			log.Fatalf("-end: %v", err)
+		}
+		to = int(min(n, 0x10000))
+	}
+
+	// the range is seen through the bank it's in
+	base := disasm.Location{Bank: *bank, Addr: disasm.BANK_SIZE}
+	if from < disasm.BANK_SIZE {
+		base = disasm.Location{}
+	}
+	offset := base.Offset()
+	if offset >= len(rom) {
+		log.Fatalf("bank %d is outside the %d byte ROM", *bank, len(rom))
+	}
+	mem := disasm.Bytes(rom[offset:min(len(rom), offset+disasm.BANK_SIZE)], base.Addr)
+
+	var syms *gameboy.Symbols
+	if *symFile != "" {
+		if syms, err = gameboy.LoadSymbols(*symFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	// other banks than the range's don't matter, see Symbols.Resolve
+	label := func(addr uint16) string {
+		sym, _ := syms.Resolve(base.Bank, addr)
+		return sym.Name
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	for _, in := range disasm.Range(mem, uint16(from), to) {
+		if name := label(in.Addr); name != "" && !strings.Contains(name, "+") {
+			fmt.Fprintf(w, "%s:\n", name)
+		}
+		fmt.Fprintf(w, "%02X:%04X  % -8X  %s\n", base.Bank, in.Addr, in.Bytes, in.Format(label))
+	}
+}
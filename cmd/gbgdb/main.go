@@ -0,0 +1,55 @@
+// Runs a ROM without a window under control of a GDB client, see package gdb.
+//
+//	gbgdb -file game.gb -listen localhost:1234
+//	gdb -ex 'target remote localhost:1234'
+package main
+
+import (
+	"flag"
+	"log"
+	"log/slog"
+	"net"
+	"os"
+
+	"github.com/kvalv/gameboy"
+	"github.com/kvalv/gameboy/gdb"
+)
+
+var file = flag.String("file", "", "gameboy file to debug")
+var boot = flag.String("boot", "", "boot ROM file; defaults to the built-in DMG one")
+var skipBoot = flag.Bool("skip-boot", false, "start at 0x0100 without running the boot ROM")
+var listen = flag.String("listen", "localhost:1234", "address to wait for GDB on")
+var verbose = flag.Bool("v", false, "log the packets")
+
+func main() {
+	flag.Parse()
+	if *file == "" {
+		log.Fatal("file missing")
+	}
+	rom, err := os.ReadFile(*file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	bootROM, model, err := gameboy.LoadBootROM(*boot)
+	if err != nil {
+		log.Fatal(err)
+	}
+	emu := gameboy.NewEmulator(rom, gameboy.EmulatorOptions{
+		BootROM:  bootROM,
+		Model:    model,
+		SkipBoot: *skipBoot,
+	})
+
+	level := slog.LevelInfo
+	if *verbose {
+		level = slog.LevelDebug
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+
+	l, err := net.Listen("tcp", *listen)
+	if err != nil {
+		log.Fatal(err)
+	}
+	logger.Info("waiting for gdb", "addr", l.Addr())
+	log.Fatal(gdb.NewServer(emu).WithLog(logger).Serve(l))
+}
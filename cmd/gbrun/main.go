@@ -0,0 +1,329 @@
+// Runs a ROM without a window, e.g. in CI, until a condition is met or a
+// number of frames have passed, and dumps what happened.
+//
+//	gbrun -file game.gb -frames 600 -png out.png
+//	gbrun -file cpu_instrs.gb -skip-boot -until-serial Passed -serial -
+//	gbrun -file game.gb -movie bug.gbm -until-mem 0xC0A0=0x01 -regs -
+//	gbrun -file game.gb -frames 900 -capture bug.gif -capture-from 600 -scale 2
+//	gbrun -file game.gb -sym game.sym -until-pc Main.loop -regs -
+//
+// Exit codes:
+//
+//	0  the condition was met, or all frames ran if there's no condition
+//	1  the condition wasn't met within the frames
+//	2  the emulator failed: the cpu stopped or panicked, or a movie desynced
+//	3  bad flags, or files that can't be read or written
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/kvalv/gameboy"
+)
+
+const (
+	EXIT_OK       = 0
+	EXIT_NOT_MET  = 1
+	EXIT_EMULATOR = 2
+	EXIT_USAGE    = 3
+)
+
+var file = flag.String("file", "", "gameboy file to run")
+var boot = flag.String("boot", "", "boot ROM file; defaults to the built-in DMG one")
+var model = flag.String("model", "", "hardware model (DMG0, DMG, MGB, SGB, SGB2, CGB); defaults to the boot ROM's")
+var skipBoot = flag.Bool("skip-boot", false, "start at 0x0100 without running the boot ROM")
+var frames = flag.Int("frames", 3600, "stop after this many frames")
+var untilPC = flag.String("until-pc", "", "stop when the cpu is about to execute this address or label, e.g. 0x0150 or Main.loop")
+var untilSerial = flag.String("until-serial", "", "stop when the serial output contains this string")
+var untilMem = flag.String("until-mem", "", "stop when memory holds a value, e.g. 0xC000=0x42")
+var movie = flag.String("movie", "", "movie file to take the input from")
+var pngOut = flag.String("png", "", "write the last frame to this PNG file")
+var scale = flag.Int("scale", 1, "size of a Game Boy pixel in the PNG and video")
+var palette = flag.String("palette", "gray", "colors of the PNG and video: gray, green, or four hex colors, lightest first")
+var capture = flag.String("capture", "", "record the frames into this video file, .gif or .y4m")
+var captureFrom = flag.Int("capture-from", 1, "first frame to record")
+var captureTo = flag.Int("capture-to", 0, "last frame to record; 0 records until the end")
+var serialOut = flag.String("serial", "", "write the serial output to this file, - for stdout")
+var regsOut = flag.String("regs", "", "write a register dump to this file, - for stdout")
+var symFile = flag.String("sym", "", "rgblink .sym or .map file, for labels in -until-pc and the register dump")
+
+func main() {
+	flag.Parse()
+	os.Exit(run())
+}
+
+func run() int {
+	if *file == "" {
+		return usage("file missing")
+	}
+	rom, err := os.ReadFile(*file)
+	if err != nil {
+		return usage("%v", err)
+	}
+	bootROM, m, err := gameboy.LoadBootROM(*boot)
+	if err != nil {
+		return usage("%v", err)
+	}
+	if *model != "" {
+		if m, err = gameboy.ParseModel(*model); err != nil {
+			return usage("%v", err)
+		}
+	}
+	var syms *gameboy.Symbols
+	if *symFile != "" {
+		if syms, err = gameboy.LoadSymbols(*symFile); err != nil {
+			return usage("%v", err)
+		}
+	}
+	var serial bytes.Buffer
+	stop, err := condition(&serial, syms)
+	if err != nil {
+		return usage("%v", err)
+	}
+	pal, err := gameboy.ParsePalette(*palette)
+	if err != nil {
+		return usage("%v", err)
+	}
+
+	emu := gameboy.NewEmulator(rom, gameboy.EmulatorOptions{
+		BootROM:  bootROM,
+		Model:    m,
+		SkipBoot: *skipBoot,
+		Serial:   &serial,
+	})
+	emu.CPU.WithSymbols(syms)
+
+	var player *gameboy.MoviePlayer
+	if *movie != "" {
+		f, err := os.Open(*movie)
+		if err != nil {
+			return usage("%v", err)
+		}
+		mv, err := gameboy.ReadMovie(f)
+		f.Close()
+		if err != nil {
+			return usage("%s: %v", *movie, err)
+		}
+		if player, err = gameboy.NewMoviePlayer(emu, mv); err != nil {
+			return usage("%s: %v", *movie, err)
+		}
+	}
+
+	var video *recording
+	if *capture != "" {
+		if video, err = startRecording(*capture, gameboy.VideoOptions{Scale: *scale, Palette: &pal}); err != nil {
+			return usage("%v", err)
+		}
+	}
+
+	code := EXIT_NOT_MET
+	if stop == nil {
+		code = EXIT_OK
+	}
+	met, err := runFrames(emu, player, stop, video.frame)
+	if err := video.close(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return EXIT_USAGE
+	}
+	switch {
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "frame %d: %v\n", emu.Frame(), err)
+		var stepErr *gameboy.StepError
+		if errors.As(err, &stepErr) {
+			fmt.Fprint(os.Stderr, stepErr.Backtrace)
+		}
+		code = EXIT_EMULATOR
+	case met:
+		fmt.Fprintf(os.Stderr, "condition met at frame %d, PC %#04x (%s)\n", emu.Frame(), emu.CPU.PC, emu.CPU.Location(emu.CPU.PC))
+		code = EXIT_OK
+	case stop != nil:
+		fmt.Fprintf(os.Stderr, "condition not met after %d frames\n", emu.Frame())
+	}
+
+	if err := dump(emu, serial.Bytes(), pal); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return EXIT_USAGE
+	}
+	return code
+}
+
+// Runs up to -frames frames, driven by the movie until it runs out, and calls
+// done after each one. Panics in the emulator are returned as errors.
+func runFrames(emu *gameboy.Emulator, player *gameboy.MoviePlayer, stop func(*gameboy.CPU) bool, done func(*gameboy.Emulator) error) (met bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic at %#04x: %v", emu.CPU.PC, r)
+		}
+	}()
+	for emu.Frame() < *frames {
+		playing := player != nil && !player.Done()
+		if playing {
+			if err := player.Press(); err != nil {
+				return false, err
+			}
+		}
+		met, err := emu.RunFrameUntil(stop)
+		if met || err != nil {
+			return met, err
+		}
+		if playing {
+			if err := player.EndFrame(); err != nil {
+				return false, err
+			}
+		}
+		if err := done(emu); err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// The -capture video; a nil one records nothing.
+type recording struct {
+	f *os.File
+	w gameboy.VideoWriter
+}
+
+func startRecording(name string, opts gameboy.VideoOptions) (*recording, error) {
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	w, err := gameboy.NewVideoWriter(f, name, opts)
+	if err != nil {
+		f.Close()
+		os.Remove(name)
+		return nil, err
+	}
+	return &recording{f: f, w: w}, nil
+}
+
+// Records the frame just completed if it's within -capture-from and
+// -capture-to. Frames are counted from 1.
+func (r *recording) frame(emu *gameboy.Emulator) error {
+	n := emu.Frame()
+	if r == nil || n < *captureFrom || *captureTo > 0 && n > *captureTo {
+		return nil
+	}
+	return r.w.WriteFrame(emu.Framebuffer())
+}
+
+func (r *recording) close() error {
+	if r == nil {
+		return nil
+	}
+	if err := r.w.Close(); err != nil {
+		r.f.Close()
+		return fmt.Errorf("%s: %w", r.f.Name(), err)
+	}
+	return r.f.Close()
+}
+
+// Parses the -until flags into a function that says whether to stop. It's
+// called before every instruction, so it had better be quick. Returns nil if
+// there are no conditions.
+func condition(serial *bytes.Buffer, syms *gameboy.Symbols) (func(*gameboy.CPU) bool, error) {
+	var conds []func(*gameboy.CPU) bool
+	if *untilPC != "" {
+		at := gameboy.Symbol{Bank: gameboy.ANY_BANK}
+		if pc, err := strconv.ParseUint(*untilPC, 0, 16); err == nil {
+			at.Addr = uint16(pc)
+		} else if at, err = syms.Locate(*untilPC); err != nil {
+			return nil, fmt.Errorf("-until-pc: %w", err)
+		}
+		banked := at.Bank != gameboy.ANY_BANK && at.Addr >= 0x4000 && at.Addr < 0x8000
+		conds = append(conds, func(cpu *gameboy.CPU) bool {
+			return cpu.PC == at.Addr && (!banked || cpu.Mem.Bank(at.Addr) == at.Bank)
+		})
+	}
+	if *untilSerial != "" {
+		s := []byte(*untilSerial)
+		checked := 0 // only look again once there's more output
+		conds = append(conds, func(*gameboy.CPU) bool {
+			if serial.Len() == checked {
+				return false
+			}
+			checked = serial.Len()
+			return bytes.Contains(serial.Bytes(), s)
+		})
+	}
+	if *untilMem != "" {
+		addrStr, valStr, ok := strings.Cut(*untilMem, "=")
+		if !ok {
+			return nil, fmt.Errorf("-until-mem: want ADDR=VALUE, got %q", *untilMem)
+		}
+		addr, err := strconv.ParseUint(addrStr, 0, 16)
+		if err != nil {
+			return nil, fmt.Errorf("-until-mem: %w", err)
+		}
+		val, err := strconv.ParseUint(valStr, 0, 8)
+		if err != nil {
+			return nil, fmt.Errorf("-until-mem: %w", err)
+		}
+		conds = append(conds, func(cpu *gameboy.CPU) bool {
+			return cpu.Mem.Read(uint16(addr)) == uint8(val)
+		})
+	}
+	if len(conds) == 0 {
+		return nil, nil
+	}
+	return func(cpu *gameboy.CPU) bool {
+		for _, c := range conds {
+			if c(cpu) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func dump(emu *gameboy.Emulator, serial []byte, pal gameboy.Palette) error {
+	if *pngOut != "" {
+		f, err := os.Create(*pngOut)
+		if err != nil {
+			return err
+		}
+		opts := gameboy.PNGOptions{Scale: *scale, Palette: &pal}
+		if err := gameboy.EncodePNG(f, emu.Framebuffer(), opts); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	if err := output(*serialOut, func(w io.Writer) { w.Write(serial) }); err != nil {
+		return err
+	}
+	return output(*regsOut, emu.CPU.Dump)
+}
+
+// Writes to the named file, or stdout for "-". Does nothing without a name.
+func output(name string, write func(io.Writer)) error {
+	switch name {
+	case "":
+		return nil
+	case "-":
+		write(os.Stdout)
+		return nil
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	write(f)
+	return f.Close()
+}
+
+func usage(format string, args ...any) int {
+	fmt.Fprintf(os.Stderr, "gbrun: "+format+"\n", args...)
+	flag.Usage()
+	return EXIT_USAGE
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kvalv/gameboy"
+	"github.com/stretchr/testify/require"
+)
+
+// Sets the flags for one run, putting them back afterwards
+func setFlags(t *testing.T, flags map[string]string) {
+	for name, value := range flags {
+		f := flag.Lookup(name)
+		old := f.Value.String()
+		require.NoError(t, f.Value.Set(value))
+		t.Cleanup(func() { f.Value.Set(old) })
+	}
+}
+
+// Writes the ROM into dir, along with a movie of it holding the given buttons
+// on each frame. Returns both file names.
+func writeMovie(t *testing.T, dir string, rom []byte, inputs []gameboy.Buttons) (string, string) {
+	req := require.New(t)
+	romFile := filepath.Join(dir, "game.gb")
+	req.NoError(os.WriteFile(romFile, rom, 0o644))
+
+	bootROM, m, err := gameboy.LoadBootROM("")
+	req.NoError(err)
+	emu := gameboy.NewEmulator(rom, gameboy.EmulatorOptions{BootROM: bootROM, Model: m, SkipBoot: true})
+	rec, err := gameboy.NewMovieRecorder(emu, 2, true)
+	req.NoError(err)
+	for _, b := range inputs {
+		emu.SetButtons(b)
+		req.NoError(rec.RunFrame())
+	}
+	movieFile := filepath.Join(dir, "game.gbm")
+	f, err := os.Create(movieFile)
+	req.NoError(err)
+	req.NoError(rec.Movie().Save(f))
+	req.NoError(f.Close())
+	return romFile, movieFile
+}
+
+func TestMovieCapture(t *testing.T) {
+	req := require.New(t)
+	dir := t.TempDir()
+	rom := make([]byte, 32*1024)
+	copy(rom[0x0100:], []byte{0x18, 0xFE}) // jr @
+	romFile, movieFile := writeMovie(t, dir, rom, make([]gameboy.Buttons, 4))
+
+	// unlike a GIF, y4m keeps frames that didn't change
+	videoFile := filepath.Join(dir, "game.y4m")
+	setFlags(t, map[string]string{
+		"file":      romFile,
+		"skip-boot": "true",
+		"frames":    "6",
+		"movie":     movieFile,
+		"capture":   videoFile,
+	})
+	req.Equal(EXIT_OK, run())
+
+	video, err := os.ReadFile(videoFile)
+	req.NoError(err)
+	req.Equal(6, bytes.Count(video, []byte("FRAME\n")), "the movie's frames and the ones after")
+}
+
+func TestMovieUntilPC(t *testing.T) {
+	req := require.New(t)
+	rom := make([]byte, 32*1024)
+	for _, b := range gameboy.MustAssemble(`
+SECTION "entry", ROM0[$0100]
+	ld a, $10        ; select the action buttons
+	ldh [$00], a
+Wait:
+	ldh a, [$00]
+	bit 0, a         ; A, 0 when pressed
+	jr nz, Wait
+Pressed:
+	nop              ; $010A, passed once, in the middle of a frame
+.loop:
+	jr .loop
+`) {
+		copy(rom[b.Offset:], b.Data)
+	}
+	inputs := []gameboy.Buttons{0, 0, gameboy.ButtonA, gameboy.ButtonA}
+	romFile, movieFile := writeMovie(t, t.TempDir(), rom, inputs)
+
+	setFlags(t, map[string]string{
+		"file":      romFile,
+		"skip-boot": "true",
+		"frames":    "6",
+		"movie":     movieFile,
+		"until-pc":  "0x010A",
+	})
+	req.Equal(EXIT_OK, run())
+}
@@ -0,0 +1,113 @@
+// Writes an execution trace in the gameboy-doctor format, or compares it
+// against a reference trace and stops at the first line that differs.
+//
+//	gbtrace -file cpu_instrs.gb > trace.log
+//	gbtrace -file cpu_instrs.gb -ref reference.log -context 5
+//	gbtrace -file game.gb -sym game.sym -n 1000
+//	gbtrace -file cpu_instrs.gb -doctor -ref cpu_instrs.log
+//
+// gameboy-doctor's reference logs are made with LY stuck at 0x90; -doctor
+// does the same.
+//
+// With -sym, lines end in a comment with the label of PC, which comparing
+// ignores.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/kvalv/gameboy"
+)
+
+var file = flag.String("file", "", "gameboy file to run")
+var ref = flag.String("ref", "", "reference trace to compare against")
+var maxInstr = flag.Int("n", 0, "stop after this many instructions (0 = no limit)")
+var context = flag.Int("context", 10, "number of lines to show before a mismatch")
+var symFile = flag.String("sym", "", "rgblink .sym or .map file, to name PC in the trace")
+var doctor = flag.Bool("doctor", false, "make LY always read 0x90, as gameboy-doctor's reference logs assume")
+var model = flag.String("model", "DMG", "hardware model whose post-boot state to start from (DMG0, DMG, MGB, SGB, SGB2)")
+
+func main() {
+	flag.Parse()
+	if *file == "" {
+		log.Fatal("file missing")
+	}
+	m, err := gameboy.ParseModel(*model)
+	if err != nil {
+		log.Fatal(err)
+	}
+	b, err := os.ReadFile(*file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	cpu := gameboy.NewEmulator(b, gameboy.EmulatorOptions{Model: m, SkipBoot: true}).CPU
+	if *doctor {
+		cpu.Mem.WithDoctor()
+	}
+	if *symFile != "" {
+		syms, err := gameboy.LoadSymbols(*symFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		cpu.WithSymbols(syms)
+	}
+
+	if *ref == "" {
+		w := bufio.NewWriter(os.Stdout)
+		run(cpu.WithTrace(w))
+		w.Flush()
+		return
+	}
+
+	f, err := os.Open(*ref)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	pr, pw := io.Pipe()
+	done := make(chan error)
+	go func() {
+		w := bufio.NewWriter(pw)
+		err := run(cpu.WithTrace(w))
+		w.Flush()
+		pw.Close()
+		done <- err
+	}()
+
+	mismatch, err := gameboy.CompareTrace(f, pr, *context)
+	pr.Close() // stops the cpu if it's still running
+	cpuErr := <-done
+	if err != nil {
+		log.Fatal(err)
+	}
+	if mismatch == nil {
+		fmt.Println("traces match")
+		return
+	}
+	fmt.Println(mismatch.Error())
+	if cpuErr != nil && !errors.Is(cpuErr, io.ErrClosedPipe) {
+		fmt.Printf("cpu stopped: %v\n", cpuErr)
+	}
+	os.Exit(1)
+}
+
+// Steps until the cpu stops or the instruction limit is reached. Returns the
+// reason the cpu stopped, if any.
+func run(cpu *gameboy.CPU) error {
+	for *maxInstr == 0 || cpu.InstrCount < *maxInstr {
+		if !cpu.Step() {
+			if err := cpu.Err(); !errors.Is(err, gameboy.ErrNoMoreInstructions) {
+				return err
+			}
+			return nil
+		}
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"io"
 	"log/slog"
 	"os"
+	"slices"
 	"strconv"
 )
 
@@ -67,17 +68,27 @@ type CPU struct {
 	Cycles     int
 	InstrCount int
 	limit      int
-	hooks      []func(*CPU, int, Instruction, *slog.Logger)
-	stopAtnop  bool
+	hooks      []instrHook
+	// see WithAccessHook
+	accessHooks []accessHook
+	stopAtnop   bool
 
 	Mem *Memory
 	log *slog.Logger
+	// names addresses in dumps and traces, see WithSymbols
+	syms *Symbols
+	// the shadow call stack, see CallStack
+	calls []CallFrame
 
 	// peripherals
-	ppu PPU
+	ppu   PPU
+	timer Timer
+	apu   APU
 
 	// interrupts enabled or not
 	ime bool
+	// set by HALT; the cpu idles until an interrupt is pending
+	halted bool
 
 	// last error from Step()
 	err error
@@ -87,7 +98,7 @@ type CPU struct {
 func (cpu *CPU) HasPrefix() bool { return cpu.prefix }
 
 func (cpu *CPU) CurrentInstr() Instruction {
-	code := cpu.loadU8(cpu.PC)
+	code := cpu.Mem.Read(cpu.PC)
 	if cpu.prefix {
 		return extOps[code]
 	}
@@ -97,6 +108,23 @@ func (cpu *CPU) CurrentInstr() Instruction {
 func (cpu *CPU) Err() error {
 	return cpu.err
 }
+
+// Whether interrupts are enabled
+func (cpu *CPU) IME() bool { return cpu.ime }
+
+// Names addresses by the labels in syms, in Dump, traces and debuggers.
+func (cpu *CPU) WithSymbols(syms *Symbols) *CPU {
+	cpu.syms = syms
+	return cpu
+}
+
+// The labels given to WithSymbols; nil if there are none.
+func (cpu *CPU) Symbols() *Symbols { return cpu.syms }
+
+// Where addr is, e.g. "Main.loop+3", or "00:0153" without a label
+func (cpu *CPU) Location(addr uint16) string {
+	return cpu.syms.Format(cpu.Mem.Bank(addr), addr)
+}
 func (cpu *CPU) WithLog(log *slog.Logger) *CPU {
 	cpu.log = log
 	return cpu
@@ -110,6 +138,9 @@ func (cpu *CPU) AF() uint16 { return concatU16(cpu.A, Register(cpu.F)) }
 // loads and increments the progrm counter
 func (cpu *CPU) load(addr uint16, dst any) {
 	b := cpu.Mem.Read(addr)
+	for _, hook := range cpu.accessHooks {
+		hook.fn(cpu, addr, b, false)
+	}
 	// cpu.IncProgramCounter()
 	switch concr := dst.(type) {
 	case *uint8:
@@ -150,11 +181,18 @@ func (cpu *CPU) WriteMemory(addr uint16, value any) {
 	}
 	switch value := value.(type) {
 	case uint8:
-		cpu.Mem.WriteAt(addr, value)
+		cpu.writeU8(addr, value)
 	case uint16:
 		msb, lsb := split(value)
-		cpu.Mem.WriteAt(addr, lsb)
-		cpu.Mem.WriteAt(addr+1, msb)
+		cpu.writeU8(addr, lsb)
+		cpu.writeU8(addr+1, msb)
+	}
+}
+
+func (cpu *CPU) writeU8(addr uint16, value uint8) {
+	cpu.Mem.WriteAt(addr, value)
+	for _, hook := range cpu.accessHooks {
+		hook.fn(cpu, addr, value, true)
 	}
 }
 
@@ -182,38 +220,66 @@ func (cpu *CPU) PopStack() uint16 {
 	return concatU16(msb, lsb)
 }
 
-// 0th entry is the ... of stack
+// The words on the stack, from the top (SP) up to 0xFFFE, where the boot ROM
+// starts it. See CallStack for which of them are return addresses.
 func (cpu *CPU) Stack() []uint16 {
 	var stack []uint16
-	for ptr := cpu.SP; ptr < 0xFFFF; ptr = ptr - 2 {
-		lsb := cpu.readU8(ptr)
-		msb := cpu.readU8(ptr - 1)
+	for ptr := uint32(cpu.SP); ptr+1 < 0xFFFF; ptr += 2 {
+		lsb := cpu.Mem.Read(uint16(ptr))
+		msb := cpu.Mem.Read(uint16(ptr + 1))
 		stack = append(stack, concatU16(msb, lsb))
 	}
 	return stack
 }
 
+// A hook and who added it, so that it can be removed again; see removeHooks
+type instrHook struct {
+	owner any
+	fn    func(cpu *CPU, loc int, instr Instruction, log *slog.Logger)
+}
+
+type accessHook struct {
+	owner any
+	fn    func(cpu *CPU, addr uint16, value uint8, write bool)
+}
+
 func (cpu *CPU) WithHook(hook func(cpu *CPU, loc int, instr Instruction, log *slog.Logger)) *CPU {
-	cpu.hooks = append(cpu.hooks, hook)
+	cpu.hooks = append(cpu.hooks, instrHook{fn: hook})
 	return cpu
 }
 
+// Calls hook on every memory access made by an instruction: its operands,
+// the data it reads or writes, and stack pushes and pops, including those of
+// interrupts. Opcode fetches are not included; see WithHook for those.
+func (cpu *CPU) WithAccessHook(hook func(cpu *CPU, addr uint16, value uint8, write bool)) *CPU {
+	cpu.accessHooks = append(cpu.accessHooks, accessHook{fn: hook})
+	return cpu
+}
+
+// Removes the hooks added on behalf of owner.
+func (cpu *CPU) removeHooks(owner any) {
+	cpu.hooks = slices.DeleteFunc(cpu.hooks, func(h instrHook) bool { return h.owner == owner })
+	cpu.accessHooks = slices.DeleteFunc(cpu.accessHooks, func(h accessHook) bool { return h.owner == owner })
+}
+
 func (cpu *CPU) Step() bool {
 	var (
 		instr Instruction
 		ok    bool
+		loc   = cpu.PC
 	)
 	defer func() {
 		if err := recover(); err != nil {
-			fmt.Printf("Panicked at %#4x\n", cpu.PC)
-			cpu.Dump(os.Stderr)
+			fmt.Fprintf(os.Stderr, "panic at %s: %v\n%s", cpu.Location(loc), err, cpu.backtrace(loc))
 			if instr != nil {
-				fmt.Printf("At instruction %s code=0x%x\n", instr.String(), instr.Code())
-			} else {
-				fmt.Printf("At unknown instruction\n")
+				fmt.Fprintf(os.Stderr, "in %s, code %#02x\n", instr, instr.Code())
 			}
+			cpu.Dump(os.Stderr)
 			panic(err)
 		}
+		if cpu.err != nil {
+			cpu.wrapErr(loc)
+		}
 	}()
 
 	cpu.InstrCount++
@@ -225,7 +291,20 @@ func (cpu *CPU) Step() bool {
 		cpu.log.Warn("Instruction limit reached", "limit", cpu.limit, "count", cpu.InstrCount)
 		return false
 	}
-	code := cpu.loadU8(cpu.PC)
+	// A flat memory has no IO registers, so nothing to interrupt or tick
+	if !cpu.Mem.flat {
+		if cpu.interrupt() {
+			cpu.stepPeripherals()
+			return true
+		}
+		if cpu.halted {
+			cpu.Cycles += 4
+			cpu.stepPeripherals()
+			return true
+		}
+	}
+
+	code := cpu.Mem.Read(cpu.PC)
 	cpu.IncProgramCounter()
 	if cpu.err != nil { // if loading next instruction failed, we'll stop
 		return false
@@ -256,12 +335,8 @@ func (cpu *CPU) Step() bool {
 		}
 	}
 
-	if cpu.Mem.data[0xFF02] == 0x81 {
-		panic("YAY")
-	}
-
 	for _, hook := range cpu.hooks {
-		hook(cpu, int(cpu.PC)-1, instr, cpu.log)
+		hook.fn(cpu, int(cpu.PC)-1, instr, cpu.log)
 	}
 
 	// Invariant: the PC is at the instruction at the start of the operation
@@ -270,13 +345,32 @@ func (cpu *CPU) Step() bool {
 	// by op. However, for instructions that load data, the op should move the
 	// PC towards the last instruction that is done by the op.
 
+	sp := cpu.SP
 	instr.Exec(cpu)
+	cpu.trackCall(loc, instr, sp)
 
-	cpu.ppu.Step(cpu)
+	if !cpu.Mem.flat {
+		cpu.stepPeripherals()
+	}
 
 	return true
 }
 
+// Adds where it happened to an error from Step, the first time.
+func (cpu *CPU) wrapErr(pc uint16) {
+	if _, ok := cpu.err.(*StepError); ok {
+		return
+	}
+	cpu.err = &StepError{Err: cpu.err, PC: pc, Location: cpu.Location(pc), Backtrace: cpu.backtrace(pc)}
+}
+
+// Catches up the peripherals with the cycles spent by the cpu
+func (cpu *CPU) stepPeripherals() {
+	cpu.ppu.Step(cpu)
+	cpu.timer.Step(cpu)
+	cpu.apu.Step(cpu)
+}
+
 func (cpu *CPU) IncProgramCounter(src ...string) {
 	cpu.PC++
 	if cpu.log != nil {
@@ -303,7 +397,11 @@ func (cpu *CPU) Dump(w io.Writer) {
 	fmt.Fprintf(w, "D:  %#02x     E:  %#02x	  DE: %#04x\n", cpu.D, cpu.E, cpu.DE())
 	fmt.Fprintf(w, "H:  %#02x     L:  %#02x	  HL: %#04x\n", cpu.H, cpu.L, cpu.HL())
 	fmt.Fprintf(w, "                          SP: %#04x\n", cpu.SP)
-	fmt.Fprintf(w, "                          PC: %#04x\n", cpu.PC)
+	fmt.Fprintf(w, "                          PC: %#04x", cpu.PC)
+	if sym, ok := cpu.syms.Resolve(cpu.Mem.Bank(cpu.PC), cpu.PC); ok {
+		fmt.Fprintf(w, " %s", sym.Name)
+	}
+	fmt.Fprintln(w)
 	// fmt.Fprintf(w, "HL: %#04x   BC: %#04x   DE: %#04x   AF: %#04x\n", cpu.HL(), cpu.BC(), cpu.DE(), cpu.AF())
 }
 
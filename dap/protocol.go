@@ -0,0 +1,188 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var errHeader = errors.New("missing Content-Length header")
+
+type request struct {
+	Seq       int             `json:"seq"`
+	Type      string          `json:"type"`
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+type response struct {
+	Seq        int    `json:"seq"`
+	Type       string `json:"type"`
+	RequestSeq int    `json:"request_seq"`
+	Success    bool   `json:"success"`
+	Command    string `json:"command"`
+	Message    string `json:"message,omitempty"`
+	Body       any    `json:"body,omitempty"`
+}
+
+type event struct {
+	Seq   int    `json:"seq"`
+	Type  string `json:"type"`
+	Event string `json:"event"`
+	Body  any    `json:"body,omitempty"`
+}
+
+// Reads the content of a message: headers, of which only Content-Length
+// matters, an empty line and then the JSON.
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, _ := strings.Cut(line, ":")
+		if strings.EqualFold(name, "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("bad Content-Length %q", value)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, errHeader
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func writeMessage(w io.Writer, msg any) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "Content-Length: %d\r\n\r\n%s", len(data), data)
+	return err
+}
+
+// Argument and body types, named as in the specification
+// https://microsoft.github.io/debug-adapter-protocol/specification
+
+type capabilities struct {
+	SupportsConfigurationDoneRequest bool `json:"supportsConfigurationDoneRequest"`
+	SupportsFunctionBreakpoints      bool `json:"supportsFunctionBreakpoints"`
+	SupportsInstructionBreakpoints   bool `json:"supportsInstructionBreakpoints"`
+	SupportsTerminateRequest         bool `json:"supportsTerminateRequest"`
+}
+
+type launchArguments struct {
+	Program string `json:"program"` // the ROM
+	// A .sym or .map file from rgblink; defaults to the program's .sym
+	Symbols string `json:"symbols"`
+	// The rgbds source files, so stack frames can show where they are. Files
+	// breakpoints are set in are added anyway.
+	Sources     []string `json:"sources"`
+	BootROM     string   `json:"bootROM"`
+	SkipBoot    bool     `json:"skipBoot"`
+	StopOnEntry bool     `json:"stopOnEntry"`
+}
+
+type source struct {
+	Name string `json:"name,omitempty"`
+	Path string `json:"path,omitempty"`
+}
+
+type sourceBreakpoint struct {
+	Line int `json:"line"`
+}
+
+type setBreakpointsArguments struct {
+	Source      source             `json:"source"`
+	Breakpoints []sourceBreakpoint `json:"breakpoints"`
+}
+
+type functionBreakpoint struct {
+	Name string `json:"name"`
+}
+
+type setFunctionBreakpointsArguments struct {
+	Breakpoints []functionBreakpoint `json:"breakpoints"`
+}
+
+type instructionBreakpoint struct {
+	InstructionReference string `json:"instructionReference"`
+	Offset               int    `json:"offset"`
+}
+
+type setInstructionBreakpointsArguments struct {
+	Breakpoints []instructionBreakpoint `json:"breakpoints"`
+}
+
+type breakpoint struct {
+	ID                   int     `json:"id,omitempty"`
+	Verified             bool    `json:"verified"`
+	Message              string  `json:"message,omitempty"`
+	Source               *source `json:"source,omitempty"`
+	Line                 int     `json:"line,omitempty"`
+	InstructionReference string  `json:"instructionReference,omitempty"`
+}
+
+type breakpointsBody struct {
+	Breakpoints []breakpoint `json:"breakpoints"`
+}
+
+type thread struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type stackTraceArguments struct {
+	StartFrame int `json:"startFrame"`
+	Levels     int `json:"levels"`
+}
+
+type stackFrame struct {
+	ID                          int     `json:"id"`
+	Name                        string  `json:"name"`
+	Source                      *source `json:"source,omitempty"`
+	Line                        int     `json:"line"`
+	Column                      int     `json:"column"`
+	InstructionPointerReference string  `json:"instructionPointerReference"`
+}
+
+type scope struct {
+	Name               string `json:"name"`
+	PresentationHint   string `json:"presentationHint,omitempty"`
+	VariablesReference int    `json:"variablesReference"`
+	Expensive          bool   `json:"expensive"`
+}
+
+type variablesArguments struct {
+	VariablesReference int `json:"variablesReference"`
+}
+
+type variable struct {
+	Name               string `json:"name"`
+	Value              string `json:"value"`
+	VariablesReference int    `json:"variablesReference"`
+}
+
+type stoppedBody struct {
+	Reason            string `json:"reason"`
+	Description       string `json:"description,omitempty"`
+	ThreadID          int    `json:"threadId"`
+	AllThreadsStopped bool   `json:"allThreadsStopped"`
+	HitBreakpointIDs  []int  `json:"hitBreakpointIds,omitempty"`
+	Text              string `json:"text,omitempty"`
+}
@@ -0,0 +1,596 @@
+// Package dap is a Debug Adapter Protocol server, so that editors such as VS
+// Code can debug a ROM running in the emulator, see cmd/gbdap. A launch
+// request names the ROM and, optionally, the .sym or .map file rgblink wrote
+// for it; with those, breakpoints can be set on lines of the rgbds sources,
+// and stack frames show where in the sources they are.
+//
+// There is a single thread; the call stack is the one the debugger sees
+// being built by CALL, RST and interrupts, see gameboy.CPU.CallStack.
+// https://microsoft.github.io/debug-adapter-protocol/specification
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kvalv/gameboy"
+)
+
+const THREAD_ID = 1
+
+// The variablesReference of the scopes
+const (
+	REGISTERS    = 1
+	IO_REGISTERS = 2
+)
+
+// Keys of Server.points for breakpoints that aren't in a source file
+const (
+	functionPoints    = "<function>"
+	instructionPoints = "<instruction>"
+)
+
+var (
+	errNotLaunched = errors.New("no program launched")
+	errNoSymbols   = errors.New("no symbols loaded, so source lines can't be mapped to addresses")
+)
+
+type ioRegister struct {
+	name string
+	addr uint16
+}
+
+var ioRegisters = []ioRegister{
+	{"P1", gameboy.ADDR_P1},
+	{"SB", gameboy.ADDR_SB},
+	{"SC", gameboy.ADDR_SC},
+	{"DIV", gameboy.ADDR_DIV},
+	{"TIMA", gameboy.ADDR_TIMA},
+	{"TMA", gameboy.ADDR_TMA},
+	{"TAC", gameboy.ADDR_TAC},
+	{"IF", gameboy.ADDR_IF},
+	{"NR50", gameboy.ADDR_NR50},
+	{"NR51", gameboy.ADDR_NR51},
+	{"NR52", gameboy.ADDR_NR52},
+	{"LCDC", gameboy.ADDR_LCDC},
+	{"STAT", gameboy.ADDR_STAT},
+	{"SCY", gameboy.ADDR_SCY},
+	{"SCX", gameboy.ADDR_SCK},
+	{"LY", gameboy.ADDR_LY},
+	{"LYC", gameboy.ADDR_LYC},
+	{"DMA", gameboy.ADDR_DMA},
+	{"BGP", gameboy.ADDR_BGP},
+	{"OBP0", gameboy.ADDR_OBP0},
+	{"OBP1", gameboy.ADDR_OBP1},
+	{"WY", 0xFF4A},
+	{"WX", 0xFF4B},
+	{"IE", gameboy.ADDR_IE},
+}
+
+// A source file, and where its lines were assembled
+type sourceFile struct {
+	text  []string
+	addrs map[int]gameboy.Symbol
+}
+
+type incoming struct {
+	req request
+	err error
+}
+
+// Serves a single client; the emulator is created by its launch request.
+type Server struct {
+	log *slog.Logger
+	w   io.Writer
+	seq int
+	// events to send after the response to the current request
+	events []event
+	done   bool
+
+	emu         *gameboy.Emulator
+	dbg         *gameboy.Debugger
+	syms        *gameboy.Symbols
+	stopOnEntry bool
+
+	// the source files by path, and the paths in the order they were added
+	files   map[string]*sourceFile
+	sources []string
+	// debugger ids of the breakpoints, by the source path they were set in
+	points map[string][]int
+	// Runs the emulator for up to a frame, while it's not stopped
+	next func() gameboy.Stop
+}
+
+func NewServer() *Server {
+	return &Server{
+		log:    slog.New(slog.DiscardHandler),
+		files:  make(map[string]*sourceFile),
+		points: make(map[string][]int),
+	}
+}
+
+// Logs the messages at debug level.
+func (s *Server) WithLog(log *slog.Logger) *Server {
+	s.log = log
+	return s
+}
+
+// Handles requests until the client disconnects or hangs up. While the
+// program runs, requests are handled in between frames.
+func (s *Server) Serve(rw io.ReadWriter) error {
+	s.w = rw
+	reqs := make(chan incoming)
+	quit := make(chan struct{})
+	defer close(quit)
+	go func() {
+		r := bufio.NewReader(rw)
+		for {
+			var in incoming
+			data, err := readMessage(r)
+			if err == nil {
+				err = json.Unmarshal(data, &in.req)
+			}
+			in.err = err
+			select {
+			case reqs <- in:
+			case <-quit:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	for !s.done {
+		var in incoming
+		if s.next != nil {
+			select {
+			case in = <-reqs:
+			default:
+				if err := s.run(); err != nil {
+					return err
+				}
+				continue
+			}
+		} else {
+			in = <-reqs
+		}
+		if errors.Is(in.err, io.EOF) {
+			return nil
+		}
+		if in.err != nil {
+			return in.err
+		}
+		if err := s.handle(in.req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) handle(req request) error {
+	s.log.Debug("request", "seq", req.Seq, "command", req.Command, "arguments", string(req.Arguments))
+	body, err := s.dispatch(req)
+	resp := &response{Type: "response", RequestSeq: req.Seq, Command: req.Command, Success: err == nil, Body: body}
+	if err != nil {
+		resp.Message = err.Error()
+	}
+	s.seq++
+	resp.Seq = s.seq
+	if err := writeMessage(s.w, resp); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+func (s *Server) event(name string, body any) {
+	s.events = append(s.events, event{Type: "event", Event: name, Body: body})
+}
+
+func (s *Server) flush() error {
+	for _, ev := range s.events {
+		s.seq++
+		ev.Seq = s.seq
+		s.log.Debug("event", "event", ev.Event)
+		if err := writeMessage(s.w, ev); err != nil {
+			return err
+		}
+	}
+	s.events = s.events[:0]
+	return nil
+}
+
+// Runs the program for up to a frame, and tells the client if it stopped.
+func (s *Server) run() error {
+	stop := s.next()
+	s.next = s.dbg.Resume
+	if stop.Reason == gameboy.StopFrame {
+		return nil
+	}
+	s.next = nil
+	body := stoppedBody{Reason: "step", Description: stop.String()}
+	switch stop.Reason {
+	case gameboy.StopBreakpoint:
+		body.Reason = "breakpoint"
+		body.HitBreakpointIDs = []int{stop.Breakpoint.ID}
+	case gameboy.StopWatchpoint:
+		body.Reason = "data breakpoint"
+		body.HitBreakpointIDs = []int{stop.Watchpoint.ID}
+	case gameboy.StopError:
+		body.Reason = "exception"
+		body.Text = stop.Err.Error()
+	}
+	s.stopped(body)
+	return s.flush()
+}
+
+func (s *Server) stopped(body stoppedBody) {
+	body.ThreadID, body.AllThreadsStopped = THREAD_ID, true
+	s.event("stopped", body)
+}
+
+// Returns the body of the response to req.
+func (s *Server) dispatch(req request) (any, error) {
+	switch req.Command {
+	case "initialize":
+		return capabilities{
+			SupportsConfigurationDoneRequest: true,
+			SupportsFunctionBreakpoints:      true,
+			SupportsInstructionBreakpoints:   true,
+			SupportsTerminateRequest:         true,
+		}, nil
+	case "launch":
+		return nil, s.launch(req.Arguments)
+	case "threads":
+		return map[string]any{"threads": []thread{{ID: THREAD_ID, Name: "SM83"}}}, nil
+	case "setExceptionBreakpoints":
+		return breakpointsBody{Breakpoints: []breakpoint{}}, nil
+	case "terminate":
+		s.next = nil
+		s.event("terminated", nil)
+		return nil, nil
+	case "disconnect":
+		s.next, s.done = nil, true
+		return nil, nil
+	}
+
+	if s.emu == nil {
+		return nil, errNotLaunched
+	}
+	switch req.Command {
+	case "setBreakpoints":
+		return s.setBreakpoints(req.Arguments)
+	case "setFunctionBreakpoints":
+		return s.setFunctionBreakpoints(req.Arguments)
+	case "setInstructionBreakpoints":
+		return s.setInstructionBreakpoints(req.Arguments)
+	case "configurationDone":
+		if s.stopOnEntry {
+			s.stopped(stoppedBody{Reason: "entry"})
+		} else {
+			s.next = s.dbg.Continue
+		}
+		return nil, nil
+	case "stackTrace":
+		return s.stackTrace(req.Arguments)
+	case "scopes":
+		return map[string]any{"scopes": []scope{
+			{Name: "Registers", PresentationHint: "registers", VariablesReference: REGISTERS},
+			{Name: "IO Registers", PresentationHint: "registers", VariablesReference: IO_REGISTERS},
+		}}, nil
+	case "variables":
+		return s.variables(req.Arguments)
+	case "continue":
+		s.next = s.dbg.Continue
+		return map[string]any{"allThreadsContinued": true}, nil
+	case "next":
+		s.next = s.dbg.StepOver
+		return nil, nil
+	case "stepIn":
+		s.next = s.dbg.StepInto
+		return nil, nil
+	case "stepOut":
+		s.next = s.dbg.StepOut
+		return nil, nil
+	case "pause":
+		if s.next != nil {
+			s.next = nil
+			s.stopped(stoppedBody{Reason: "pause"})
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported command %q", req.Command)
+}
+
+func (s *Server) launch(raw json.RawMessage) error {
+	var args launchArguments
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return err
+	}
+	rom, err := os.ReadFile(args.Program)
+	if err != nil {
+		return err
+	}
+	bootROM, model, err := gameboy.LoadBootROM(args.BootROM)
+	if err != nil {
+		return err
+	}
+	s.emu = gameboy.NewEmulator(rom, gameboy.EmulatorOptions{
+		BootROM:  bootROM,
+		Model:    model,
+		SkipBoot: args.SkipBoot,
+	})
+	s.dbg = gameboy.NewDebugger(s.emu)
+
+	symbols := args.Symbols
+	if symbols == "" {
+		symbols = strings.TrimSuffix(args.Program, filepath.Ext(args.Program)) + ".sym"
+		if _, err := os.Stat(symbols); err != nil {
+			symbols = ""
+		}
+	}
+	if symbols != "" {
+		if s.syms, err = gameboy.LoadSymbols(symbols); err != nil {
+			return err
+		}
+		s.emu.CPU.WithSymbols(s.syms)
+	}
+	for _, path := range args.Sources {
+		if _, err := s.file(path); err != nil {
+			return err
+		}
+	}
+	s.stopOnEntry = args.StopOnEntry
+	s.event("initialized", nil)
+	return nil
+}
+
+// Loads a source file, the first time it's asked for.
+func (s *Server) file(path string) (*sourceFile, error) {
+	if f, ok := s.files[path]; ok {
+		return f, nil
+	}
+	if s.syms == nil {
+		return nil, errNoSymbols
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	src := string(data)
+	f := &sourceFile{text: strings.Split(src, "\n"), addrs: gameboy.LineAddresses(src, s.syms)}
+	s.files[path] = f
+	s.sources = append(s.sources, path)
+	return f, nil
+}
+
+// The first line from line on with code, skipping blank and comment lines.
+func (f *sourceFile) find(line int) (int, gameboy.Symbol, bool) {
+	for ; line >= 1 && line <= len(f.text); line++ {
+		if sym, ok := f.addrs[line]; ok {
+			return line, sym, true
+		}
+		text := strings.TrimSpace(f.text[line-1])
+		if text != "" && !strings.HasPrefix(text, ";") {
+			break
+		}
+	}
+	return 0, gameboy.Symbol{}, false
+}
+
+// Removes the breakpoints previously set under key.
+func (s *Server) clear(key string) {
+	for _, id := range s.points[key] {
+		s.dbg.Remove(id)
+	}
+	delete(s.points, key)
+}
+
+func (s *Server) setBreakpoints(raw json.RawMessage) (any, error) {
+	var args setBreakpointsArguments
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	path := args.Source.Path
+	s.clear(path)
+	f, err := s.file(path)
+
+	var ids []int
+	bps := make([]breakpoint, 0, len(args.Breakpoints))
+	for _, b := range args.Breakpoints {
+		if err != nil {
+			bps = append(bps, breakpoint{Message: err.Error()})
+			continue
+		}
+		line, sym, ok := f.find(b.Line)
+		if !ok {
+			bps = append(bps, breakpoint{Message: fmt.Sprintf("no code at line %d", b.Line)})
+			continue
+		}
+		bp := s.dbg.AddBreakpoint(sym.Addr).WithBank(sym.Bank)
+		ids = append(ids, bp.ID)
+		bps = append(bps, breakpoint{
+			ID:                   bp.ID,
+			Verified:             true,
+			Source:               &source{Name: filepath.Base(path), Path: path},
+			Line:                 line,
+			InstructionReference: reference(sym.Addr),
+		})
+	}
+	s.points[path] = ids
+	return breakpointsBody{Breakpoints: bps}, nil
+}
+
+func (s *Server) setFunctionBreakpoints(raw json.RawMessage) (any, error) {
+	var args setFunctionBreakpointsArguments
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	s.clear(functionPoints)
+	var ids []int
+	bps := make([]breakpoint, 0, len(args.Breakpoints))
+	for _, b := range args.Breakpoints {
+		bp, err := s.dbg.Break(b.Name)
+		if err != nil {
+			bps = append(bps, breakpoint{Message: err.Error()})
+			continue
+		}
+		ids = append(ids, bp.ID)
+		bps = append(bps, breakpoint{ID: bp.ID, Verified: true, InstructionReference: reference(bp.Addr)})
+	}
+	s.points[functionPoints] = ids
+	return breakpointsBody{Breakpoints: bps}, nil
+}
+
+func (s *Server) setInstructionBreakpoints(raw json.RawMessage) (any, error) {
+	var args setInstructionBreakpointsArguments
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	s.clear(instructionPoints)
+	var ids []int
+	bps := make([]breakpoint, 0, len(args.Breakpoints))
+	for _, b := range args.Breakpoints {
+		addr, err := strconv.ParseUint(b.InstructionReference, 0, 16)
+		if err != nil {
+			bps = append(bps, breakpoint{Message: fmt.Sprintf("bad instruction reference %q", b.InstructionReference)})
+			continue
+		}
+		bp := s.dbg.AddBreakpoint(uint16(int(addr) + b.Offset))
+		ids = append(ids, bp.ID)
+		bps = append(bps, breakpoint{ID: bp.ID, Verified: true, InstructionReference: reference(bp.Addr)})
+	}
+	s.points[instructionPoints] = ids
+	return breakpointsBody{Breakpoints: bps}, nil
+}
+
+func (s *Server) stackTrace(raw json.RawMessage) (any, error) {
+	var args stackTraceArguments
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return nil, err
+		}
+	}
+	cpu := s.emu.CPU
+	calls := cpu.CallStack()
+	frames := make([]stackFrame, 0, len(calls)+1)
+	pc, bank := cpu.PC, cpu.Mem.Bank(cpu.PC)
+	// innermost first; the outermost frame's function isn't known
+	for i := len(calls); i >= 0; i-- {
+		frame := stackFrame{
+			ID:                          len(frames) + 1,
+			Name:                        s.syms.Format(bank, pc),
+			InstructionPointerReference: reference(pc),
+		}
+		if i > 0 {
+			frame.Name = s.funcName(calls[i-1])
+		}
+		if path, line, ok := s.lineAt(pc, bank); ok {
+			frame.Source = &source{Name: filepath.Base(path), Path: path}
+			frame.Line, frame.Column = line, 1
+		}
+		frames = append(frames, frame)
+
+		if i > 0 {
+			pc, bank = calls[i-1].Caller, cpu.Mem.Bank(calls[i-1].Caller)
+			if i > 1 {
+				bank = calls[i-2].Bank // the caller is in the function below
+			}
+		}
+	}
+
+	total := len(frames)
+	frames = frames[min(args.StartFrame, total):]
+	if args.Levels > 0 && args.Levels < len(frames) {
+		frames = frames[:args.Levels]
+	}
+	return map[string]any{"stackFrames": frames, "totalFrames": total}, nil
+}
+
+func (s *Server) funcName(frame gameboy.CallFrame) string {
+	name := s.syms.Format(frame.Bank, frame.Func)
+	if frame.Interrupt {
+		name += " (interrupt)"
+	}
+	return name
+}
+
+// The last line assembled to addr in bank; earlier ones are labels.
+func (s *Server) lineAt(addr uint16, bank int) (string, int, bool) {
+	banked := addr >= 0x4000 && addr < 0x8000
+	for _, path := range s.sources {
+		found := 0
+		for line, sym := range s.files[path].addrs {
+			if sym.Addr == addr && (!banked || sym.Bank == bank) && line > found {
+				found = line
+			}
+		}
+		if found > 0 {
+			return path, found, true
+		}
+	}
+	return "", 0, false
+}
+
+func (s *Server) variables(raw json.RawMessage) (any, error) {
+	var args variablesArguments
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	cpu := s.emu.CPU
+	vars := []variable{}
+	switch args.VariablesReference {
+	case REGISTERS:
+		vars = append(vars,
+			variable{Name: "A", Value: hex8(cpu.A)},
+			variable{Name: "F", Value: flags(cpu.F)},
+			variable{Name: "B", Value: hex8(cpu.B)},
+			variable{Name: "C", Value: hex8(cpu.C)},
+			variable{Name: "D", Value: hex8(cpu.D)},
+			variable{Name: "E", Value: hex8(cpu.E)},
+			variable{Name: "H", Value: hex8(cpu.H)},
+			variable{Name: "L", Value: hex8(cpu.L)},
+			variable{Name: "AF", Value: hex16(cpu.AF())},
+			variable{Name: "BC", Value: hex16(cpu.BC())},
+			variable{Name: "DE", Value: hex16(cpu.DE())},
+			variable{Name: "HL", Value: hex16(cpu.HL())},
+			variable{Name: "SP", Value: hex16(cpu.SP)},
+			variable{Name: "PC", Value: hex16(cpu.PC)},
+			variable{Name: "IME", Value: strconv.FormatBool(cpu.IME())},
+			variable{Name: "ROM bank", Value: strconv.Itoa(cpu.Mem.Bank(0x4000))},
+		)
+	case IO_REGISTERS:
+		for _, r := range ioRegisters {
+			vars = append(vars, variable{Name: r.name, Value: hex8(cpu.Mem.Read(r.addr))})
+		}
+	}
+	return map[string]any{"variables": vars}, nil
+}
+
+func hex8(v uint8) string   { return fmt.Sprintf("$%02X", v) }
+func hex16(v uint16) string { return fmt.Sprintf("$%04X", v) }
+
+// e.g. "$B0 Z-HC"
+func flags(f gameboy.Flags) string {
+	b := []byte("ZNHC")
+	for i, flag := range []gameboy.Flags{gameboy.FLAGZ, gameboy.FLAGN, gameboy.FLAGH, gameboy.FLAGC} {
+		if f&flag == 0 {
+			b[i] = '-'
+		}
+	}
+	return fmt.Sprintf("$%02X %s", uint8(f), b)
+}
+
+// Instruction references are addresses, e.g. 0x0150
+func reference(addr uint16) string {
+	return fmt.Sprintf("0x%04X", addr)
+}
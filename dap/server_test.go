@@ -0,0 +1,334 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kvalv/gameboy"
+	"github.com/stretchr/testify/require"
+)
+
+// Counts in A, storing it at $C000 from a function that calls another
+const program = `SECTION "entry", ROM0[$0100]
+	nop
+	jp Main
+SECTION "main", ROM0[$0150]
+Main:
+	inc a            ; $0150
+	call Store       ; $0151
+	jr Main          ; $0154
+Store:
+	ld [$C000], a    ; $0156
+	call Nested      ; $0159
+	ret              ; $015C
+Nested:
+	ld b, a          ; $015D
+	ret              ; $015E
+`
+
+const symbols = `; File generated by rgblink
+00:0150 Main
+00:0156 Store
+00:015d Nested
+`
+
+// Writes the ROM, its symbols and its source to a directory
+func writeProgram(t *testing.T) string {
+	dir := t.TempDir()
+	rom := make([]byte, 32*1024)
+	for _, b := range gameboy.MustAssemble(program) {
+		copy(rom[b.Offset:], b.Data)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "game.gb"), rom, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "game.sym"), []byte(symbols), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "game.asm"), []byte(program), 0o644))
+	return dir
+}
+
+// A fake client, talking to a server over a pipe
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+	seq  int
+	done chan error
+}
+
+func newClient(t *testing.T) *client {
+	server, conn := net.Pipe()
+	c := &client{t: t, conn: conn, r: bufio.NewReader(conn), done: make(chan error, 1)}
+	go func() {
+		c.done <- NewServer().Serve(server)
+		server.Close()
+	}()
+	t.Cleanup(func() { conn.Close() })
+	return c
+}
+
+func (c *client) write(msg map[string]any) {
+	c.seq++
+	msg["seq"], msg["type"] = c.seq, "request"
+	require.NoError(c.t, writeMessage(c.conn, msg))
+}
+
+func (c *client) read() map[string]any {
+	// unlike the server, editors don't skip stray lines, e.g. output
+	head, err := c.r.Peek(len("Content-Length:"))
+	require.NoError(c.t, err)
+	require.Equal(c.t, "Content-Length:", string(head))
+	data, err := readMessage(c.r)
+	require.NoError(c.t, err)
+	var msg map[string]any
+	require.NoError(c.t, json.Unmarshal(data, &msg))
+	return msg
+}
+
+// Sends a request and returns the body of the response, which must succeed
+func (c *client) request(command string, args any) map[string]any {
+	c.write(map[string]any{"command": command, "arguments": args})
+	resp := c.read()
+	require.Equal(c.t, "response", resp["type"])
+	require.Equal(c.t, true, resp["success"], "%s: %v", command, resp["message"])
+	body, _ := resp["body"].(map[string]any)
+	return body
+}
+
+// Runs a transcript of the session: "->" lines are requests to send, "<-"
+// lines messages the server must send next. Those only need to contain the
+// expected fields; arrays must have the same length though. {dir} is the
+// directory of the program.
+func (c *client) exchange(dir, script string) {
+	// messages may continue on the following lines
+	var lines []string
+	for _, line := range strings.Split(script, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "->") || strings.HasPrefix(line, "<-"):
+			lines = append(lines, line)
+		default:
+			lines[len(lines)-1] += line
+		}
+	}
+	quoted, _ := json.Marshal(dir)
+	for _, line := range lines {
+		line = strings.ReplaceAll(line, `"{dir}`, strings.TrimSuffix(string(quoted), `"`))
+		var msg map[string]any
+		require.NoError(c.t, json.Unmarshal([]byte(line[2:]), &msg), line)
+		switch line[:2] {
+		case "->":
+			c.write(msg)
+		case "<-":
+			got := c.read()
+			require.True(c.t, contains(msg, got), "expected %s\ngot %v", line[2:], got)
+		default:
+			c.t.Fatalf("bad line %q", line)
+		}
+	}
+}
+
+// Whether got has everything in want
+func contains(want, got any) bool {
+	switch want := want.(type) {
+	case map[string]any:
+		got, ok := got.(map[string]any)
+		if !ok {
+			return false
+		}
+		for k, v := range want {
+			if !contains(v, got[k]) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		got, ok := got.([]any)
+		if !ok || len(got) != len(want) {
+			return false
+		}
+		for i := range want {
+			if !contains(want[i], got[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return want == got
+}
+
+func TestSession(t *testing.T) {
+	dir := writeProgram(t)
+	c := newClient(t)
+	c.exchange(dir, `
+-> {"command": "initialize", "arguments": {"adapterID": "gameboy"}}
+<- {"type": "response", "command": "initialize", "success": true, "body": {"supportsConfigurationDoneRequest": true}}
+-> {"command": "launch", "arguments": {"program": "{dir}/game.gb", "skipBoot": true}}
+<- {"type": "response", "command": "launch", "success": true}
+<- {"type": "event", "event": "initialized"}
+-> {"command": "goto"}
+<- {"command": "goto", "success": false, "message": "unsupported command \"goto\""}
+
+# line 2 is in the entry, which has no label
+-> {"command": "setBreakpoints", "arguments": {"source": {"path": "{dir}/game.asm"}, "breakpoints": [{"line": 14}, {"line": 2}, {"line": 99}]}}
+<- {"command": "setBreakpoints", "success": true, "body": {"breakpoints": [
+	{"id": 1, "verified": true, "line": 14, "instructionReference": "0x015D"},
+	{"verified": false, "message": "no code at line 2"},
+	{"verified": false}]}}
+-> {"command": "configurationDone"}
+<- {"command": "configurationDone", "success": true}
+<- {"event": "stopped", "body": {"reason": "breakpoint", "threadId": 1, "hitBreakpointIds": [1]}}
+
+-> {"command": "threads"}
+<- {"command": "threads", "body": {"threads": [{"id": 1, "name": "SM83"}]}}
+-> {"command": "stackTrace", "arguments": {"threadId": 1}}
+<- {"command": "stackTrace", "body": {"totalFrames": 3, "stackFrames": [
+	{"id": 1, "name": "Nested", "line": 14, "source": {"name": "game.asm", "path": "{dir}/game.asm"}, "instructionPointerReference": "0x015D"},
+	{"id": 2, "name": "Store", "line": 11, "instructionPointerReference": "0x0159"},
+	{"id": 3, "name": "Main+1", "line": 7, "instructionPointerReference": "0x0151"}]}}
+-> {"command": "stackTrace", "arguments": {"threadId": 1, "startFrame": 1, "levels": 1}}
+<- {"command": "stackTrace", "body": {"totalFrames": 3, "stackFrames": [{"id": 2, "line": 11}]}}
+
+-> {"command": "next", "arguments": {"threadId": 1}}
+<- {"command": "next", "success": true}
+<- {"event": "stopped", "body": {"reason": "step"}}
+-> {"command": "stackTrace", "arguments": {"threadId": 1, "levels": 1}}
+<- {"command": "stackTrace", "body": {"stackFrames": [{"line": 15, "instructionPointerReference": "0x015E"}]}}
+-> {"command": "stepOut", "arguments": {"threadId": 1}}
+<- {"command": "stepOut", "success": true}
+<- {"event": "stopped", "body": {"reason": "step"}}
+-> {"command": "stackTrace", "arguments": {"threadId": 1}}
+<- {"command": "stackTrace", "body": {"totalFrames": 2, "stackFrames": [{"name": "Store", "line": 12}, {"name": "Main+1", "line": 7}]}}
+
+-> {"command": "continue", "arguments": {"threadId": 1}}
+<- {"command": "continue", "success": true, "body": {"allThreadsContinued": true}}
+<- {"event": "stopped", "body": {"reason": "breakpoint", "hitBreakpointIds": [1]}}
+
+# replace the source breakpoints with a function and an instruction one
+-> {"command": "setBreakpoints", "arguments": {"source": {"path": "{dir}/game.asm"}, "breakpoints": []}}
+<- {"command": "setBreakpoints", "body": {"breakpoints": []}}
+-> {"command": "setFunctionBreakpoints", "arguments": {"breakpoints": [{"name": "Store"}, {"name": "Nowhere"}]}}
+<- {"command": "setFunctionBreakpoints", "body": {"breakpoints": [{"id": 2, "verified": true}, {"verified": false, "message": "unknown label or address: \"Nowhere\""}]}}
+-> {"command": "setInstructionBreakpoints", "arguments": {"breakpoints": [{"instructionReference": "0x0150", "offset": 4}]}}
+<- {"command": "setInstructionBreakpoints", "body": {"breakpoints": [{"id": 3, "verified": true, "instructionReference": "0x0154"}]}}
+-> {"command": "continue", "arguments": {"threadId": 1}}
+<- {"command": "continue"}
+<- {"event": "stopped", "body": {"reason": "breakpoint", "hitBreakpointIds": [3]}}
+-> {"command": "continue", "arguments": {"threadId": 1}}
+<- {"command": "continue"}
+<- {"event": "stopped", "body": {"reason": "breakpoint", "hitBreakpointIds": [2]}}
+-> {"command": "stackTrace", "arguments": {"threadId": 1}}
+<- {"command": "stackTrace", "body": {"stackFrames": [{"name": "Store", "line": 10}, {"line": 7}]}}
+
+# without breakpoints, it runs until paused
+-> {"command": "setFunctionBreakpoints", "arguments": {"breakpoints": []}}
+<- {"command": "setFunctionBreakpoints"}
+-> {"command": "setInstructionBreakpoints", "arguments": {"breakpoints": []}}
+<- {"command": "setInstructionBreakpoints"}
+-> {"command": "continue", "arguments": {"threadId": 1}}
+<- {"command": "continue"}
+-> {"command": "pause", "arguments": {"threadId": 1}}
+<- {"command": "pause", "success": true}
+<- {"event": "stopped", "body": {"reason": "pause"}}
+
+-> {"command": "disconnect"}
+<- {"command": "disconnect", "success": true}
+`)
+	require.NoError(t, <-c.done)
+}
+
+func TestVariables(t *testing.T) {
+	req := require.New(t)
+	dir := writeProgram(t)
+	c := newClient(t)
+	c.request("initialize", map[string]any{})
+	c.request("launch", map[string]any{"program": filepath.Join(dir, "game.gb"), "skipBoot": true, "stopOnEntry": true})
+	req.Equal("initialized", c.read()["event"])
+	c.request("configurationDone", nil)
+	stopped := c.read()
+	req.Equal("stopped", stopped["event"])
+	req.Equal("entry", stopped["body"].(map[string]any)["reason"])
+
+	vars := func(ref int) map[string]string {
+		body := c.request("variables", map[string]any{"variablesReference": ref})
+		values := make(map[string]string)
+		for _, v := range body["variables"].([]any) {
+			v := v.(map[string]any)
+			values[v["name"].(string)] = v["value"].(string)
+		}
+		return values
+	}
+	regs := vars(REGISTERS)
+	req.Equal("$01", regs["A"])
+	// only Z is set, as the header checksum is 0
+	req.Equal("$80 Z---", regs["F"])
+	req.Equal("$0013", regs["BC"])
+	req.Equal("$FFFE", regs["SP"])
+	req.Equal("$0100", regs["PC"])
+	req.Equal("1", regs["ROM bank"])
+
+	io := vars(IO_REGISTERS)
+	req.Len(io, len(ioRegisters))
+	req.Equal("$91", io["LCDC"])
+
+	req.Empty(vars(99))
+}
+
+func TestErrors(t *testing.T) {
+	req := require.New(t)
+	c := newClient(t)
+	c.write(map[string]any{"command": "stackTrace"})
+	resp := c.read()
+	req.Equal(false, resp["success"])
+	req.Equal(errNotLaunched.Error(), resp["message"])
+
+	c.write(map[string]any{"command": "launch", "arguments": map[string]any{"program": "missing.gb"}})
+	resp = c.read()
+	req.Equal(false, resp["success"])
+	req.Contains(resp["message"], "missing.gb")
+
+	c.conn.Close()
+	req.NoError(<-c.done, "hanging up ends the session")
+}
+
+func TestUnimplemented(t *testing.T) {
+	req := require.New(t)
+	dir := t.TempDir()
+	rom := make([]byte, 32*1024)
+	// DAA isn't implemented, and says so on stderr
+	for _, b := range gameboy.MustAssemble(`SECTION "main", ROM0[$0100]
+Main:
+	daa
+	jr Main
+`) {
+		copy(rom[b.Offset:], b.Data)
+	}
+	req.NoError(os.WriteFile(filepath.Join(dir, "daa.gb"), rom, 0o644))
+
+	c := newClient(t)
+	c.exchange(dir, `
+-> {"command": "initialize", "arguments": {"adapterID": "gameboy"}}
+<- {"command": "initialize", "success": true}
+-> {"command": "launch", "arguments": {"program": "{dir}/daa.gb", "skipBoot": true, "stopOnEntry": true}}
+<- {"command": "launch", "success": true}
+<- {"event": "initialized"}
+-> {"command": "configurationDone"}
+<- {"command": "configurationDone", "success": true}
+<- {"event": "stopped", "body": {"reason": "entry"}}
+-> {"command": "next", "arguments": {"threadId": 1}}
+<- {"command": "next", "success": true}
+<- {"event": "stopped", "body": {"reason": "step"}}
+-> {"command": "continue", "arguments": {"threadId": 1}}
+<- {"command": "continue", "success": true}
+-> {"command": "pause", "arguments": {"threadId": 1}}
+<- {"command": "pause", "success": true}
+<- {"event": "stopped", "body": {"reason": "pause"}}
+-> {"command": "disconnect"}
+<- {"command": "disconnect", "success": true}
+`)
+	req.NoError(<-c.done)
+}
@@ -0,0 +1,398 @@
+package gameboy
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// Matches an address in whichever ROM bank is mapped, see Breakpoint.Bank
+const ANY_BANK = -1
+
+// Why the debugger handed back control.
+type StopReason int
+
+const (
+	StopFrame      StopReason = iota // the frame is complete; Resume carries on
+	StopBreakpoint                   // about to run a breakpoint's instruction
+	StopWatchpoint                   // the last instruction hit a watchpoint
+	StopStep                         // a step or RunTo completed
+	StopError                        // the cpu stopped, see Stop.Err
+)
+
+func (r StopReason) String() string {
+	switch r {
+	case StopFrame:
+		return "frame"
+	case StopBreakpoint:
+		return "breakpoint"
+	case StopWatchpoint:
+		return "watchpoint"
+	case StopStep:
+		return "step"
+	case StopError:
+		return "error"
+	}
+	return fmt.Sprintf("StopReason(%d)", int(r))
+}
+
+// Where and why the debugger stopped.
+type Stop struct {
+	Reason StopReason
+	PC     uint16
+	Bank   int // the ROM bank of PC, see Memory.Bank
+
+	Breakpoint *Breakpoint // for StopBreakpoint
+	Watchpoint *Watchpoint // for StopWatchpoint
+	Access     Access      // for StopWatchpoint
+	Err        error       // for StopError
+}
+
+func (s Stop) String() string {
+	loc := fmt.Sprintf("%02X:%04X", s.Bank, s.PC)
+	switch s.Reason {
+	case StopBreakpoint:
+		return fmt.Sprintf("breakpoint %d at %s", s.Breakpoint.ID, loc)
+	case StopWatchpoint:
+		return fmt.Sprintf("watchpoint %d: %s at %s", s.Watchpoint.ID, s.Access, loc)
+	case StopError:
+		return fmt.Sprintf("error at %s: %v", loc, s.Err)
+	}
+	return fmt.Sprintf("%s at %s", s.Reason, loc)
+}
+
+// Stops before the instruction at Addr runs.
+type Breakpoint struct {
+	ID   int
+	Addr uint16
+	// For addresses in 0x4000-0x7FFF, the ROM bank the instruction must be
+	// in, or ANY_BANK. Ignored for other addresses.
+	Bank int
+	// If set, the breakpoint only triggers when this returns true
+	Cond func(*CPU) bool
+	// How many times the breakpoint triggered
+	Hits int
+}
+
+func (bp *Breakpoint) WithBank(bank int) *Breakpoint {
+	bp.Bank = bank
+	return bp
+}
+
+func (bp *Breakpoint) WithCondition(cond func(*CPU) bool) *Breakpoint {
+	bp.Cond = cond
+	return bp
+}
+
+func (bp *Breakpoint) matches(cpu *CPU) bool {
+	if cpu.PC != bp.Addr {
+		return false
+	}
+	if bp.Bank != ANY_BANK && within(bp.Addr, 0x4000, 0x8000) && cpu.Mem.Bank(bp.Addr) != bp.Bank {
+		return false
+	}
+	return bp.Cond == nil || bp.Cond(cpu)
+}
+
+// The kinds of access a watchpoint stops on; they can be combined.
+type WatchKind uint8
+
+const (
+	WatchRead WatchKind = 1 << iota
+	WatchWrite
+	WatchExec
+)
+
+func (k WatchKind) String() string {
+	var s []string
+	for i, name := range []string{"read", "write", "exec"} {
+		if k&(1<<i) != 0 {
+			s = append(s, name)
+		}
+	}
+	return strings.Join(s, "|")
+}
+
+// Stops when an instruction accesses Start-End, both inclusive. Read and
+// write watchpoints stop after the instruction, exec watchpoints before it.
+type Watchpoint struct {
+	ID         int
+	Start, End uint16
+	Kind       WatchKind
+	Hits       int
+}
+
+func (wp *Watchpoint) contains(addr uint16) bool {
+	return wp.Start <= addr && addr <= wp.End
+}
+
+// A memory access that triggered a watchpoint.
+type Access struct {
+	Kind  WatchKind // a single kind
+	Addr  uint16
+	Value uint8  // the value read or written; the opcode for WatchExec
+	PC    uint16 // the instruction that made the access
+}
+
+func (a Access) String() string {
+	return fmt.Sprintf("%s $%04X = $%02X", a.Kind, a.Addr, a.Value)
+}
+
+// Runs an emulator under control of breakpoints, watchpoints and stepping
+// commands. It doesn't depend on any frontend: every command runs at most
+// until the end of the current frame and says why it returned, so a UI can
+// draw in between, and keep calling Resume until the command completes:
+//
+//	dbg := gameboy.NewDebugger(emu)
+//	dbg.AddBreakpoint(0x4000).WithBank(2)
+//	stop := dbg.Continue()
+//	for stop.Reason == gameboy.StopFrame {
+//		stop = dbg.Resume()
+//	}
+//
+// Only one debugger should be attached to an emulator at a time; see Detach.
+type Debugger struct {
+	emu *Emulator
+
+	breakpoints []*Breakpoint
+	watchpoints []*Watchpoint
+	lastID      int
+
+	// The condition that completes the pending step command, nil if there
+	// is none. ret is whether the last instruction returned.
+	until func(cpu *CPU, ret bool) bool
+
+	// The instruction being executed, as seen by the hook
+	instrPC  uint16
+	instrLen int
+	returned bool
+
+	// The first watched access of the last instruction
+	access *Access
+	hit    *Watchpoint
+	// InstrCount when the debugger last stopped. Resuming doesn't stop at
+	// the same place again.
+	stoppedAt int
+}
+
+func NewDebugger(emu *Emulator) *Debugger {
+	d := &Debugger{emu: emu, stoppedAt: -1}
+	emu.CPU.hooks = append(emu.CPU.hooks, instrHook{d, d.hook})
+	emu.CPU.accessHooks = append(emu.CPU.accessHooks, accessHook{d, d.accessHook})
+	return d
+}
+
+// Removes the breakpoints and watchpoints, and takes the debugger off the
+// emulator, so another one can be attached. It can't be used after.
+func (d *Debugger) Detach() {
+	d.breakpoints, d.watchpoints = nil, nil
+	d.until = nil
+	d.emu.CPU.removeHooks(d)
+}
+
+// Adds a breakpoint at addr, in any bank.
+func (d *Debugger) AddBreakpoint(addr uint16) *Breakpoint {
+	d.lastID++
+	bp := &Breakpoint{ID: d.lastID, Addr: addr, Bank: ANY_BANK}
+	d.breakpoints = append(d.breakpoints, bp)
+	return bp
+}
+
+// Adds a breakpoint at a label or address, as understood by Symbols.Locate,
+// e.g. "Main.loop" or "02:4000", using the symbols of the cpu.
+func (d *Debugger) Break(spec string) (*Breakpoint, error) {
+	sym, err := d.emu.CPU.Symbols().Locate(spec)
+	if err != nil {
+		return nil, err
+	}
+	return d.AddBreakpoint(sym.Addr).WithBank(sym.Bank), nil
+}
+
+// Adds a watchpoint on start-end, both inclusive.
+func (d *Debugger) AddWatchpoint(start, end uint16, kind WatchKind) *Watchpoint {
+	d.lastID++
+	wp := &Watchpoint{ID: d.lastID, Start: start, End: end, Kind: kind}
+	d.watchpoints = append(d.watchpoints, wp)
+	return wp
+}
+
+// Removes the breakpoint or watchpoint with the given id. Returns false if
+// there is none.
+func (d *Debugger) Remove(id int) bool {
+	for i, bp := range d.breakpoints {
+		if bp.ID == id {
+			d.breakpoints = append(d.breakpoints[:i], d.breakpoints[i+1:]...)
+			return true
+		}
+	}
+	for i, wp := range d.watchpoints {
+		if wp.ID == id {
+			d.watchpoints = append(d.watchpoints[:i], d.watchpoints[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Debugger) Breakpoints() []*Breakpoint { return d.breakpoints }
+func (d *Debugger) Watchpoints() []*Watchpoint { return d.watchpoints }
+
+// Whether a step command is still running, i.e. Resume would carry on with
+// it rather than just continue.
+func (d *Debugger) Stepping() bool { return d.until != nil }
+
+// Runs until a breakpoint or watchpoint, cancelling any pending step.
+func (d *Debugger) Continue() Stop {
+	d.until = nil
+	return d.run()
+}
+
+// Carries on with the pending command after StopFrame: the step command,
+// if any, otherwise continues.
+func (d *Debugger) Resume() Stop {
+	return d.run()
+}
+
+// Runs a single instruction. If an interrupt is serviced instead, it stops
+// at the first instruction of the handler.
+func (d *Debugger) StepInto() Stop {
+	start := d.emu.CPU.InstrCount
+	d.until = func(cpu *CPU, ret bool) bool {
+		return cpu.InstrCount > start
+	}
+	return d.run()
+}
+
+// Like StepInto, but runs a CALL or RST until it returns to the next
+// instruction.
+func (d *Debugger) StepOver() Stop {
+	cpu := d.emu.CPU
+	instr := cpu.CurrentInstr()
+	if cpu.prefix || !isCall(instr) {
+		return d.StepInto()
+	}
+	next, sp := cpu.PC+uint16(instrLen(instr)), cpu.SP
+	d.until = func(cpu *CPU, ret bool) bool {
+		return cpu.PC == next && cpu.SP >= sp
+	}
+	return d.run()
+}
+
+// Runs until the current function returns, i.e. until a RET or RETI pops
+// the return address pushed by its caller.
+func (d *Debugger) StepOut() Stop {
+	sp := d.emu.CPU.SP
+	d.until = func(cpu *CPU, ret bool) bool {
+		return ret && cpu.SP > sp
+	}
+	return d.run()
+}
+
+// Runs until the instruction at addr in bank (or ANY_BANK), as if there
+// were a temporary breakpoint.
+func (d *Debugger) RunTo(addr uint16, bank int) Stop {
+	target := Breakpoint{Addr: addr, Bank: bank}
+	start := d.emu.CPU.InstrCount
+	d.until = func(cpu *CPU, ret bool) bool {
+		return cpu.InstrCount > start && target.matches(cpu)
+	}
+	return d.run()
+}
+
+func (d *Debugger) run() Stop {
+	var stop *Stop
+	_, err := d.emu.RunFrameUntil(func(cpu *CPU) bool {
+		stop = d.check(cpu)
+		return stop != nil
+	})
+	cpu := d.emu.CPU
+	switch {
+	case err != nil:
+		stop = &Stop{Reason: StopError, Err: err}
+	case stop == nil:
+		return Stop{Reason: StopFrame, PC: cpu.PC, Bank: cpu.Mem.Bank(cpu.PC)}
+	}
+	stop.PC, stop.Bank = cpu.PC, cpu.Mem.Bank(cpu.PC)
+	var stepErr *StepError
+	if errors.As(err, &stepErr) {
+		// PC has moved past the instruction that failed
+		stop.PC, stop.Bank = stepErr.PC, cpu.Mem.Bank(stepErr.PC)
+	}
+	d.until = nil
+	d.stoppedAt = cpu.InstrCount
+	return *stop
+}
+
+// Called before every instruction; returns why to stop before it, if at all.
+func (d *Debugger) check(cpu *CPU) *Stop {
+	if cpu.prefix {
+		return nil // halfway through a CB-prefixed instruction
+	}
+	ret := d.returned
+	d.returned = false
+
+	if d.access != nil {
+		stop := &Stop{Reason: StopWatchpoint, Watchpoint: d.hit, Access: *d.access}
+		d.hit, d.access = nil, nil
+		return stop
+	}
+	if cpu.InstrCount != d.stoppedAt {
+		for _, bp := range d.breakpoints {
+			if bp.matches(cpu) {
+				bp.Hits++
+				return &Stop{Reason: StopBreakpoint, Breakpoint: bp}
+			}
+		}
+		for _, wp := range d.watchpoints {
+			if wp.Kind&WatchExec != 0 && wp.contains(cpu.PC) {
+				wp.Hits++
+				access := Access{Kind: WatchExec, Addr: cpu.PC, Value: cpu.Mem.Read(cpu.PC), PC: cpu.PC}
+				return &Stop{Reason: StopWatchpoint, Watchpoint: wp, Access: access}
+			}
+		}
+	}
+	if d.until != nil && d.until(cpu, ret) {
+		return &Stop{Reason: StopStep}
+	}
+	return nil
+}
+
+func (d *Debugger) hook(cpu *CPU, loc int, instr Instruction, log *slog.Logger) {
+	d.instrPC, d.instrLen = uint16(loc), instrLen(instr)
+	d.returned = strings.HasPrefix(instr.String(), "RET")
+}
+
+func (d *Debugger) accessHook(cpu *CPU, addr uint16, value uint8, write bool) {
+	if d.access != nil || len(d.watchpoints) == 0 {
+		return
+	}
+	kind := WatchRead
+	if write {
+		kind = WatchWrite
+	} else if addr > d.instrPC && addr < d.instrPC+uint16(d.instrLen) {
+		return // an operand of the instruction, not data
+	}
+	for _, wp := range d.watchpoints {
+		if wp.Kind&kind != 0 && wp.contains(addr) {
+			wp.Hits++
+			d.hit = wp
+			d.access = &Access{Kind: kind, Addr: addr, Value: value, PC: d.instrPC}
+			return
+		}
+	}
+}
+
+// The size of an unprefixed instruction in bytes, from its operands
+func instrLen(instr Instruction) int {
+	name := instr.String()
+	switch {
+	case strings.Contains(name, "16"): // n16, a16
+		return 3
+	case strings.Contains(name, "n8"), strings.Contains(name, "a8"), strings.Contains(name, "e8"):
+		return 2
+	case name == "STOP": // followed by a padding byte
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,239 @@
+package gameboy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kvalv/gameboy/disasm"
+	"github.com/stretchr/testify/require"
+)
+
+// A loop that counts in A, and calls into two levels of functions
+var debugProgram = MustAssemble(`
+SECTION "main", ROM0[$0150]
+	ld a, 0          ; $0150
+.loop:
+	inc a            ; $0152
+	call Store       ; $0153
+	ld b, a          ; $0156
+	jr .loop         ; $0157
+Store:
+	ld [$C000], a    ; $0159
+	call Nested      ; $015C
+	ret              ; $015F
+Nested:
+	ld hl, $C001     ; $0160
+	ld c, [hl]       ; $0163
+	ret              ; $0164
+`)[0].Data
+
+func newDebugger(prog []byte) (*Emulator, *Debugger) {
+	emu := NewEmulator(testROM(prog), EmulatorOptions{Model: DMG, SkipBoot: true})
+	return emu, NewDebugger(emu)
+}
+
+func TestBreakpoint(t *testing.T) {
+	t.Run("stops before the instruction", func(t *testing.T) {
+		req := require.New(t)
+		emu, dbg := newDebugger(debugProgram)
+		bp := dbg.AddBreakpoint(0x0159)
+
+		stop := dbg.Continue()
+		req.Equal(StopBreakpoint, stop.Reason)
+		req.Equal(bp, stop.Breakpoint)
+		req.Equal(uint16(0x0159), stop.PC)
+		req.Equal(uint8(1), emu.CPU.A)
+		req.Equal("breakpoint 1 at 00:0159", stop.String())
+
+		// doesn't stop at the same place twice
+		stop = dbg.Continue()
+		req.Equal(StopBreakpoint, stop.Reason)
+		req.Equal(uint8(2), emu.CPU.A)
+		req.Equal(2, bp.Hits)
+
+		req.True(dbg.Remove(bp.ID))
+		req.False(dbg.Remove(bp.ID))
+		req.Equal(StopFrame, dbg.Continue().Reason)
+	})
+
+	t.Run("by label", func(t *testing.T) {
+		req := require.New(t)
+		emu, dbg := newDebugger(debugProgram)
+		syms, err := ReadSymbols(strings.NewReader("00:0150 Main\n00:0152 Main.loop\n00:0159 Store\n"))
+		req.NoError(err)
+		emu.CPU.WithSymbols(syms)
+
+		bp, err := dbg.Break("Store+3")
+		req.NoError(err)
+		req.Equal(uint16(0x015C), bp.Addr)
+		stop := dbg.Continue()
+		req.Equal(bp, stop.Breakpoint)
+		req.Equal("Store+3", emu.CPU.Location(stop.PC))
+
+		_, err = dbg.Break("Nowhere")
+		req.ErrorIs(err, ErrLocation)
+	})
+
+	t.Run("condition", func(t *testing.T) {
+		req := require.New(t)
+		emu, dbg := newDebugger(debugProgram)
+		dbg.AddBreakpoint(0x0156).WithCondition(func(cpu *CPU) bool { return cpu.A == 5 })
+		stop := dbg.Continue()
+		req.Equal(StopBreakpoint, stop.Reason)
+		req.Equal(uint8(5), emu.CPU.A)
+	})
+
+	t.Run("bank", func(t *testing.T) {
+		req := require.New(t)
+		// 32kB without an MBC: bank 1 is always mapped
+		rom := testROM([]byte{code("JP a16"), 0x00, 0x40})
+		copy(rom[0x4000:], []byte{code("JR e8"), 0xFE})
+		emu := NewEmulator(rom, EmulatorOptions{Model: DMG, SkipBoot: true})
+		dbg := NewDebugger(emu)
+
+		other := dbg.AddBreakpoint(0x4000).WithBank(2)
+		for range 3 {
+			req.Equal(StopFrame, dbg.Continue().Reason)
+		}
+		req.Zero(other.Hits)
+
+		bp := dbg.AddBreakpoint(0x4000).WithBank(1)
+		stop := dbg.Continue()
+		req.Equal(StopBreakpoint, stop.Reason)
+		req.Equal(bp, stop.Breakpoint)
+		req.Equal(1, stop.Bank)
+	})
+}
+
+func TestWatchpoint(t *testing.T) {
+	t.Run("write", func(t *testing.T) {
+		req := require.New(t)
+		_, dbg := newDebugger(debugProgram)
+		wp := dbg.AddWatchpoint(0xC000, 0xC000, WatchWrite)
+		stop := dbg.Continue()
+		req.Equal(StopWatchpoint, stop.Reason)
+		req.Equal(wp, stop.Watchpoint)
+		req.Equal(uint16(0x015C), stop.PC, "stops after the instruction")
+		req.Equal(Access{Kind: WatchWrite, Addr: 0xC000, Value: 1, PC: 0x0159}, stop.Access)
+		req.Equal("watchpoint 1: write $C000 = $01 at 00:015C", stop.String())
+	})
+
+	t.Run("read", func(t *testing.T) {
+		req := require.New(t)
+		emu, dbg := newDebugger(debugProgram)
+		emu.Memory().WriteAt(0xC001, 0x42)
+		dbg.AddWatchpoint(0xC001, 0xC00F, WatchRead)
+		stop := dbg.Continue()
+		req.Equal(StopWatchpoint, stop.Reason)
+		req.Equal(uint16(0x0164), stop.PC)
+		req.Equal(Access{Kind: WatchRead, Addr: 0xC001, Value: 0x42, PC: 0x0163}, stop.Access)
+	})
+
+	t.Run("exec", func(t *testing.T) {
+		req := require.New(t)
+		emu, dbg := newDebugger(debugProgram)
+		// ld [$C000], a: the operands being read doesn't count
+		dbg.AddWatchpoint(0x0159, 0x015B, WatchRead|WatchExec)
+		for i := range 2 {
+			stop := dbg.Continue()
+			req.Equal(StopWatchpoint, stop.Reason)
+			req.Equal(uint16(0x0159), stop.PC, "stops before the instruction")
+			req.Equal(WatchExec, stop.Access.Kind)
+			req.Equal(uint8(i+1), emu.CPU.A)
+		}
+	})
+}
+
+func TestStep(t *testing.T) {
+	// stops at the call to Store
+	atCall := func(t *testing.T) (*Emulator, *Debugger) {
+		emu, dbg := newDebugger(debugProgram)
+		bp := dbg.AddBreakpoint(0x0153)
+		require.Equal(t, StopBreakpoint, dbg.Continue().Reason)
+		dbg.Remove(bp.ID)
+		return emu, dbg
+	}
+
+	t.Run("into", func(t *testing.T) {
+		req := require.New(t)
+		emu, dbg := atCall(t)
+		for _, pc := range []uint16{0x0159, 0x015C, 0x0160, 0x0163, 0x0164, 0x015F} {
+			stop := dbg.StepInto()
+			req.Equal(StopStep, stop.Reason)
+			req.Equal(pc, emu.CPU.PC)
+		}
+	})
+
+	t.Run("over", func(t *testing.T) {
+		req := require.New(t)
+		emu, dbg := atCall(t)
+		stop := dbg.StepOver()
+		req.Equal(StopStep, stop.Reason)
+		req.Equal(uint16(0x0156), stop.PC)
+		req.Equal(uint16(0xFFFE), emu.CPU.SP)
+
+		req.Equal(uint16(0x0157), dbg.StepOver().PC)
+		req.Equal(uint16(0x0152), dbg.StepOver().PC)
+	})
+
+	t.Run("over a breakpoint", func(t *testing.T) {
+		req := require.New(t)
+		_, dbg := atCall(t)
+		dbg.AddBreakpoint(0x0163)
+		stop := dbg.StepOver()
+		req.Equal(StopBreakpoint, stop.Reason)
+		req.False(dbg.Stepping(), "the step is abandoned")
+	})
+
+	t.Run("out", func(t *testing.T) {
+		req := require.New(t)
+		emu, dbg := atCall(t)
+		dbg.StepInto()
+		dbg.StepInto()
+		dbg.StepInto() // in Nested
+		stop := dbg.StepOut()
+		req.Equal(StopStep, stop.Reason)
+		req.Equal(uint16(0x015F), stop.PC)
+		stop = dbg.StepOut()
+		req.Equal(uint16(0x0156), stop.PC)
+		req.Equal(uint16(0xFFFE), emu.CPU.SP)
+	})
+
+	t.Run("run to", func(t *testing.T) {
+		req := require.New(t)
+		emu, dbg := atCall(t)
+		stop := dbg.RunTo(0x0163, ANY_BANK)
+		req.Equal(StopStep, stop.Reason)
+		req.Equal(uint16(0x0163), emu.CPU.PC)
+
+		// a full round to get back
+		stop = dbg.RunTo(0x0163, ANY_BANK)
+		req.Equal(uint16(0x0163), stop.PC)
+		req.Equal(uint8(2), emu.CPU.A)
+	})
+
+	t.Run("across frames", func(t *testing.T) {
+		req := require.New(t)
+		_, dbg := newDebugger(debugProgram)
+		// the main loop never returns
+		stop := dbg.StepOut()
+		req.Equal(StopFrame, stop.Reason)
+		req.True(dbg.Stepping())
+		req.Equal(StopFrame, dbg.Resume().Reason)
+		req.True(dbg.Stepping())
+
+		req.Equal(StopFrame, dbg.Continue().Reason)
+		req.False(dbg.Stepping())
+	})
+}
+
+// instrLen agrees with the disassembler for every unprefixed instruction
+func TestInstrLen(t *testing.T) {
+	for code, instr := range ops {
+		if _, ok := instr.(PREFIX_CB); ok {
+			continue
+		}
+		in := disasm.Decode(disasm.Bytes([]byte{code, 0, 0}, 0), 0)
+		require.Equal(t, in.Len(), instrLen(instr), instr.String())
+	}
+}
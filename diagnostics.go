@@ -0,0 +1,81 @@
+package gameboy
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Accesses that real hardware handles without complaint, but that usually
+// point at a bug in the game (or the emulator). Memory never panics on them;
+// in strict mode they're recorded, see Memory.WithStrict.
+var (
+	ErrUnusableMemory = errors.New("access to unusable memory")
+	ErrEchoRAM        = errors.New("access to echo RAM")
+	ErrBootROMWrite   = errors.New("write to the boot ROM")
+)
+
+// Stop recording diagnostics after this many, so a game that hammers an
+// unusable address every frame doesn't eat all memory.
+const MAX_DIAGNOSTICS = 1024
+
+// A memory access that was suspicious. Err is one of the errors above, or one
+// returned by the cartridge, e.g. cartridge.ErrROMWrite.
+type AccessError struct {
+	Addr  uint16
+	Value uint8 // the value written, or read
+	Write bool
+	Err   error
+}
+
+func (e *AccessError) Error() string {
+	op := "read"
+	if e.Write {
+		op = "write"
+	}
+	return fmt.Sprintf("%s %#02x at %#04x: %v", op, e.Value, e.Addr, e.Err)
+}
+
+func (e *AccessError) Unwrap() error { return e.Err }
+
+// Makes the memory record suspicious accesses, which are otherwise silently
+// handled the way the hardware does. See Diagnostics.
+func (m *Memory) WithStrict() *Memory {
+	m.strict = true
+	return m
+}
+
+// Calls f on every suspicious access as it happens, strict mode or not. For
+// example, a linter can flag homebrew that relies on echo RAM:
+//
+//	mem.WithWarning(func(e *AccessError) {
+//		if errors.Is(e, ErrEchoRAM) {
+//			log.Printf("PC=%04x: %v", cpu.PC, e)
+//		}
+//	})
+func (m *Memory) WithWarning(f func(*AccessError)) *Memory {
+	m.warn = f
+	return m
+}
+
+// The suspicious accesses recorded in strict mode, oldest first.
+func (m *Memory) Diagnostics() []*AccessError {
+	return m.diagnostics
+}
+
+func (m *Memory) diagnose(addr uint16, value uint8, write bool, err error) {
+	if err == nil || (!m.strict && m.warn == nil) {
+		return
+	}
+	e := &AccessError{
+		Addr:  addr,
+		Value: value,
+		Write: write,
+		Err:   err,
+	}
+	if m.warn != nil {
+		m.warn(e)
+	}
+	if m.strict && len(m.diagnostics) < MAX_DIAGNOSTICS {
+		m.diagnostics = append(m.diagnostics, e)
+	}
+}
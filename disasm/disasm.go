@@ -0,0 +1,276 @@
+// Package disasm turns SM83 machine code back into assembly, in the syntax
+// rgbds (rgbasm) accepts:
+//
+//	for _, in := range disasm.Range(mem, 0x0150, 0x0160) {
+//		fmt.Printf("%04x  %s\n", in.Addr, in)
+//	}
+//
+// The opcode tables are generated from gen/Opcodes.json, see opcodes.go.
+package disasm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Anything that bytes can be read from, e.g. *gameboy.Memory.
+type Memory interface {
+	Read(addr uint16) uint8
+}
+
+// The bytes of data, starting at address base. Reading outside of it gives
+// 0xFF, like reading unmapped memory.
+func Bytes(data []byte, base uint16) Memory {
+	return bytesMemory{data: data, base: base}
+}
+
+type bytesMemory struct {
+	data []byte
+	base uint16
+}
+
+func (m bytesMemory) Read(addr uint16) uint8 {
+	i := int(addr) - int(m.base)
+	if i < 0 || i >= len(m.data) {
+		return 0xFF
+	}
+	return m.data[i]
+}
+
+// generated, see opcodes.go
+type opcode struct {
+	mnemonic string
+	bytes    int
+	operands []operand
+}
+
+type operand struct {
+	name                 string
+	indirect             bool
+	increment, decrement bool
+}
+
+type Instruction struct {
+	Addr uint16
+	// The opcode, 0xCB included, followed by the operand bytes
+	Bytes []byte
+	// As in the opcode table, e.g. "LD" or "ILLEGAL_D3"
+	Mnemonic string
+	Operands []Operand
+	// Set for the 0xCB prefixed opcodes
+	Prefixed bool
+}
+
+type Operand struct {
+	// As in the opcode table: a register ("A", "HL"), a condition ("NZ"), a
+	// bit number ("3"), an RST vector ("$38"), or the kind of immediate
+	// value ("n8", "n16", "e8", "a8", "a16").
+	Name string
+	// The operand is the memory at the address, written [hl] or [$C000]
+	Indirect bool
+	// HL is incremented or decremented after use, as in [hl+]; SP is
+	// offset, as in sp + e8
+	Increment, Decrement bool
+	// The immediate value: n8, n16 and a16 as is, e8 sign extended, and a8
+	// with 0xFF00 added. Zero for the other operands.
+	Value int
+}
+
+// Whether the operand is read from the instruction's bytes
+func (o Operand) Immediate() bool {
+	switch o.Name {
+	case "n8", "n16", "e8", "a8", "a16":
+		return true
+	}
+	return false
+}
+
+// Decodes the instruction at addr. Every byte decodes to something: the
+// opcodes the cpu doesn't have come out as Illegal instructions.
+func Decode(mem Memory, addr uint16) Instruction {
+	code := mem.Read(addr)
+	op := unprefixed[code]
+	in := Instruction{Addr: addr, Bytes: []byte{code}}
+	if op.mnemonic == "PREFIX" {
+		code = mem.Read(addr + 1)
+		op = cbprefixed[code]
+		in.Bytes = append(in.Bytes, code)
+		in.Prefixed = true
+	}
+	for len(in.Bytes) < op.bytes {
+		in.Bytes = append(in.Bytes, mem.Read(addr+uint16(len(in.Bytes))))
+	}
+	in.Mnemonic = op.mnemonic
+
+	// immediate values are always last
+	imm := in.Bytes[len(in.Bytes)-1]
+	for _, o := range op.operands {
+		operand := Operand{
+			Name:      o.name,
+			Indirect:  o.indirect,
+			Increment: o.increment,
+			Decrement: o.decrement,
+		}
+		switch o.name {
+		case "n8":
+			operand.Value = int(imm)
+		case "e8":
+			operand.Value = int(int8(imm))
+		case "a8":
+			operand.Value = 0xFF00 + int(imm)
+		case "n16", "a16":
+			operand.Value = int(in.Bytes[len(in.Bytes)-2]) | int(imm)<<8
+		}
+		in.Operands = append(in.Operands, operand)
+	}
+	return in
+}
+
+// Decodes the instructions from start up to, not including, end. The last
+// one may run past end. An end of 0x10000 goes to the end of memory.
+func Range(mem Memory, start uint16, end int) []Instruction {
+	var ins []Instruction
+	for addr := int(start); addr < end; {
+		in := Decode(mem, uint16(addr))
+		ins = append(ins, in)
+		addr += in.Len()
+	}
+	return ins
+}
+
+// Length in bytes
+func (in Instruction) Len() int { return len(in.Bytes) }
+
+// The address after the instruction
+func (in Instruction) Next() uint16 { return in.Addr + uint16(in.Len()) }
+
+// Not an instruction the cpu has; it locks up on these
+func (in Instruction) Illegal() bool { return strings.HasPrefix(in.Mnemonic, "ILLEGAL") }
+
+// Where a JR, JP, CALL or RST goes, if it's known without running it; JP HL
+// and RET aren't.
+func (in Instruction) Target() (uint16, bool) {
+	switch in.Mnemonic {
+	case "JR":
+		e := in.Operands[len(in.Operands)-1].Value
+		return in.Next() + uint16(e), true
+	case "JP", "CALL":
+		if o := in.Operands[len(in.Operands)-1]; o.Name == "a16" {
+			return uint16(o.Value), true
+		}
+	case "RST":
+		vec, _ := strconv.ParseUint(strings.TrimPrefix(in.Operands[0].Name, "$"), 16, 16)
+		return uint16(vec), true
+	}
+	return 0, false
+}
+
+// The condition of a conditional jump, call or return ("NZ", "Z", "NC" or
+// "C"), or "" if there is none.
+func (in Instruction) Condition() string {
+	switch in.Mnemonic {
+	case "JR", "JP", "CALL", "RET":
+		if len(in.Operands) == 2 || in.Mnemonic == "RET" && len(in.Operands) == 1 {
+			return in.Operands[0].Name
+		}
+	}
+	return ""
+}
+
+// Whether execution never carries on with the next instruction: an
+// unconditional jump or return, or an illegal opcode.
+func (in Instruction) Ends() bool {
+	switch in.Mnemonic {
+	case "JR", "JP", "RET":
+		return in.Condition() == ""
+	case "RETI":
+		return true
+	}
+	return in.Illegal()
+}
+
+// In rgbds syntax, e.g. "ld a, [hl+]", "jr nz, $0150" or "ldh [$FF44], a".
+// Opcodes rgbds can't express come out as data, e.g. "db $D3".
+func (in Instruction) String() string {
+	return in.Format(nil)
+}
+
+// Like String, but label, if not nil, names addresses: jump targets and
+// 16-bit addresses are replaced by what it returns, if that's not "".
+func (in Instruction) Format(label func(addr uint16) string) string {
+	if in.Illegal() || in.Mnemonic == "STOP" && in.Bytes[1] != 0 {
+		return db(in.Bytes)
+	}
+	if in.Mnemonic == "STOP" {
+		return "stop"
+	}
+
+	target, hasTarget := in.Target()
+	var ops []string
+	for _, o := range in.Operands {
+		if hasTarget && o.Immediate() {
+			ops = append(ops, address(target, label))
+			continue
+		}
+		ops = append(ops, o.format(label))
+	}
+	// LD HL,SP+e8 takes sp + 5 or sp - 5 as a single operand
+	if in.Mnemonic == "LD" && len(ops) == 3 {
+		e := in.Operands[2].Value
+		sign := "+"
+		if e < 0 {
+			sign, e = "-", -e
+		}
+		ops = []string{ops[0], fmt.Sprintf("%s %s %d", ops[1], sign, e)}
+	}
+	s := strings.ToLower(in.Mnemonic)
+	if len(ops) > 0 {
+		s += " " + strings.Join(ops, ", ")
+	}
+	return s
+}
+
+func (o Operand) format(label func(addr uint16) string) string {
+	var s string
+	switch o.Name {
+	case "n8":
+		s = fmt.Sprintf("$%02X", o.Value)
+	case "e8":
+		s = fmt.Sprint(o.Value)
+	case "n16":
+		s = fmt.Sprintf("$%04X", o.Value)
+	case "a8", "a16":
+		s = address(uint16(o.Value), label)
+	default:
+		s = strings.ToLower(o.Name)
+	}
+	// SP is also "incremented" in SP+e8, which Format takes care of
+	if o.Name == "HL" && o.Increment {
+		s += "+"
+	}
+	if o.Name == "HL" && o.Decrement {
+		s += "-"
+	}
+	if o.Indirect {
+		s = "[" + s + "]"
+	}
+	return s
+}
+
+func address(addr uint16, label func(uint16) string) string {
+	if label != nil {
+		if s := label(addr); s != "" {
+			return s
+		}
+	}
+	return fmt.Sprintf("$%04X", addr)
+}
+
+func db(b []byte) string {
+	parts := make([]string, len(b))
+	for i, x := range b {
+		parts[i] = fmt.Sprintf("$%02X", x)
+	}
+	return "db " + strings.Join(parts, ", ")
+}
@@ -0,0 +1,122 @@
+package disasm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecode(t *testing.T) {
+	cases := []struct {
+		bytes []byte
+		want  string
+	}{
+		{[]byte{0x00}, "nop"},
+		{[]byte{0x3E, 0x42}, "ld a, $42"},
+		{[]byte{0x21, 0x34, 0x12}, "ld hl, $1234"},
+		{[]byte{0x2A}, "ld a, [hl+]"},
+		{[]byte{0x32}, "ld [hl-], a"},
+		{[]byte{0x08, 0x00, 0xC0}, "ld [$C000], sp"},
+		{[]byte{0xEA, 0x00, 0xC0}, "ld [$C000], a"},
+		{[]byte{0xE0, 0x44}, "ldh [$FF44], a"},
+		{[]byte{0xF0, 0x44}, "ldh a, [$FF44]"},
+		{[]byte{0xE2}, "ldh [c], a"},
+		{[]byte{0xF8, 0xFE}, "ld hl, sp - 2"},
+		{[]byte{0xF8, 0x05}, "ld hl, sp + 5"},
+		{[]byte{0xE8, 0xFE}, "add sp, -2"},
+		{[]byte{0x18, 0xFE}, "jr $0200"},
+		{[]byte{0x20, 0x05}, "jr nz, $0207"},
+		{[]byte{0xC3, 0x50, 0x01}, "jp $0150"},
+		{[]byte{0xDA, 0x50, 0x01}, "jp c, $0150"},
+		{[]byte{0xE9}, "jp hl"},
+		{[]byte{0xCD, 0x00, 0x40}, "call $4000"},
+		{[]byte{0xC0}, "ret nz"},
+		{[]byte{0xFF}, "rst $38"},
+		{[]byte{0xCB, 0x7E}, "bit 7, [hl]"},
+		{[]byte{0xCB, 0x37}, "swap a"},
+		{[]byte{0x10, 0x00}, "stop"},
+		{[]byte{0x10, 0x01}, "db $10, $01"},
+		{[]byte{0xD3}, "db $D3"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.want, func(t *testing.T) {
+			req := require.New(t)
+			in := Decode(Bytes(tc.bytes, 0x0200), 0x0200)
+			req.Equal(tc.want, in.String())
+			req.Equal(tc.bytes, in.Bytes)
+		})
+	}
+}
+
+func TestAllOpcodes(t *testing.T) {
+	for code := range 256 {
+		t.Run(fmt.Sprintf("%02x", code), func(t *testing.T) {
+			in := Decode(Bytes([]byte{uint8(code), 0x11, 0x22}, 0), 0)
+			want := unprefixed[code].bytes
+			if code == 0xCB {
+				want = 2
+			}
+			require.Equal(t, want, in.Len())
+			require.NotEmpty(t, in.String())
+
+			in = Decode(Bytes([]byte{0xCB, uint8(code)}, 0), 0)
+			require.True(t, in.Prefixed)
+			require.Equal(t, 2, in.Len())
+		})
+	}
+}
+
+func TestTarget(t *testing.T) {
+	cases := []struct {
+		bytes     []byte
+		target    uint16
+		hasTarget bool
+		cond      string
+		ends      bool
+	}{
+		{[]byte{0x18, 0xFE}, 0x0100, true, "", true},
+		{[]byte{0x38, 0x10}, 0x0112, true, "C", false},
+		{[]byte{0xC3, 0x50, 0x01}, 0x0150, true, "", true},
+		{[]byte{0xC4, 0x00, 0x20}, 0x2000, true, "NZ", false},
+		{[]byte{0xE9}, 0, false, "", true},
+		{[]byte{0xC9}, 0, false, "", true},
+		{[]byte{0xC8}, 0, false, "Z", false},
+		{[]byte{0xD9}, 0, false, "", true},
+		{[]byte{0xEF}, 0x0028, true, "", false},
+		{[]byte{0xDD}, 0, false, "", true},
+		{[]byte{0x3C}, 0, false, "", false},
+	}
+	for _, tc := range cases {
+		in := Decode(Bytes(tc.bytes, 0x0100), 0x0100)
+		target, ok := in.Target()
+		require.Equal(t, tc.hasTarget, ok, in.String())
+		require.Equal(t, tc.target, target, in.String())
+		require.Equal(t, tc.cond, in.Condition(), in.String())
+		require.Equal(t, tc.ends, in.Ends(), in.String())
+	}
+}
+
+func TestRange(t *testing.T) {
+	prog := []byte{
+		0x3E, 0x05, // ld a, $05
+		0xEA, 0x00, 0xC0, // ld [$C000], a
+		0x3C,       // inc a
+		0x18, 0xF8, // jr $0150
+	}
+	ins := Range(Bytes(prog, 0x0150), 0x0150, 0x0150+len(prog))
+	var got []string
+	for _, in := range ins {
+		got = append(got, fmt.Sprintf("%04X %s", in.Addr, in))
+	}
+	require.Equal(t, []string{
+		"0150 ld a, $05",
+		"0152 ld [$C000], a",
+		"0155 inc a",
+		"0156 jr $0150",
+	}, got)
+
+	labels := map[uint16]string{0x0150: "Main", 0xC000: "wCounter"}
+	require.Equal(t, "ld [wCounter], a", ins[1].Format(func(addr uint16) string { return labels[addr] }))
+	require.Equal(t, "jr Main", ins[3].Format(func(addr uint16) string { return labels[addr] }))
+}
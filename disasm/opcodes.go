@@ -0,0 +1,521 @@
+// Code generated by go run ./gen; DO NOT EDIT.
+
+package disasm
+
+var unprefixed = [256]opcode{
+	0x00: {mnemonic: "NOP", bytes: 1, operands: []operand{}},
+	0x01: {mnemonic: "LD", bytes: 3, operands: []operand{{name: "BC"}, {name: "n16"}}},
+	0x02: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "BC", indirect: true}, {name: "A"}}},
+	0x03: {mnemonic: "INC", bytes: 1, operands: []operand{{name: "BC"}}},
+	0x04: {mnemonic: "INC", bytes: 1, operands: []operand{{name: "B"}}},
+	0x05: {mnemonic: "DEC", bytes: 1, operands: []operand{{name: "B"}}},
+	0x06: {mnemonic: "LD", bytes: 2, operands: []operand{{name: "B"}, {name: "n8"}}},
+	0x07: {mnemonic: "RLCA", bytes: 1, operands: []operand{}},
+	0x08: {mnemonic: "LD", bytes: 3, operands: []operand{{name: "a16", indirect: true}, {name: "SP"}}},
+	0x09: {mnemonic: "ADD", bytes: 1, operands: []operand{{name: "HL"}, {name: "BC"}}},
+	0x0a: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "A"}, {name: "BC", indirect: true}}},
+	0x0b: {mnemonic: "DEC", bytes: 1, operands: []operand{{name: "BC"}}},
+	0x0c: {mnemonic: "INC", bytes: 1, operands: []operand{{name: "C"}}},
+	0x0d: {mnemonic: "DEC", bytes: 1, operands: []operand{{name: "C"}}},
+	0x0e: {mnemonic: "LD", bytes: 2, operands: []operand{{name: "C"}, {name: "n8"}}},
+	0x0f: {mnemonic: "RRCA", bytes: 1, operands: []operand{}},
+	0x10: {mnemonic: "STOP", bytes: 2, operands: []operand{{name: "n8"}}},
+	0x11: {mnemonic: "LD", bytes: 3, operands: []operand{{name: "DE"}, {name: "n16"}}},
+	0x12: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "DE", indirect: true}, {name: "A"}}},
+	0x13: {mnemonic: "INC", bytes: 1, operands: []operand{{name: "DE"}}},
+	0x14: {mnemonic: "INC", bytes: 1, operands: []operand{{name: "D"}}},
+	0x15: {mnemonic: "DEC", bytes: 1, operands: []operand{{name: "D"}}},
+	0x16: {mnemonic: "LD", bytes: 2, operands: []operand{{name: "D"}, {name: "n8"}}},
+	0x17: {mnemonic: "RLA", bytes: 1, operands: []operand{}},
+	0x18: {mnemonic: "JR", bytes: 2, operands: []operand{{name: "e8"}}},
+	0x19: {mnemonic: "ADD", bytes: 1, operands: []operand{{name: "HL"}, {name: "DE"}}},
+	0x1a: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "A"}, {name: "DE", indirect: true}}},
+	0x1b: {mnemonic: "DEC", bytes: 1, operands: []operand{{name: "DE"}}},
+	0x1c: {mnemonic: "INC", bytes: 1, operands: []operand{{name: "E"}}},
+	0x1d: {mnemonic: "DEC", bytes: 1, operands: []operand{{name: "E"}}},
+	0x1e: {mnemonic: "LD", bytes: 2, operands: []operand{{name: "E"}, {name: "n8"}}},
+	0x1f: {mnemonic: "RRA", bytes: 1, operands: []operand{}},
+	0x20: {mnemonic: "JR", bytes: 2, operands: []operand{{name: "NZ"}, {name: "e8"}}},
+	0x21: {mnemonic: "LD", bytes: 3, operands: []operand{{name: "HL"}, {name: "n16"}}},
+	0x22: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "HL", indirect: true, increment: true}, {name: "A"}}},
+	0x23: {mnemonic: "INC", bytes: 1, operands: []operand{{name: "HL"}}},
+	0x24: {mnemonic: "INC", bytes: 1, operands: []operand{{name: "H"}}},
+	0x25: {mnemonic: "DEC", bytes: 1, operands: []operand{{name: "H"}}},
+	0x26: {mnemonic: "LD", bytes: 2, operands: []operand{{name: "H"}, {name: "n8"}}},
+	0x27: {mnemonic: "DAA", bytes: 1, operands: []operand{}},
+	0x28: {mnemonic: "JR", bytes: 2, operands: []operand{{name: "Z"}, {name: "e8"}}},
+	0x29: {mnemonic: "ADD", bytes: 1, operands: []operand{{name: "HL"}, {name: "HL"}}},
+	0x2a: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "A"}, {name: "HL", indirect: true, increment: true}}},
+	0x2b: {mnemonic: "DEC", bytes: 1, operands: []operand{{name: "HL"}}},
+	0x2c: {mnemonic: "INC", bytes: 1, operands: []operand{{name: "L"}}},
+	0x2d: {mnemonic: "DEC", bytes: 1, operands: []operand{{name: "L"}}},
+	0x2e: {mnemonic: "LD", bytes: 2, operands: []operand{{name: "L"}, {name: "n8"}}},
+	0x2f: {mnemonic: "CPL", bytes: 1, operands: []operand{}},
+	0x30: {mnemonic: "JR", bytes: 2, operands: []operand{{name: "NC"}, {name: "e8"}}},
+	0x31: {mnemonic: "LD", bytes: 3, operands: []operand{{name: "SP"}, {name: "n16"}}},
+	0x32: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "HL", indirect: true, decrement: true}, {name: "A"}}},
+	0x33: {mnemonic: "INC", bytes: 1, operands: []operand{{name: "SP"}}},
+	0x34: {mnemonic: "INC", bytes: 1, operands: []operand{{name: "HL", indirect: true}}},
+	0x35: {mnemonic: "DEC", bytes: 1, operands: []operand{{name: "HL", indirect: true}}},
+	0x36: {mnemonic: "LD", bytes: 2, operands: []operand{{name: "HL", indirect: true}, {name: "n8"}}},
+	0x37: {mnemonic: "SCF", bytes: 1, operands: []operand{}},
+	0x38: {mnemonic: "JR", bytes: 2, operands: []operand{{name: "C"}, {name: "e8"}}},
+	0x39: {mnemonic: "ADD", bytes: 1, operands: []operand{{name: "HL"}, {name: "SP"}}},
+	0x3a: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "A"}, {name: "HL", indirect: true, decrement: true}}},
+	0x3b: {mnemonic: "DEC", bytes: 1, operands: []operand{{name: "SP"}}},
+	0x3c: {mnemonic: "INC", bytes: 1, operands: []operand{{name: "A"}}},
+	0x3d: {mnemonic: "DEC", bytes: 1, operands: []operand{{name: "A"}}},
+	0x3e: {mnemonic: "LD", bytes: 2, operands: []operand{{name: "A"}, {name: "n8"}}},
+	0x3f: {mnemonic: "CCF", bytes: 1, operands: []operand{}},
+	0x40: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "B"}, {name: "B"}}},
+	0x41: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "B"}, {name: "C"}}},
+	0x42: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "B"}, {name: "D"}}},
+	0x43: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "B"}, {name: "E"}}},
+	0x44: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "B"}, {name: "H"}}},
+	0x45: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "B"}, {name: "L"}}},
+	0x46: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "B"}, {name: "HL", indirect: true}}},
+	0x47: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "B"}, {name: "A"}}},
+	0x48: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "C"}, {name: "B"}}},
+	0x49: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "C"}, {name: "C"}}},
+	0x4a: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "C"}, {name: "D"}}},
+	0x4b: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "C"}, {name: "E"}}},
+	0x4c: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "C"}, {name: "H"}}},
+	0x4d: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "C"}, {name: "L"}}},
+	0x4e: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "C"}, {name: "HL", indirect: true}}},
+	0x4f: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "C"}, {name: "A"}}},
+	0x50: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "D"}, {name: "B"}}},
+	0x51: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "D"}, {name: "C"}}},
+	0x52: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "D"}, {name: "D"}}},
+	0x53: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "D"}, {name: "E"}}},
+	0x54: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "D"}, {name: "H"}}},
+	0x55: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "D"}, {name: "L"}}},
+	0x56: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "D"}, {name: "HL", indirect: true}}},
+	0x57: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "D"}, {name: "A"}}},
+	0x58: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "E"}, {name: "B"}}},
+	0x59: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "E"}, {name: "C"}}},
+	0x5a: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "E"}, {name: "D"}}},
+	0x5b: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "E"}, {name: "E"}}},
+	0x5c: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "E"}, {name: "H"}}},
+	0x5d: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "E"}, {name: "L"}}},
+	0x5e: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "E"}, {name: "HL", indirect: true}}},
+	0x5f: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "E"}, {name: "A"}}},
+	0x60: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "H"}, {name: "B"}}},
+	0x61: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "H"}, {name: "C"}}},
+	0x62: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "H"}, {name: "D"}}},
+	0x63: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "H"}, {name: "E"}}},
+	0x64: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "H"}, {name: "H"}}},
+	0x65: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "H"}, {name: "L"}}},
+	0x66: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "H"}, {name: "HL", indirect: true}}},
+	0x67: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "H"}, {name: "A"}}},
+	0x68: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "L"}, {name: "B"}}},
+	0x69: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "L"}, {name: "C"}}},
+	0x6a: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "L"}, {name: "D"}}},
+	0x6b: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "L"}, {name: "E"}}},
+	0x6c: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "L"}, {name: "H"}}},
+	0x6d: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "L"}, {name: "L"}}},
+	0x6e: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "L"}, {name: "HL", indirect: true}}},
+	0x6f: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "L"}, {name: "A"}}},
+	0x70: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "HL", indirect: true}, {name: "B"}}},
+	0x71: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "HL", indirect: true}, {name: "C"}}},
+	0x72: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "HL", indirect: true}, {name: "D"}}},
+	0x73: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "HL", indirect: true}, {name: "E"}}},
+	0x74: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "HL", indirect: true}, {name: "H"}}},
+	0x75: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "HL", indirect: true}, {name: "L"}}},
+	0x76: {mnemonic: "HALT", bytes: 1, operands: []operand{}},
+	0x77: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "HL", indirect: true}, {name: "A"}}},
+	0x78: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "A"}, {name: "B"}}},
+	0x79: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "A"}, {name: "C"}}},
+	0x7a: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "A"}, {name: "D"}}},
+	0x7b: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "A"}, {name: "E"}}},
+	0x7c: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "A"}, {name: "H"}}},
+	0x7d: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "A"}, {name: "L"}}},
+	0x7e: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "A"}, {name: "HL", indirect: true}}},
+	0x7f: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "A"}, {name: "A"}}},
+	0x80: {mnemonic: "ADD", bytes: 1, operands: []operand{{name: "A"}, {name: "B"}}},
+	0x81: {mnemonic: "ADD", bytes: 1, operands: []operand{{name: "A"}, {name: "C"}}},
+	0x82: {mnemonic: "ADD", bytes: 1, operands: []operand{{name: "A"}, {name: "D"}}},
+	0x83: {mnemonic: "ADD", bytes: 1, operands: []operand{{name: "A"}, {name: "E"}}},
+	0x84: {mnemonic: "ADD", bytes: 1, operands: []operand{{name: "A"}, {name: "H"}}},
+	0x85: {mnemonic: "ADD", bytes: 1, operands: []operand{{name: "A"}, {name: "L"}}},
+	0x86: {mnemonic: "ADD", bytes: 1, operands: []operand{{name: "A"}, {name: "HL", indirect: true}}},
+	0x87: {mnemonic: "ADD", bytes: 1, operands: []operand{{name: "A"}, {name: "A"}}},
+	0x88: {mnemonic: "ADC", bytes: 1, operands: []operand{{name: "A"}, {name: "B"}}},
+	0x89: {mnemonic: "ADC", bytes: 1, operands: []operand{{name: "A"}, {name: "C"}}},
+	0x8a: {mnemonic: "ADC", bytes: 1, operands: []operand{{name: "A"}, {name: "D"}}},
+	0x8b: {mnemonic: "ADC", bytes: 1, operands: []operand{{name: "A"}, {name: "E"}}},
+	0x8c: {mnemonic: "ADC", bytes: 1, operands: []operand{{name: "A"}, {name: "H"}}},
+	0x8d: {mnemonic: "ADC", bytes: 1, operands: []operand{{name: "A"}, {name: "L"}}},
+	0x8e: {mnemonic: "ADC", bytes: 1, operands: []operand{{name: "A"}, {name: "HL", indirect: true}}},
+	0x8f: {mnemonic: "ADC", bytes: 1, operands: []operand{{name: "A"}, {name: "A"}}},
+	0x90: {mnemonic: "SUB", bytes: 1, operands: []operand{{name: "A"}, {name: "B"}}},
+	0x91: {mnemonic: "SUB", bytes: 1, operands: []operand{{name: "A"}, {name: "C"}}},
+	0x92: {mnemonic: "SUB", bytes: 1, operands: []operand{{name: "A"}, {name: "D"}}},
+	0x93: {mnemonic: "SUB", bytes: 1, operands: []operand{{name: "A"}, {name: "E"}}},
+	0x94: {mnemonic: "SUB", bytes: 1, operands: []operand{{name: "A"}, {name: "H"}}},
+	0x95: {mnemonic: "SUB", bytes: 1, operands: []operand{{name: "A"}, {name: "L"}}},
+	0x96: {mnemonic: "SUB", bytes: 1, operands: []operand{{name: "A"}, {name: "HL", indirect: true}}},
+	0x97: {mnemonic: "SUB", bytes: 1, operands: []operand{{name: "A"}, {name: "A"}}},
+	0x98: {mnemonic: "SBC", bytes: 1, operands: []operand{{name: "A"}, {name: "B"}}},
+	0x99: {mnemonic: "SBC", bytes: 1, operands: []operand{{name: "A"}, {name: "C"}}},
+	0x9a: {mnemonic: "SBC", bytes: 1, operands: []operand{{name: "A"}, {name: "D"}}},
+	0x9b: {mnemonic: "SBC", bytes: 1, operands: []operand{{name: "A"}, {name: "E"}}},
+	0x9c: {mnemonic: "SBC", bytes: 1, operands: []operand{{name: "A"}, {name: "H"}}},
+	0x9d: {mnemonic: "SBC", bytes: 1, operands: []operand{{name: "A"}, {name: "L"}}},
+	0x9e: {mnemonic: "SBC", bytes: 1, operands: []operand{{name: "A"}, {name: "HL", indirect: true}}},
+	0x9f: {mnemonic: "SBC", bytes: 1, operands: []operand{{name: "A"}, {name: "A"}}},
+	0xa0: {mnemonic: "AND", bytes: 1, operands: []operand{{name: "A"}, {name: "B"}}},
+	0xa1: {mnemonic: "AND", bytes: 1, operands: []operand{{name: "A"}, {name: "C"}}},
+	0xa2: {mnemonic: "AND", bytes: 1, operands: []operand{{name: "A"}, {name: "D"}}},
+	0xa3: {mnemonic: "AND", bytes: 1, operands: []operand{{name: "A"}, {name: "E"}}},
+	0xa4: {mnemonic: "AND", bytes: 1, operands: []operand{{name: "A"}, {name: "H"}}},
+	0xa5: {mnemonic: "AND", bytes: 1, operands: []operand{{name: "A"}, {name: "L"}}},
+	0xa6: {mnemonic: "AND", bytes: 1, operands: []operand{{name: "A"}, {name: "HL", indirect: true}}},
+	0xa7: {mnemonic: "AND", bytes: 1, operands: []operand{{name: "A"}, {name: "A"}}},
+	0xa8: {mnemonic: "XOR", bytes: 1, operands: []operand{{name: "A"}, {name: "B"}}},
+	0xa9: {mnemonic: "XOR", bytes: 1, operands: []operand{{name: "A"}, {name: "C"}}},
+	0xaa: {mnemonic: "XOR", bytes: 1, operands: []operand{{name: "A"}, {name: "D"}}},
+	0xab: {mnemonic: "XOR", bytes: 1, operands: []operand{{name: "A"}, {name: "E"}}},
+	0xac: {mnemonic: "XOR", bytes: 1, operands: []operand{{name: "A"}, {name: "H"}}},
+	0xad: {mnemonic: "XOR", bytes: 1, operands: []operand{{name: "A"}, {name: "L"}}},
+	0xae: {mnemonic: "XOR", bytes: 1, operands: []operand{{name: "A"}, {name: "HL", indirect: true}}},
+	0xaf: {mnemonic: "XOR", bytes: 1, operands: []operand{{name: "A"}, {name: "A"}}},
+	0xb0: {mnemonic: "OR", bytes: 1, operands: []operand{{name: "A"}, {name: "B"}}},
+	0xb1: {mnemonic: "OR", bytes: 1, operands: []operand{{name: "A"}, {name: "C"}}},
+	0xb2: {mnemonic: "OR", bytes: 1, operands: []operand{{name: "A"}, {name: "D"}}},
+	0xb3: {mnemonic: "OR", bytes: 1, operands: []operand{{name: "A"}, {name: "E"}}},
+	0xb4: {mnemonic: "OR", bytes: 1, operands: []operand{{name: "A"}, {name: "H"}}},
+	0xb5: {mnemonic: "OR", bytes: 1, operands: []operand{{name: "A"}, {name: "L"}}},
+	0xb6: {mnemonic: "OR", bytes: 1, operands: []operand{{name: "A"}, {name: "HL", indirect: true}}},
+	0xb7: {mnemonic: "OR", bytes: 1, operands: []operand{{name: "A"}, {name: "A"}}},
+	0xb8: {mnemonic: "CP", bytes: 1, operands: []operand{{name: "A"}, {name: "B"}}},
+	0xb9: {mnemonic: "CP", bytes: 1, operands: []operand{{name: "A"}, {name: "C"}}},
+	0xba: {mnemonic: "CP", bytes: 1, operands: []operand{{name: "A"}, {name: "D"}}},
+	0xbb: {mnemonic: "CP", bytes: 1, operands: []operand{{name: "A"}, {name: "E"}}},
+	0xbc: {mnemonic: "CP", bytes: 1, operands: []operand{{name: "A"}, {name: "H"}}},
+	0xbd: {mnemonic: "CP", bytes: 1, operands: []operand{{name: "A"}, {name: "L"}}},
+	0xbe: {mnemonic: "CP", bytes: 1, operands: []operand{{name: "A"}, {name: "HL", indirect: true}}},
+	0xbf: {mnemonic: "CP", bytes: 1, operands: []operand{{name: "A"}, {name: "A"}}},
+	0xc0: {mnemonic: "RET", bytes: 1, operands: []operand{{name: "NZ"}}},
+	0xc1: {mnemonic: "POP", bytes: 1, operands: []operand{{name: "BC"}}},
+	0xc2: {mnemonic: "JP", bytes: 3, operands: []operand{{name: "NZ"}, {name: "a16"}}},
+	0xc3: {mnemonic: "JP", bytes: 3, operands: []operand{{name: "a16"}}},
+	0xc4: {mnemonic: "CALL", bytes: 3, operands: []operand{{name: "NZ"}, {name: "a16"}}},
+	0xc5: {mnemonic: "PUSH", bytes: 1, operands: []operand{{name: "BC"}}},
+	0xc6: {mnemonic: "ADD", bytes: 2, operands: []operand{{name: "A"}, {name: "n8"}}},
+	0xc7: {mnemonic: "RST", bytes: 1, operands: []operand{{name: "$00"}}},
+	0xc8: {mnemonic: "RET", bytes: 1, operands: []operand{{name: "Z"}}},
+	0xc9: {mnemonic: "RET", bytes: 1, operands: []operand{}},
+	0xca: {mnemonic: "JP", bytes: 3, operands: []operand{{name: "Z"}, {name: "a16"}}},
+	0xcb: {mnemonic: "PREFIX", bytes: 1, operands: []operand{}},
+	0xcc: {mnemonic: "CALL", bytes: 3, operands: []operand{{name: "Z"}, {name: "a16"}}},
+	0xcd: {mnemonic: "CALL", bytes: 3, operands: []operand{{name: "a16"}}},
+	0xce: {mnemonic: "ADC", bytes: 2, operands: []operand{{name: "A"}, {name: "n8"}}},
+	0xcf: {mnemonic: "RST", bytes: 1, operands: []operand{{name: "$08"}}},
+	0xd0: {mnemonic: "RET", bytes: 1, operands: []operand{{name: "NC"}}},
+	0xd1: {mnemonic: "POP", bytes: 1, operands: []operand{{name: "DE"}}},
+	0xd2: {mnemonic: "JP", bytes: 3, operands: []operand{{name: "NC"}, {name: "a16"}}},
+	0xd3: {mnemonic: "ILLEGAL_D3", bytes: 1, operands: []operand{}},
+	0xd4: {mnemonic: "CALL", bytes: 3, operands: []operand{{name: "NC"}, {name: "a16"}}},
+	0xd5: {mnemonic: "PUSH", bytes: 1, operands: []operand{{name: "DE"}}},
+	0xd6: {mnemonic: "SUB", bytes: 2, operands: []operand{{name: "A"}, {name: "n8"}}},
+	0xd7: {mnemonic: "RST", bytes: 1, operands: []operand{{name: "$10"}}},
+	0xd8: {mnemonic: "RET", bytes: 1, operands: []operand{{name: "C"}}},
+	0xd9: {mnemonic: "RETI", bytes: 1, operands: []operand{}},
+	0xda: {mnemonic: "JP", bytes: 3, operands: []operand{{name: "C"}, {name: "a16"}}},
+	0xdb: {mnemonic: "ILLEGAL_DB", bytes: 1, operands: []operand{}},
+	0xdc: {mnemonic: "CALL", bytes: 3, operands: []operand{{name: "C"}, {name: "a16"}}},
+	0xdd: {mnemonic: "ILLEGAL_DD", bytes: 1, operands: []operand{}},
+	0xde: {mnemonic: "SBC", bytes: 2, operands: []operand{{name: "A"}, {name: "n8"}}},
+	0xdf: {mnemonic: "RST", bytes: 1, operands: []operand{{name: "$18"}}},
+	0xe0: {mnemonic: "LDH", bytes: 2, operands: []operand{{name: "a8", indirect: true}, {name: "A"}}},
+	0xe1: {mnemonic: "POP", bytes: 1, operands: []operand{{name: "HL"}}},
+	0xe2: {mnemonic: "LDH", bytes: 1, operands: []operand{{name: "C", indirect: true}, {name: "A"}}},
+	0xe3: {mnemonic: "ILLEGAL_E3", bytes: 1, operands: []operand{}},
+	0xe4: {mnemonic: "ILLEGAL_E4", bytes: 1, operands: []operand{}},
+	0xe5: {mnemonic: "PUSH", bytes: 1, operands: []operand{{name: "HL"}}},
+	0xe6: {mnemonic: "AND", bytes: 2, operands: []operand{{name: "A"}, {name: "n8"}}},
+	0xe7: {mnemonic: "RST", bytes: 1, operands: []operand{{name: "$20"}}},
+	0xe8: {mnemonic: "ADD", bytes: 2, operands: []operand{{name: "SP"}, {name: "e8"}}},
+	0xe9: {mnemonic: "JP", bytes: 1, operands: []operand{{name: "HL"}}},
+	0xea: {mnemonic: "LD", bytes: 3, operands: []operand{{name: "a16", indirect: true}, {name: "A"}}},
+	0xeb: {mnemonic: "ILLEGAL_EB", bytes: 1, operands: []operand{}},
+	0xec: {mnemonic: "ILLEGAL_EC", bytes: 1, operands: []operand{}},
+	0xed: {mnemonic: "ILLEGAL_ED", bytes: 1, operands: []operand{}},
+	0xee: {mnemonic: "XOR", bytes: 2, operands: []operand{{name: "A"}, {name: "n8"}}},
+	0xef: {mnemonic: "RST", bytes: 1, operands: []operand{{name: "$28"}}},
+	0xf0: {mnemonic: "LDH", bytes: 2, operands: []operand{{name: "A"}, {name: "a8", indirect: true}}},
+	0xf1: {mnemonic: "POP", bytes: 1, operands: []operand{{name: "AF"}}},
+	0xf2: {mnemonic: "LDH", bytes: 1, operands: []operand{{name: "A"}, {name: "C", indirect: true}}},
+	0xf3: {mnemonic: "DI", bytes: 1, operands: []operand{}},
+	0xf4: {mnemonic: "ILLEGAL_F4", bytes: 1, operands: []operand{}},
+	0xf5: {mnemonic: "PUSH", bytes: 1, operands: []operand{{name: "AF"}}},
+	0xf6: {mnemonic: "OR", bytes: 2, operands: []operand{{name: "A"}, {name: "n8"}}},
+	0xf7: {mnemonic: "RST", bytes: 1, operands: []operand{{name: "$30"}}},
+	0xf8: {mnemonic: "LD", bytes: 2, operands: []operand{{name: "HL"}, {name: "SP", increment: true}, {name: "e8"}}},
+	0xf9: {mnemonic: "LD", bytes: 1, operands: []operand{{name: "SP"}, {name: "HL"}}},
+	0xfa: {mnemonic: "LD", bytes: 3, operands: []operand{{name: "A"}, {name: "a16", indirect: true}}},
+	0xfb: {mnemonic: "EI", bytes: 1, operands: []operand{}},
+	0xfc: {mnemonic: "ILLEGAL_FC", bytes: 1, operands: []operand{}},
+	0xfd: {mnemonic: "ILLEGAL_FD", bytes: 1, operands: []operand{}},
+	0xfe: {mnemonic: "CP", bytes: 2, operands: []operand{{name: "A"}, {name: "n8"}}},
+	0xff: {mnemonic: "RST", bytes: 1, operands: []operand{{name: "$38"}}},
+}
+
+var cbprefixed = [256]opcode{
+	0x00: {mnemonic: "RLC", bytes: 2, operands: []operand{{name: "B"}}},
+	0x01: {mnemonic: "RLC", bytes: 2, operands: []operand{{name: "C"}}},
+	0x02: {mnemonic: "RLC", bytes: 2, operands: []operand{{name: "D"}}},
+	0x03: {mnemonic: "RLC", bytes: 2, operands: []operand{{name: "E"}}},
+	0x04: {mnemonic: "RLC", bytes: 2, operands: []operand{{name: "H"}}},
+	0x05: {mnemonic: "RLC", bytes: 2, operands: []operand{{name: "L"}}},
+	0x06: {mnemonic: "RLC", bytes: 2, operands: []operand{{name: "HL", indirect: true}}},
+	0x07: {mnemonic: "RLC", bytes: 2, operands: []operand{{name: "A"}}},
+	0x08: {mnemonic: "RRC", bytes: 2, operands: []operand{{name: "B"}}},
+	0x09: {mnemonic: "RRC", bytes: 2, operands: []operand{{name: "C"}}},
+	0x0a: {mnemonic: "RRC", bytes: 2, operands: []operand{{name: "D"}}},
+	0x0b: {mnemonic: "RRC", bytes: 2, operands: []operand{{name: "E"}}},
+	0x0c: {mnemonic: "RRC", bytes: 2, operands: []operand{{name: "H"}}},
+	0x0d: {mnemonic: "RRC", bytes: 2, operands: []operand{{name: "L"}}},
+	0x0e: {mnemonic: "RRC", bytes: 2, operands: []operand{{name: "HL", indirect: true}}},
+	0x0f: {mnemonic: "RRC", bytes: 2, operands: []operand{{name: "A"}}},
+	0x10: {mnemonic: "RL", bytes: 2, operands: []operand{{name: "B"}}},
+	0x11: {mnemonic: "RL", bytes: 2, operands: []operand{{name: "C"}}},
+	0x12: {mnemonic: "RL", bytes: 2, operands: []operand{{name: "D"}}},
+	0x13: {mnemonic: "RL", bytes: 2, operands: []operand{{name: "E"}}},
+	0x14: {mnemonic: "RL", bytes: 2, operands: []operand{{name: "H"}}},
+	0x15: {mnemonic: "RL", bytes: 2, operands: []operand{{name: "L"}}},
+	0x16: {mnemonic: "RL", bytes: 2, operands: []operand{{name: "HL", indirect: true}}},
+	0x17: {mnemonic: "RL", bytes: 2, operands: []operand{{name: "A"}}},
+	0x18: {mnemonic: "RR", bytes: 2, operands: []operand{{name: "B"}}},
+	0x19: {mnemonic: "RR", bytes: 2, operands: []operand{{name: "C"}}},
+	0x1a: {mnemonic: "RR", bytes: 2, operands: []operand{{name: "D"}}},
+	0x1b: {mnemonic: "RR", bytes: 2, operands: []operand{{name: "E"}}},
+	0x1c: {mnemonic: "RR", bytes: 2, operands: []operand{{name: "H"}}},
+	0x1d: {mnemonic: "RR", bytes: 2, operands: []operand{{name: "L"}}},
+	0x1e: {mnemonic: "RR", bytes: 2, operands: []operand{{name: "HL", indirect: true}}},
+	0x1f: {mnemonic: "RR", bytes: 2, operands: []operand{{name: "A"}}},
+	0x20: {mnemonic: "SLA", bytes: 2, operands: []operand{{name: "B"}}},
+	0x21: {mnemonic: "SLA", bytes: 2, operands: []operand{{name: "C"}}},
+	0x22: {mnemonic: "SLA", bytes: 2, operands: []operand{{name: "D"}}},
+	0x23: {mnemonic: "SLA", bytes: 2, operands: []operand{{name: "E"}}},
+	0x24: {mnemonic: "SLA", bytes: 2, operands: []operand{{name: "H"}}},
+	0x25: {mnemonic: "SLA", bytes: 2, operands: []operand{{name: "L"}}},
+	0x26: {mnemonic: "SLA", bytes: 2, operands: []operand{{name: "HL", indirect: true}}},
+	0x27: {mnemonic: "SLA", bytes: 2, operands: []operand{{name: "A"}}},
+	0x28: {mnemonic: "SRA", bytes: 2, operands: []operand{{name: "B"}}},
+	0x29: {mnemonic: "SRA", bytes: 2, operands: []operand{{name: "C"}}},
+	0x2a: {mnemonic: "SRA", bytes: 2, operands: []operand{{name: "D"}}},
+	0x2b: {mnemonic: "SRA", bytes: 2, operands: []operand{{name: "E"}}},
+	0x2c: {mnemonic: "SRA", bytes: 2, operands: []operand{{name: "H"}}},
+	0x2d: {mnemonic: "SRA", bytes: 2, operands: []operand{{name: "L"}}},
+	0x2e: {mnemonic: "SRA", bytes: 2, operands: []operand{{name: "HL", indirect: true}}},
+	0x2f: {mnemonic: "SRA", bytes: 2, operands: []operand{{name: "A"}}},
+	0x30: {mnemonic: "SWAP", bytes: 2, operands: []operand{{name: "B"}}},
+	0x31: {mnemonic: "SWAP", bytes: 2, operands: []operand{{name: "C"}}},
+	0x32: {mnemonic: "SWAP", bytes: 2, operands: []operand{{name: "D"}}},
+	0x33: {mnemonic: "SWAP", bytes: 2, operands: []operand{{name: "E"}}},
+	0x34: {mnemonic: "SWAP", bytes: 2, operands: []operand{{name: "H"}}},
+	0x35: {mnemonic: "SWAP", bytes: 2, operands: []operand{{name: "L"}}},
+	0x36: {mnemonic: "SWAP", bytes: 2, operands: []operand{{name: "HL", indirect: true}}},
+	0x37: {mnemonic: "SWAP", bytes: 2, operands: []operand{{name: "A"}}},
+	0x38: {mnemonic: "SRL", bytes: 2, operands: []operand{{name: "B"}}},
+	0x39: {mnemonic: "SRL", bytes: 2, operands: []operand{{name: "C"}}},
+	0x3a: {mnemonic: "SRL", bytes: 2, operands: []operand{{name: "D"}}},
+	0x3b: {mnemonic: "SRL", bytes: 2, operands: []operand{{name: "E"}}},
+	0x3c: {mnemonic: "SRL", bytes: 2, operands: []operand{{name: "H"}}},
+	0x3d: {mnemonic: "SRL", bytes: 2, operands: []operand{{name: "L"}}},
+	0x3e: {mnemonic: "SRL", bytes: 2, operands: []operand{{name: "HL", indirect: true}}},
+	0x3f: {mnemonic: "SRL", bytes: 2, operands: []operand{{name: "A"}}},
+	0x40: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "0"}, {name: "B"}}},
+	0x41: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "0"}, {name: "C"}}},
+	0x42: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "0"}, {name: "D"}}},
+	0x43: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "0"}, {name: "E"}}},
+	0x44: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "0"}, {name: "H"}}},
+	0x45: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "0"}, {name: "L"}}},
+	0x46: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "0"}, {name: "HL", indirect: true}}},
+	0x47: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "0"}, {name: "A"}}},
+	0x48: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "1"}, {name: "B"}}},
+	0x49: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "1"}, {name: "C"}}},
+	0x4a: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "1"}, {name: "D"}}},
+	0x4b: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "1"}, {name: "E"}}},
+	0x4c: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "1"}, {name: "H"}}},
+	0x4d: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "1"}, {name: "L"}}},
+	0x4e: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "1"}, {name: "HL", indirect: true}}},
+	0x4f: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "1"}, {name: "A"}}},
+	0x50: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "2"}, {name: "B"}}},
+	0x51: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "2"}, {name: "C"}}},
+	0x52: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "2"}, {name: "D"}}},
+	0x53: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "2"}, {name: "E"}}},
+	0x54: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "2"}, {name: "H"}}},
+	0x55: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "2"}, {name: "L"}}},
+	0x56: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "2"}, {name: "HL", indirect: true}}},
+	0x57: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "2"}, {name: "A"}}},
+	0x58: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "3"}, {name: "B"}}},
+	0x59: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "3"}, {name: "C"}}},
+	0x5a: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "3"}, {name: "D"}}},
+	0x5b: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "3"}, {name: "E"}}},
+	0x5c: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "3"}, {name: "H"}}},
+	0x5d: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "3"}, {name: "L"}}},
+	0x5e: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "3"}, {name: "HL", indirect: true}}},
+	0x5f: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "3"}, {name: "A"}}},
+	0x60: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "4"}, {name: "B"}}},
+	0x61: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "4"}, {name: "C"}}},
+	0x62: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "4"}, {name: "D"}}},
+	0x63: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "4"}, {name: "E"}}},
+	0x64: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "4"}, {name: "H"}}},
+	0x65: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "4"}, {name: "L"}}},
+	0x66: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "4"}, {name: "HL", indirect: true}}},
+	0x67: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "4"}, {name: "A"}}},
+	0x68: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "5"}, {name: "B"}}},
+	0x69: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "5"}, {name: "C"}}},
+	0x6a: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "5"}, {name: "D"}}},
+	0x6b: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "5"}, {name: "E"}}},
+	0x6c: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "5"}, {name: "H"}}},
+	0x6d: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "5"}, {name: "L"}}},
+	0x6e: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "5"}, {name: "HL", indirect: true}}},
+	0x6f: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "5"}, {name: "A"}}},
+	0x70: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "6"}, {name: "B"}}},
+	0x71: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "6"}, {name: "C"}}},
+	0x72: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "6"}, {name: "D"}}},
+	0x73: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "6"}, {name: "E"}}},
+	0x74: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "6"}, {name: "H"}}},
+	0x75: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "6"}, {name: "L"}}},
+	0x76: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "6"}, {name: "HL", indirect: true}}},
+	0x77: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "6"}, {name: "A"}}},
+	0x78: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "7"}, {name: "B"}}},
+	0x79: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "7"}, {name: "C"}}},
+	0x7a: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "7"}, {name: "D"}}},
+	0x7b: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "7"}, {name: "E"}}},
+	0x7c: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "7"}, {name: "H"}}},
+	0x7d: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "7"}, {name: "L"}}},
+	0x7e: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "7"}, {name: "HL", indirect: true}}},
+	0x7f: {mnemonic: "BIT", bytes: 2, operands: []operand{{name: "7"}, {name: "A"}}},
+	0x80: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "0"}, {name: "B"}}},
+	0x81: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "0"}, {name: "C"}}},
+	0x82: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "0"}, {name: "D"}}},
+	0x83: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "0"}, {name: "E"}}},
+	0x84: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "0"}, {name: "H"}}},
+	0x85: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "0"}, {name: "L"}}},
+	0x86: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "0"}, {name: "HL", indirect: true}}},
+	0x87: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "0"}, {name: "A"}}},
+	0x88: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "1"}, {name: "B"}}},
+	0x89: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "1"}, {name: "C"}}},
+	0x8a: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "1"}, {name: "D"}}},
+	0x8b: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "1"}, {name: "E"}}},
+	0x8c: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "1"}, {name: "H"}}},
+	0x8d: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "1"}, {name: "L"}}},
+	0x8e: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "1"}, {name: "HL", indirect: true}}},
+	0x8f: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "1"}, {name: "A"}}},
+	0x90: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "2"}, {name: "B"}}},
+	0x91: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "2"}, {name: "C"}}},
+	0x92: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "2"}, {name: "D"}}},
+	0x93: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "2"}, {name: "E"}}},
+	0x94: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "2"}, {name: "H"}}},
+	0x95: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "2"}, {name: "L"}}},
+	0x96: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "2"}, {name: "HL", indirect: true}}},
+	0x97: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "2"}, {name: "A"}}},
+	0x98: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "3"}, {name: "B"}}},
+	0x99: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "3"}, {name: "C"}}},
+	0x9a: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "3"}, {name: "D"}}},
+	0x9b: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "3"}, {name: "E"}}},
+	0x9c: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "3"}, {name: "H"}}},
+	0x9d: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "3"}, {name: "L"}}},
+	0x9e: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "3"}, {name: "HL", indirect: true}}},
+	0x9f: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "3"}, {name: "A"}}},
+	0xa0: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "4"}, {name: "B"}}},
+	0xa1: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "4"}, {name: "C"}}},
+	0xa2: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "4"}, {name: "D"}}},
+	0xa3: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "4"}, {name: "E"}}},
+	0xa4: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "4"}, {name: "H"}}},
+	0xa5: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "4"}, {name: "L"}}},
+	0xa6: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "4"}, {name: "HL", indirect: true}}},
+	0xa7: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "4"}, {name: "A"}}},
+	0xa8: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "5"}, {name: "B"}}},
+	0xa9: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "5"}, {name: "C"}}},
+	0xaa: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "5"}, {name: "D"}}},
+	0xab: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "5"}, {name: "E"}}},
+	0xac: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "5"}, {name: "H"}}},
+	0xad: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "5"}, {name: "L"}}},
+	0xae: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "5"}, {name: "HL", indirect: true}}},
+	0xaf: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "5"}, {name: "A"}}},
+	0xb0: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "6"}, {name: "B"}}},
+	0xb1: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "6"}, {name: "C"}}},
+	0xb2: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "6"}, {name: "D"}}},
+	0xb3: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "6"}, {name: "E"}}},
+	0xb4: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "6"}, {name: "H"}}},
+	0xb5: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "6"}, {name: "L"}}},
+	0xb6: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "6"}, {name: "HL", indirect: true}}},
+	0xb7: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "6"}, {name: "A"}}},
+	0xb8: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "7"}, {name: "B"}}},
+	0xb9: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "7"}, {name: "C"}}},
+	0xba: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "7"}, {name: "D"}}},
+	0xbb: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "7"}, {name: "E"}}},
+	0xbc: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "7"}, {name: "H"}}},
+	0xbd: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "7"}, {name: "L"}}},
+	0xbe: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "7"}, {name: "HL", indirect: true}}},
+	0xbf: {mnemonic: "RES", bytes: 2, operands: []operand{{name: "7"}, {name: "A"}}},
+	0xc0: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "0"}, {name: "B"}}},
+	0xc1: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "0"}, {name: "C"}}},
+	0xc2: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "0"}, {name: "D"}}},
+	0xc3: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "0"}, {name: "E"}}},
+	0xc4: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "0"}, {name: "H"}}},
+	0xc5: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "0"}, {name: "L"}}},
+	0xc6: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "0"}, {name: "HL", indirect: true}}},
+	0xc7: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "0"}, {name: "A"}}},
+	0xc8: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "1"}, {name: "B"}}},
+	0xc9: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "1"}, {name: "C"}}},
+	0xca: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "1"}, {name: "D"}}},
+	0xcb: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "1"}, {name: "E"}}},
+	0xcc: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "1"}, {name: "H"}}},
+	0xcd: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "1"}, {name: "L"}}},
+	0xce: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "1"}, {name: "HL", indirect: true}}},
+	0xcf: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "1"}, {name: "A"}}},
+	0xd0: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "2"}, {name: "B"}}},
+	0xd1: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "2"}, {name: "C"}}},
+	0xd2: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "2"}, {name: "D"}}},
+	0xd3: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "2"}, {name: "E"}}},
+	0xd4: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "2"}, {name: "H"}}},
+	0xd5: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "2"}, {name: "L"}}},
+	0xd6: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "2"}, {name: "HL", indirect: true}}},
+	0xd7: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "2"}, {name: "A"}}},
+	0xd8: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "3"}, {name: "B"}}},
+	0xd9: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "3"}, {name: "C"}}},
+	0xda: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "3"}, {name: "D"}}},
+	0xdb: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "3"}, {name: "E"}}},
+	0xdc: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "3"}, {name: "H"}}},
+	0xdd: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "3"}, {name: "L"}}},
+	0xde: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "3"}, {name: "HL", indirect: true}}},
+	0xdf: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "3"}, {name: "A"}}},
+	0xe0: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "4"}, {name: "B"}}},
+	0xe1: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "4"}, {name: "C"}}},
+	0xe2: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "4"}, {name: "D"}}},
+	0xe3: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "4"}, {name: "E"}}},
+	0xe4: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "4"}, {name: "H"}}},
+	0xe5: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "4"}, {name: "L"}}},
+	0xe6: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "4"}, {name: "HL", indirect: true}}},
+	0xe7: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "4"}, {name: "A"}}},
+	0xe8: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "5"}, {name: "B"}}},
+	0xe9: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "5"}, {name: "C"}}},
+	0xea: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "5"}, {name: "D"}}},
+	0xeb: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "5"}, {name: "E"}}},
+	0xec: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "5"}, {name: "H"}}},
+	0xed: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "5"}, {name: "L"}}},
+	0xee: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "5"}, {name: "HL", indirect: true}}},
+	0xef: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "5"}, {name: "A"}}},
+	0xf0: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "6"}, {name: "B"}}},
+	0xf1: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "6"}, {name: "C"}}},
+	0xf2: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "6"}, {name: "D"}}},
+	0xf3: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "6"}, {name: "E"}}},
+	0xf4: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "6"}, {name: "H"}}},
+	0xf5: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "6"}, {name: "L"}}},
+	0xf6: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "6"}, {name: "HL", indirect: true}}},
+	0xf7: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "6"}, {name: "A"}}},
+	0xf8: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "7"}, {name: "B"}}},
+	0xf9: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "7"}, {name: "C"}}},
+	0xfa: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "7"}, {name: "D"}}},
+	0xfb: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "7"}, {name: "E"}}},
+	0xfc: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "7"}, {name: "H"}}},
+	0xfd: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "7"}, {name: "L"}}},
+	0xfe: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "7"}, {name: "HL", indirect: true}}},
+	0xff: {mnemonic: "SET", bytes: 2, operands: []operand{{name: "7"}, {name: "A"}}},
+}
@@ -0,0 +1,423 @@
+package disasm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const BANK_SIZE = 0x4000
+
+// Where execution starts: the cartridge entry point, and the RST and
+// interrupt vectors.
+var ENTRY_POINTS = map[uint16]string{
+	0x0000: "RST_00",
+	0x0008: "RST_08",
+	0x0010: "RST_10",
+	0x0018: "RST_18",
+	0x0020: "RST_20",
+	0x0028: "RST_28",
+	0x0030: "RST_30",
+	0x0038: "RST_38",
+	0x0040: "VBlankInterrupt",
+	0x0048: "LCDCInterrupt",
+	0x0050: "TimerInterrupt",
+	0x0058: "SerialInterrupt",
+	0x0060: "JoypadInterrupt",
+	0x0100: "Boot",
+}
+
+// A place in the ROM: the bank, and the address the cpu sees it at, i.e.
+// 0x0000-0x3FFF in bank 0 and 0x4000-0x7FFF in the others.
+type Location struct {
+	Bank int
+	Addr uint16
+}
+
+func (l Location) String() string { return fmt.Sprintf("%02X:%04X", l.Bank, l.Addr) }
+
+// Position in the ROM file
+func (l Location) Offset() int {
+	if l.Bank == 0 {
+		return int(l.Addr)
+	}
+	return l.Bank*BANK_SIZE + int(l.Addr) - BANK_SIZE
+}
+
+// The addresses the bank is seen at, end excluded
+func window(bank int) (start, end int) {
+	if bank == 0 {
+		return 0, BANK_SIZE
+	}
+	return BANK_SIZE, 2 * BANK_SIZE
+}
+
+// A ROM, split into code and data by following the code from the entry
+// points, see Analyze.
+type Program struct {
+	rom    []byte
+	banks  int
+	code   map[Location]Instruction
+	owned  []bool // bytes that belong to an instruction
+	labels map[Location]string
+	// the bank at 0x4000-0x7FFF while an instruction runs; 0 if unknown
+	switched map[Location]int
+}
+
+type job struct {
+	loc      Location
+	switched int
+}
+
+// Follows the code from ENTRY_POINTS through every jump, call and RST it
+// can work out the destination of, and labels the destinations.
+//
+// Calls and jumps into 0x4000-0x7FFF from bank 0 go to whichever bank is
+// switched in. That's tracked by looking for writes of a known value to the
+// MBC's bank register, e.g. "ld a, 3; ld [$2000], a", before them; jumps
+// into an unknown bank aren't followed. Code that's reached with different
+// banks switched in is only followed with the first.
+func Analyze(rom []byte) *Program {
+	p := &Program{
+		rom:      rom,
+		banks:    (len(rom) + BANK_SIZE - 1) / BANK_SIZE,
+		code:     map[Location]Instruction{},
+		owned:    make([]bool, len(rom)),
+		labels:   map[Location]string{},
+		switched: map[Location]int{},
+	}
+	switched := 0
+	if p.banks <= 2 {
+		// no MBC; bank 1 is always there
+		switched = 1
+	}
+
+	var work []job
+	for _, addr := range slices.Sorted(maps.Keys(ENTRY_POINTS)) {
+		loc := Location{0, addr}
+		p.labels[loc] = ENTRY_POINTS[addr]
+		work = append(work, job{loc, switched})
+	}
+	// depth first, so the blocks of a routine are traced together
+	slices.Reverse(work)
+	for len(work) > 0 {
+		j := work[len(work)-1]
+		work = p.trace(j, work[:len(work)-1])
+	}
+	return p
+}
+
+// Decodes from j.loc until the code ends, and adds the destinations of the
+// jumps along the way to work.
+func (p *Program) trace(j job, work []job) []job {
+	loc, switched := j.loc, j.switched
+	start, end := window(loc.Bank)
+	base := loc.Bank * BANK_SIZE
+	mem := Bytes(p.rom[base:min(len(p.rom), base+BANK_SIZE)], uint16(start))
+	var regs registers
+	regs.reset()
+
+	for {
+		off := loc.Offset()
+		if off >= len(p.rom) || p.owned[off] {
+			// ran into code that's already traced, or into the middle of an
+			// instruction
+			return work
+		}
+		in := Decode(mem, loc.Addr)
+		if int(loc.Addr)+in.Len() > end || off+in.Len() > len(p.rom) || slices.Contains(p.owned[off:off+in.Len()], true) {
+			return work
+		}
+		for i := range in.Len() {
+			p.owned[off+i] = true
+		}
+		p.code[loc] = in
+		p.switched[loc] = switched
+
+		if bank, ok := regs.update(in); ok {
+			switched = p.mbcBank(bank)
+		}
+		if target, ok := in.Target(); ok {
+			if dest, ok := p.resolve(loc, switched, target); ok {
+				p.label(dest, in.Mnemonic)
+				// code in a switchable bank only runs with that bank in
+				next := switched
+				if loc.Bank > 0 {
+					next = loc.Bank
+				}
+				work = append(work, job{dest, next})
+			}
+		}
+		if in.Ends() {
+			return work
+		}
+		loc.Addr = in.Next()
+	}
+}
+
+// Where in the ROM addr goes, seen from code at loc with the given bank
+// switched in.
+func (p *Program) resolve(loc Location, switched int, addr uint16) (Location, bool) {
+	switch {
+	case addr < BANK_SIZE:
+		return Location{0, addr}, true
+	case addr >= 2*BANK_SIZE:
+		return Location{}, false // not ROM
+	case loc.Bank > 0:
+		return Location{loc.Bank, addr}, true
+	case switched > 0 && switched < p.banks:
+		return Location{switched, addr}, true
+	}
+	return Location{}, false
+}
+
+func (p *Program) label(loc Location, mnemonic string) {
+	name, ok := p.labels[loc]
+	switch {
+	case mnemonic == "CALL" && (!ok || strings.HasPrefix(name, "Jump_")):
+		p.labels[loc] = fmt.Sprintf("Call_%03X_%04X", loc.Bank, loc.Addr)
+	case !ok:
+		p.labels[loc] = fmt.Sprintf("Jump_%03X_%04X", loc.Bank, loc.Addr)
+	}
+}
+
+// The bank selected by writing v to the bank register
+func (p *Program) mbcBank(v int) int {
+	kind := byte(0)
+	if len(p.rom) > 0x0147 {
+		kind = p.rom[0x0147]
+	}
+	if kind >= 0x19 && kind <= 0x1E {
+		return v // MBC5 can select bank 0
+	}
+	return max(v, 1)
+}
+
+// Register values known from earlier instructions in the same block; -1 if
+// unknown. Just enough to follow bank switches.
+type registers struct {
+	a, hl int
+}
+
+func (r *registers) reset() { r.a, r.hl = -1, -1 }
+
+// Tracks the effect of in, and returns the value written to the bank
+// register (0x2000-0x3FFF), if any.
+func (r *registers) update(in Instruction) (bank int, ok bool) {
+	isBankRegister := func(addr int) bool { return addr >= 0x2000 && addr < 0x4000 }
+	first := Operand{}
+	if len(in.Operands) > 0 {
+		first = in.Operands[0]
+	}
+
+	switch {
+	case in.Mnemonic == "LD" && first.Indirect && first.Name == "a16" && in.Operands[1].Name == "A":
+		if isBankRegister(first.Value) && r.a >= 0 {
+			return r.a, true
+		}
+		return 0, false
+	case in.Mnemonic == "LD" && first.Indirect && first.Name == "HL" && in.Operands[1].Name == "A":
+		if isBankRegister(r.hl) && r.a >= 0 && !first.Increment && !first.Decrement {
+			return r.a, true
+		}
+	case in.Mnemonic == "LD" && first.Name == "A" && !first.Indirect && in.Operands[1].Name == "n8":
+		r.a = in.Operands[1].Value
+		return 0, false
+	case in.Mnemonic == "XOR" && first.Name == "A" && in.Operands[1].Name == "A":
+		r.a = 0
+		return 0, false
+	case in.Mnemonic == "LD" && first.Name == "HL" && !first.Indirect && in.Operands[1].Name == "n16":
+		r.hl = in.Operands[1].Value
+		return 0, false
+	}
+
+	switch in.Mnemonic {
+	case "CALL", "RST":
+		r.reset()
+		return 0, false
+	case "CPL", "DAA", "RLA", "RRA", "RLCA", "RRCA":
+		r.a = -1
+	}
+	for _, o := range in.Operands {
+		if o.Name == "HL" && (o.Increment || o.Decrement) {
+			r.hl = -1
+		}
+	}
+	if first.Indirect || in.Mnemonic == "CP" || in.Mnemonic == "BIT" {
+		return 0, false
+	}
+	switch first.Name {
+	case "A", "AF":
+		r.a = -1
+	case "H", "L", "HL":
+		r.hl = -1
+	}
+	// CB prefixed shifts and SET/RES name the register last
+	if in.Prefixed && in.Mnemonic != "BIT" {
+		switch last := in.Operands[len(in.Operands)-1]; {
+		case last.Indirect:
+		case last.Name == "A":
+			r.a = -1
+		case last.Name == "H" || last.Name == "L":
+			r.hl = -1
+		}
+	}
+	return 0, false
+}
+
+// Whether an instruction starts at loc
+func (p *Program) Code(loc Location) (Instruction, bool) {
+	in, ok := p.code[loc]
+	return in, ok
+}
+
+// The label at loc, or "" if there's none. Only the start of an
+// instruction can have a label.
+func (p *Program) Label(loc Location) string {
+	if _, ok := p.code[loc]; !ok {
+		return ""
+	}
+	return p.labels[loc]
+}
+
+func (p *Program) Banks() int { return p.banks }
+
+// Writes the bank as an rgbds section: the code as instructions, and the
+// rest as data.
+func (p *Program) WriteBank(w io.Writer, bank int) error {
+	bw := bufio.NewWriter(w)
+	if bank == 0 {
+		fmt.Fprintf(bw, "SECTION \"ROM Bank $%03X\", ROM0[$0000]\n", bank)
+	} else {
+		fmt.Fprintf(bw, "SECTION \"ROM Bank $%03X\", ROMX[$4000], BANK[$%03X]\n", bank, bank)
+	}
+
+	start, _ := window(bank)
+	size := min(BANK_SIZE, len(p.rom)-bank*BANK_SIZE)
+	for i := 0; i < size; {
+		loc := Location{bank, uint16(start + i)}
+		if in, ok := p.code[loc]; ok {
+			if name := p.labels[loc]; name != "" {
+				fmt.Fprintf(bw, "\n%s:\n", name)
+			}
+			fmt.Fprintf(bw, "\t%s\n", in.Format(p.labeler(loc)))
+			i += in.Len()
+			continue
+		}
+		// data, up to the next instruction
+		n := 0
+		for i+n < size && !p.owned[loc.Offset()+n] {
+			n++
+		}
+		p.writeData(bw, p.rom[loc.Offset():loc.Offset()+n])
+		i += n
+	}
+	return bw.Flush()
+}
+
+// Names the addresses in the instruction at loc that are labelled code.
+func (p *Program) labeler(loc Location) func(uint16) string {
+	switched := p.switched[loc]
+	return func(addr uint16) string {
+		if dest, ok := p.resolve(loc, switched, addr); ok {
+			return p.Label(dest)
+		}
+		return ""
+	}
+}
+
+// Runs of a repeated byte, such as padding, become ds; the rest db, eight
+// bytes to a line.
+func (p *Program) writeData(w io.Writer, data []byte) {
+	const MIN_RUN = 16
+	for len(data) > 0 {
+		run := 1
+		for run < len(data) && data[run] == data[0] {
+			run++
+		}
+		if run >= MIN_RUN {
+			fmt.Fprintf(w, "\tds %d, $%02X\n", run, data[0])
+			data = data[run:]
+			continue
+		}
+		n := min(8, len(data))
+		// don't swallow the start of a run
+		for j := 1; j < n; j++ {
+			k := j
+			for k < len(data) && data[k] == data[j] {
+				k++
+			}
+			if k-j >= MIN_RUN {
+				n = j
+				break
+			}
+		}
+		fmt.Fprintf(w, "\t%s\n", db(data[:n]))
+		data = data[n:]
+	}
+}
+
+// Writes an rgbds project to dir: a source file per bank, main.asm that
+// includes them, and a Makefile that builds game.gb, which is the same as
+// the ROM byte for byte.
+func (p *Program) WriteProject(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	var main strings.Builder
+	for bank := range p.banks {
+		name := fmt.Sprintf("bank_%03X.asm", bank)
+		fmt.Fprintf(&main, "INCLUDE \"%s\"\n", name)
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		if err := p.WriteBank(f, bank); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.asm"), []byte(main.String()), 0o644); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "Makefile"), []byte(p.makefile()), 0o644)
+}
+
+// The header and the checksums are part of the source, so rgbfix only runs
+// if it would leave them as they are.
+func (p *Program) makefile() string {
+	var b strings.Builder
+	b.WriteString("game.gb: main.asm bank_*.asm\n")
+	b.WriteString("\trgbasm -o main.o main.asm\n")
+	b.WriteString("\trgblink -o game.gb main.o\n")
+	if p.checksumsValid() {
+		b.WriteString("\trgbfix -v -p 0 game.gb\n")
+	}
+	return b.String()
+}
+
+func (p *Program) checksumsValid() bool {
+	rom := p.rom
+	if len(rom) < 0x0150 {
+		return false
+	}
+	var header uint8
+	for _, b := range rom[0x0134:0x014D] {
+		header = header - b - 1
+	}
+	var global uint16
+	for i, b := range rom {
+		if i != 0x014E && i != 0x014F {
+			global += uint16(b)
+		}
+	}
+	return header == rom[0x014D] && global == uint16(rom[0x014E])<<8|uint16(rom[0x014F])
+}
@@ -0,0 +1,120 @@
+package disasm
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kvalv/gameboy"
+	"github.com/stretchr/testify/require"
+)
+
+// Four banks on an MBC1. Bank 0 switches to bank 2 and calls into it; the
+// byte after the JR in bank 2 is data.
+func bankedROM() []byte {
+	rom := make([]byte, 4*BANK_SIZE)
+	for i := range rom {
+		rom[i] = 0xFF
+	}
+	copy(rom[0x0100:], []byte{0x00, 0xC3, 0x50, 0x01}) // nop; jp $0150
+	copy(rom[0x0104:], []byte{0xCE, 0xED, 0x66, 0x66}) // start of the logo
+	rom[0x0147] = 0x01
+	copy(rom[0x0150:], []byte{
+		0x3E, 0x02, // ld a, $02
+		0xEA, 0x00, 0x20, // ld [$2000], a
+		0xCD, 0x00, 0x40, // call $4000
+		0x18, 0xF6, // jr $0150
+	})
+	copy(rom[2*BANK_SIZE:], []byte{
+		0x21, 0x00, 0xC0, // ld hl, $C000
+		0x34,       // inc [hl]
+		0x20, 0x01, // jr nz, $4007
+		0xC9,       // ret
+		0xC9,       // ret
+		0x12, 0x34, // data
+	})
+	return rom
+}
+
+func TestAnalyze(t *testing.T) {
+	req := require.New(t)
+	p := Analyze(bankedROM())
+	req.Equal(4, p.Banks())
+
+	for _, loc := range []Location{{0, 0x0100}, {0, 0x0150}, {0, 0x0158}, {2, 0x4000}, {2, 0x4006}, {2, 0x4007}} {
+		_, ok := p.Code(loc)
+		req.True(ok, "%v is code", loc)
+	}
+	for _, loc := range []Location{{0, 0x0104}, {1, 0x4000}, {2, 0x4008}} {
+		_, ok := p.Code(loc)
+		req.False(ok, "%v is data", loc)
+	}
+	req.Equal("Boot", p.Label(Location{0, 0x0100}))
+	req.Equal("Jump_000_0150", p.Label(Location{0, 0x0150}))
+	req.Equal("Call_002_4000", p.Label(Location{2, 0x4000}))
+	req.Equal("Jump_002_4007", p.Label(Location{2, 0x4007}))
+	req.Equal("", p.Label(Location{2, 0x4001}))
+
+	var bank0, bank2 strings.Builder
+	req.NoError(p.WriteBank(&bank0, 0))
+	req.NoError(p.WriteBank(&bank2, 2))
+	req.Contains(bank0.String(), "SECTION \"ROM Bank $000\", ROM0[$0000]\n")
+	req.Contains(bank0.String(), "\nJump_000_0150:\n\tld a, $02\n\tld [$2000], a\n\tcall Call_002_4000\n\tjr Jump_000_0150\n")
+	req.Contains(bank0.String(), "\tdb $CE, $ED, $66, $66\n")
+	req.Contains(bank2.String(), "SECTION \"ROM Bank $002\", ROMX[$4000], BANK[$002]\n")
+	req.Contains(bank2.String(), "\tjr nz, Jump_002_4007\n\tret\n\nJump_002_4007:\n\tret\n\tdb $12, $34\n\tds 16374, $FF\n")
+}
+
+func TestUnknownBank(t *testing.T) {
+	rom := bankedROM()
+	// nop instead of ld a, $02: the bank written is unknown
+	rom[0x0150], rom[0x0151] = 0x00, 0x00
+	p := Analyze(rom)
+	for bank := 1; bank < 4; bank++ {
+		_, ok := p.Code(Location{bank, 0x4000})
+		require.False(t, ok, "bank %d", bank)
+	}
+	var b strings.Builder
+	require.NoError(t, p.WriteBank(&b, 0))
+	require.Contains(t, b.String(), "\tcall $4000\n")
+}
+
+func TestWriteProject(t *testing.T) {
+	req := require.New(t)
+	dir := t.TempDir()
+	req.NoError(Analyze(bankedROM()).WriteProject(dir))
+
+	main, err := os.ReadFile(filepath.Join(dir, "main.asm"))
+	req.NoError(err)
+	req.Equal("INCLUDE \"bank_000.asm\"\nINCLUDE \"bank_001.asm\"\nINCLUDE \"bank_002.asm\"\nINCLUDE \"bank_003.asm\"\n", string(main))
+	for _, name := range []string{"bank_000.asm", "bank_003.asm"} {
+		req.FileExists(filepath.Join(dir, name))
+	}
+	makefile, err := os.ReadFile(filepath.Join(dir, "Makefile"))
+	req.NoError(err)
+	// the checksums are wrong, so rgbfix would change the ROM
+	req.NotContains(string(makefile), "rgbfix")
+	req.Contains(string(makefile), "rgblink -o game.gb main.o")
+}
+
+// Reassembling the source gives back the ROM. The banks refer to each
+// other's labels, so they're assembled together, like main.asm does.
+func TestReassemble(t *testing.T) {
+	req := require.New(t)
+	rom, err := os.ReadFile("../tetris.gb")
+	req.NoError(err)
+	p := Analyze(rom)
+	var src strings.Builder
+	for bank := range p.Banks() {
+		req.NoError(p.WriteBank(&src, bank))
+	}
+	blocks, err := gameboy.Assemble(src.String())
+	req.NoError(err)
+	req.Len(blocks, p.Banks())
+	for bank, b := range blocks {
+		want := rom[bank*BANK_SIZE : (bank+1)*BANK_SIZE]
+		req.True(bytes.Equal(want, b.Data), "bank %d differs", bank)
+	}
+}
@@ -0,0 +1,143 @@
+package gameboy
+
+import (
+	"io"
+	"log/slog"
+	"slices"
+)
+
+type EmulatorOptions struct {
+	// Boot ROM to run, see LoadBootROM. Defaults to the built-in one, which
+	// is a DMG one.
+	BootROM []byte
+	Model   Model
+	// Start at 0x0100 in the state the boot ROM of Model leaves behind,
+	// instead of running the boot ROM. Model is used as given, also without
+	// a BootROM.
+	SkipBoot bool
+	// Receives bytes sent over the link port
+	Serial io.Writer
+	// Record suspicious memory accesses, see Memory.WithStrict
+	Strict bool
+	// Called on suspicious memory accesses, see Memory.WithWarning
+	Warning func(*AccessError)
+	Log     *slog.Logger
+}
+
+// A complete Game Boy: the cpu, and through it the memory, cartridge and
+// peripherals. This is what frontends (the UI, headless tools) should use
+// rather than wiring up a CPU by hand.
+type Emulator struct {
+	CPU *CPU
+
+	rom  []byte
+	opts EmulatorOptions
+
+	frame int
+	// set while a frame is interrupted by RunFrameUntil
+	midFrame bool
+	rewind   *rewindBuffer // nil unless enabled, see WithRewind
+}
+
+func NewEmulator(rom []byte, opts EmulatorOptions) *Emulator {
+	if opts.Log == nil {
+		opts.Log = slog.New(slog.DiscardHandler)
+	}
+	if opts.BootROM == nil {
+		opts.BootROM = BootROM
+		if !opts.SkipBoot {
+			opts.Model = DMG
+		}
+	}
+	e := &Emulator{
+		rom:  slices.Clone(rom),
+		opts: opts,
+	}
+	e.Reset()
+	return e
+}
+
+// Power cycles the machine: the cartridge is reloaded, and all memory and
+// registers are reset. Hooks attached to the cpu are kept, and so is the
+// rewind setting, but not the snapshots.
+func (e *Emulator) Reset() {
+	// Memory.Write can load into the ROM image, so give the cartridge a fresh
+	// copy every time.
+	mem := NewMemory(slices.Clone(e.rom)).
+		WithBootROM(e.opts.BootROM, e.opts.Model).
+		WithSerial(e.opts.Serial).
+		WithWarning(e.opts.Warning)
+	if e.opts.Strict {
+		mem.WithStrict()
+	}
+
+	cpu := &CPU{Mem: mem}
+	if e.CPU != nil {
+		cpu.hooks, cpu.accessHooks = e.CPU.hooks, e.CPU.accessHooks
+		cpu.syms = e.CPU.syms
+	}
+	cpu.WithLog(e.opts.Log)
+	if e.opts.SkipBoot {
+		cpu.SkipBoot(e.opts.Model)
+	}
+	e.CPU = cpu
+
+	e.frame, e.midFrame = 0, false
+	if e.rewind != nil {
+		e.WithRewind(e.rewind.opts)
+	}
+}
+
+// Runs until the PPU enters VBlank, i.e. until a new frame is complete.
+// Returns an error if the cpu stopped.
+func (e *Emulator) RunFrame() error {
+	_, err := e.RunFrameUntil(nil)
+	return err
+}
+
+// Like RunFrame, but checks stop before every instruction, and returns early
+// if it's true. The next call then carries on with the same frame.
+func (e *Emulator) RunFrameUntil(stop func(*CPU) bool) (stopped bool, err error) {
+	if !e.midFrame {
+		if err := e.recordFrame(); err != nil {
+			return false, err
+		}
+	}
+	return e.runFrame(stop)
+}
+
+func (e *Emulator) runFrame(stop func(*CPU) bool) (bool, error) {
+	cpu := e.CPU
+	if !e.midFrame {
+		cpu.ppu.vblank = false
+		e.midFrame = true
+	}
+	for !cpu.ppu.vblank {
+		if stop != nil && stop(cpu) {
+			return true, nil
+		}
+		if !cpu.Step() {
+			return false, cpu.Err()
+		}
+	}
+	e.midFrame = false
+	e.frame++
+	return false, nil
+}
+
+// The last complete frame
+func (e *Emulator) Framebuffer() Frame {
+	return e.CPU.ppu.front
+}
+
+// Returns the audio samples (mono, SAMPLE_RATE) produced since the last call.
+func (e *Emulator) AudioSamples() []int16 {
+	return e.CPU.apu.Samples()
+}
+
+// Sets which buttons are held down from now on.
+func (e *Emulator) SetButtons(b Buttons) {
+	e.CPU.Mem.SetButtons(b)
+}
+
+func (e *Emulator) Memory() *Memory { return e.CPU.Mem }
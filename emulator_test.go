@@ -0,0 +1,103 @@
+package gameboy
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// A program that spins forever
+var loopProgram = []byte{code("JR e8"), 0xFE}
+
+func TestEmulator(t *testing.T) {
+	t.Run("run frame", func(t *testing.T) {
+		req := require.New(t)
+		emu := NewEmulator(testROM(loopProgram), EmulatorOptions{Model: DMG, SkipBoot: true})
+		req.NoError(emu.RunFrame())
+		req.Equal(uint8(SCREEN_HEIGHT), emu.Memory().LY())
+
+		start := emu.CPU.Cycles
+		req.NoError(emu.RunFrame())
+		req.InDelta(CYCLES_PER_FRAME, emu.CPU.Cycles-start, 12)
+		req.Equal(uint8(SCREEN_HEIGHT), emu.Memory().LY())
+		req.NotZero(emu.Memory().Read(ADDR_IF)&INT_VBLANK, "vblank interrupt requested")
+	})
+
+	t.Run("run frame until", func(t *testing.T) {
+		req := require.New(t)
+		emu := NewEmulator(testROM(busyProgram), EmulatorOptions{Model: DMG, SkipBoot: true})
+		stop := func(cpu *CPU) bool { return cpu.Mem.Read(0xC000) == 0x80 }
+		var stopped bool
+		for !stopped {
+			var err error
+			stopped, err = emu.RunFrameUntil(stop)
+			req.NoError(err)
+		}
+		req.Equal(uint8(0x80), emu.Memory().Read(0xC000))
+		req.Equal(uint16(0x0158), emu.CPU.PC, "stops right after the write")
+		frame := emu.Frame()
+
+		// carries on with the same frame
+		req.NoError(emu.RunFrame())
+		req.Equal(frame+1, emu.Frame())
+		req.Equal(uint8(SCREEN_HEIGHT), emu.Memory().LY())
+	})
+
+	t.Run("buttons", func(t *testing.T) {
+		req := require.New(t)
+		emu := NewEmulator(testROM(loopProgram), EmulatorOptions{Model: DMG, SkipBoot: true})
+		mem := emu.Memory()
+		mem.WriteAt(ADDR_IF, 0)
+		emu.SetButtons(ButtonStart | ButtonLeft)
+		req.NotZero(mem.Read(ADDR_IF) & INT_JOYPAD)
+
+		mem.WriteAt(ADDR_P1, 0x10) // action buttons
+		req.Equal(uint8(0xD7), mem.Read(ADDR_P1))
+		mem.WriteAt(ADDR_P1, 0x20) // direction buttons
+		req.Equal(uint8(0xED), mem.Read(ADDR_P1))
+		mem.WriteAt(ADDR_P1, 0x30) // neither
+		req.Equal(uint8(0xFF), mem.Read(ADDR_P1))
+	})
+
+	t.Run("timer", func(t *testing.T) {
+		req := require.New(t)
+		rom := testROM([]byte{
+			code("LD A,n8"), 0x05, // enabled, every 16 cycles
+			code("LDH (a8),A"), 0x07,
+			code("JR e8"), 0xFE,
+		})
+		emu := NewEmulator(rom, EmulatorOptions{Model: DMG, SkipBoot: true})
+		emu.Memory().WriteAt(ADDR_IF, 0)
+		req.NoError(emu.RunFrame())
+		req.NotZero(emu.Memory().Read(ADDR_IF) & INT_TIMER)
+	})
+
+	t.Run("audio", func(t *testing.T) {
+		req := require.New(t)
+		emu := NewEmulator(testROM(loopProgram), EmulatorOptions{Model: DMG, SkipBoot: true})
+		req.NoError(emu.RunFrame())
+		emu.AudioSamples()
+		req.NoError(emu.RunFrame())
+		req.InDelta(SAMPLE_RATE*CYCLES_PER_FRAME/CPU_FREQUENCY, len(emu.AudioSamples()), 1)
+		req.Empty(emu.AudioSamples())
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		req := require.New(t)
+		emu := NewEmulator(testROM(loopProgram), EmulatorOptions{Model: DMG, SkipBoot: true})
+		var calls int
+		emu.CPU.WithHook(func(*CPU, int, Instruction, *slog.Logger) { calls++ })
+		req.NoError(emu.RunFrame())
+		emu.Memory().WriteAt(0xC000, 0x42)
+
+		emu.Reset()
+		req.Zero(emu.CPU.Cycles)
+		req.Equal(uint16(0x0100), emu.CPU.PC)
+		req.Zero(emu.Memory().Read(0xC000))
+
+		calls = 0
+		req.NoError(emu.RunFrame())
+		req.NotZero(calls, "hooks are kept")
+	})
+}
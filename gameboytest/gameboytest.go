@@ -0,0 +1,155 @@
+// Package gameboytest compares what the emulator draws against golden images,
+// to catch rendering regressions:
+//
+//	frame := gameboytest.RunUntilFrame(t, rom, 200)
+//	gameboytest.AssertFrameMatches(t, frame, "testdata/logo.png")
+//
+// Run the tests with -update to (re)write the golden images from the current
+// frames. When a frame doesn't match, a diff image is written next to the
+// golden one, with the mismatched pixels in red.
+package gameboytest
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kvalv/gameboy"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden images")
+
+// Mismatched pixels in the diff image
+var DIFF_COLOR = color.RGBA{0xFF, 0x00, 0x00, 0xFF}
+
+// Powers on the ROM, boot ROM included, and returns the framebuffer after n
+// frames. Fails the test if the emulator stops or panics before that.
+func RunUntilFrame(t testing.TB, rom []byte, n int) gameboy.Frame {
+	t.Helper()
+	return RunUntilFrameWith(t, rom, n, gameboy.EmulatorOptions{})
+}
+
+// Like RunUntilFrame, with options, e.g. to skip the boot ROM.
+func RunUntilFrameWith(t testing.TB, rom []byte, n int, opts gameboy.EmulatorOptions) gameboy.Frame {
+	t.Helper()
+	emu := gameboy.NewEmulator(rom, opts)
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("frame %d: panic at %#04x: %v", emu.Frame(), emu.CPU.PC, r)
+		}
+	}()
+	for emu.Frame() < n {
+		if err := emu.RunFrame(); err != nil {
+			t.Fatalf("frame %d: %v", emu.Frame(), err)
+		}
+	}
+	return emu.Framebuffer()
+}
+
+// Compares the frame to the golden PNG at path, in gameboy.PaletteGray. With
+// -update, the golden image is written instead.
+func AssertFrameMatches(t testing.TB, frame gameboy.Frame, path string) {
+	t.Helper()
+	if *update {
+		if err := writeGolden(frame, path); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("updated %s", path)
+		return
+	}
+
+	golden, err := readGolden(path)
+	if os.IsNotExist(err) {
+		t.Fatalf("%s doesn't exist; run the test with -update to create it", path)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff, n := compare(golden, frame.Image(gameboy.PaletteGray))
+	diffPath := DiffPath(path)
+	if n == 0 {
+		os.Remove(diffPath) // left over from an earlier failure
+		return
+	}
+	if err := writePNG(diffPath, diff); err != nil {
+		t.Errorf("writing diff: %v", err)
+	}
+	t.Errorf("frame doesn't match %s: %d pixels differ, see %s", path, n, diffPath)
+}
+
+// Where the diff image of the golden image at path goes, e.g.
+// testdata/logo.diff.png for testdata/logo.png.
+func DiffPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".diff.png"
+}
+
+// Returns an image of got with the pixels that differ from want in DIFF_COLOR,
+// and the rest faded so they stand out, along with the number of pixels that
+// differ. Pixels outside either image count as different.
+func compare(want, got image.Image) (*image.RGBA, int) {
+	bounds := want.Bounds().Union(got.Bounds())
+	diff := image.NewRGBA(bounds)
+	n := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			p := image.Pt(x, y)
+			w := color.RGBAModel.Convert(want.At(x, y)).(color.RGBA)
+			g := color.RGBAModel.Convert(got.At(x, y)).(color.RGBA)
+			if w != g || !p.In(want.Bounds()) || !p.In(got.Bounds()) {
+				diff.SetRGBA(x, y, DIFF_COLOR)
+				n++
+				continue
+			}
+			diff.SetRGBA(x, y, fade(g))
+		}
+	}
+	return diff, n
+}
+
+// Blends c halfway towards white.
+func fade(c color.RGBA) color.RGBA {
+	return color.RGBA{
+		R: uint8((int(c.R) + 0xFF) / 2),
+		G: uint8((int(c.G) + 0xFF) / 2),
+		B: uint8((int(c.B) + 0xFF) / 2),
+		A: 0xFF,
+	}
+}
+
+func readGolden(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return img, nil
+}
+
+func writeGolden(frame gameboy.Frame, path string) error {
+	return writePNG(path, frame.Image(gameboy.PaletteGray))
+}
+
+func writePNG(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,93 @@
+package gameboytest
+
+import (
+	"fmt"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kvalv/gameboy"
+	"github.com/stretchr/testify/require"
+)
+
+// A cartridge that only has the logo in its header, and loops forever once
+// the boot ROM hands over, so the logo stays on screen.
+func logoROM() []byte {
+	rom := make([]byte, 0x8000)
+	copy(rom[0x0100:], []byte{0x18, 0xFE}) // JR -2
+	copy(rom[0x0104:], gameboy.BootLogo)
+	var sum uint8
+	for _, b := range rom[0x0134:0x014D] {
+		sum = sum - b - 1
+	}
+	rom[0x014D] = sum
+	return rom
+}
+
+func TestBootLogo(t *testing.T) {
+	for _, n := range []int{60, 200} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			frame := RunUntilFrame(t, logoROM(), n)
+			AssertFrameMatches(t, frame, fmt.Sprintf("testdata/logo-%d.png", n))
+		})
+	}
+}
+
+func TestTetrisLogo(t *testing.T) {
+	rom, err := os.ReadFile("../tetris.gb")
+	require.NoError(t, err)
+	// tetris carries the same logo, so it looks the same while booting
+	AssertFrameMatches(t, RunUntilFrame(t, rom, 200), "testdata/logo-200.png")
+}
+
+// Catches what AssertFrameMatches reports
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertFrameMatches(t *testing.T) {
+	if *update {
+		t.Skip("nothing to compare with -update")
+	}
+	req := require.New(t)
+	path := filepath.Join(t.TempDir(), "golden.png")
+	var frame gameboy.Frame
+	req.NoError(writeGolden(frame, path))
+
+	rec := &recorder{TB: t}
+	AssertFrameMatches(rec, frame, path)
+	req.Empty(rec.errors)
+	req.NoFileExists(DiffPath(path))
+
+	frame[5*gameboy.SCREEN_WIDTH+3] = 3
+	frame[7*gameboy.SCREEN_WIDTH+9] = 1
+	AssertFrameMatches(rec, frame, path)
+	req.Len(rec.errors, 1)
+	req.Contains(rec.errors[0], "2 pixels differ")
+
+	diff, err := readGolden(DiffPath(path))
+	req.NoError(err)
+	red := color.RGBAModel.Convert(DIFF_COLOR)
+	req.Equal(red, color.RGBAModel.Convert(diff.At(3, 5)))
+	req.Equal(red, color.RGBAModel.Convert(diff.At(9, 7)))
+	req.NotEqual(red, color.RGBAModel.Convert(diff.At(0, 0)))
+
+	// matching again cleans up the diff
+	frame[5*gameboy.SCREEN_WIDTH+3] = 0
+	frame[7*gameboy.SCREEN_WIDTH+9] = 0
+	AssertFrameMatches(rec, frame, path)
+	req.Len(rec.errors, 1)
+	req.NoFileExists(DiffPath(path))
+}
+
+func TestDiffPath(t *testing.T) {
+	require.Equal(t, "testdata/logo.diff.png", DiffPath("testdata/logo.png"))
+}
@@ -0,0 +1,167 @@
+package gdb
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Sent by the client to stop a running target, outside of any packet
+const INTERRUPT = 0x03
+
+// A client connection. Packets are framed as $data#xx, where xx is the sum of
+// the data bytes modulo 256 in hex. The receiver acks each packet with +, or
+// asks for it again with -, until acks are switched off by QStartNoAckMode.
+// https://sourceware.org/gdb/current/onlinedocs/gdb.html/Overview.html
+type conn struct {
+	w io.Writer
+	// bytes from the client, closed when it hangs up
+	in   chan byte
+	done chan struct{}
+
+	noAck bool
+	// the last packet sent, sent again if the client asks with -
+	last string
+}
+
+func newConn(rw io.ReadWriter) *conn {
+	c := &conn{w: rw, in: make(chan byte, 64), done: make(chan struct{})}
+	go func() {
+		defer close(c.in)
+		r := bufio.NewReader(rw)
+		for {
+			b, err := r.ReadByte()
+			if err != nil {
+				return
+			}
+			select {
+			case c.in <- b:
+			case <-c.done:
+				return
+			}
+		}
+	}()
+	return c
+}
+
+// Stops reading from the client; the connection itself is closed by its owner.
+func (c *conn) close() { close(c.done) }
+
+// Waits for the next packet, and returns its data. Acks and anything else
+// outside a packet are skipped. Returns io.EOF when the client hangs up.
+func (c *conn) readPacket() (string, error) {
+	for {
+		b, ok := <-c.in
+		if !ok {
+			return "", io.EOF
+		}
+		switch b {
+		case '$':
+		case '-':
+			if err := c.send(c.last); err != nil {
+				return "", err
+			}
+			continue
+		default:
+			continue
+		}
+
+		var data []byte
+		for b = range c.in {
+			if b == '#' {
+				break
+			}
+			data = append(data, b)
+		}
+		sum := make([]byte, 0, 2)
+		for len(sum) < 2 {
+			if b, ok = <-c.in; !ok {
+				return "", io.EOF
+			}
+			sum = append(sum, b)
+		}
+
+		if want, err := strconv.ParseUint(string(sum), 16, 8); err != nil || uint8(want) != checksum(data) {
+			if !c.noAck {
+				if _, err := c.w.Write([]byte{'-'}); err != nil {
+					return "", err
+				}
+			}
+			continue
+		}
+		if !c.noAck {
+			if _, err := c.w.Write([]byte{'+'}); err != nil {
+				return "", err
+			}
+		}
+		return unescape(data), nil
+	}
+}
+
+// Whether the client sent an interrupt (or hung up) since the last check.
+// Doesn't wait.
+func (c *conn) interrupted() bool {
+	for {
+		select {
+		case b, ok := <-c.in:
+			if !ok || b == INTERRUPT {
+				return true
+			}
+		default:
+			return false
+		}
+	}
+}
+
+func (c *conn) writePacket(data string) error {
+	c.last = data
+	return c.send(data)
+}
+
+func (c *conn) send(data string) error {
+	escaped := escape(data)
+	_, err := fmt.Fprintf(c.w, "$%s#%02x", escaped, checksum([]byte(escaped)))
+	return err
+}
+
+func checksum(data []byte) uint8 {
+	var sum uint8
+	for _, b := range data {
+		sum += b
+	}
+	return sum
+}
+
+// Characters that have a meaning in the framing are sent as } followed by
+// the character xor 0x20.
+func escape(s string) string {
+	if !strings.ContainsAny(s, "#$}*") {
+		return s
+	}
+	var b strings.Builder
+	for i := range len(s) {
+		switch s[i] {
+		case '#', '$', '}', '*':
+			b.WriteByte('}')
+			b.WriteByte(s[i] ^ 0x20)
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+func unescape(data []byte) string {
+	var b strings.Builder
+	for i := 0; i < len(data); i++ {
+		if data[i] == '}' && i+1 < len(data) {
+			i++
+			b.WriteByte(data[i] ^ 0x20)
+			continue
+		}
+		b.WriteByte(data[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,432 @@
+// Package gdb is a stub for the GDB remote serial protocol (RSP), so that
+// GDB, or any other client of the protocol, can debug a game running in the
+// emulator:
+//
+//	gbgdb -file game.gb -listen localhost:1234
+//	gdb -ex 'target remote localhost:1234'
+//
+// The registers are af, bc, de, hl, sp and pc, 16 bits each, as described by
+// the target.xml sent to the client. Addresses are 16 bits; breakpoints and
+// watchpoints apply to all ROM banks.
+// https://sourceware.org/gdb/current/onlinedocs/gdb.html/Remote-Protocol.html
+package gdb
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/kvalv/gameboy"
+)
+
+// Largest packet we accept, also the limit for memory reads
+const PACKET_SIZE = 0x1000
+
+const TARGET_XML = `<?xml version="1.0"?>
+<!DOCTYPE target SYSTEM "gdb-target.dtd">
+<target version="1.0">
+  <feature name="org.gnu.gdb.sm83.core">
+    <reg name="af" bitsize="16" type="int" regnum="0"/>
+    <reg name="bc" bitsize="16" type="int"/>
+    <reg name="de" bitsize="16" type="int"/>
+    <reg name="hl" bitsize="16" type="int"/>
+    <reg name="sp" bitsize="16" type="data_ptr"/>
+    <reg name="pc" bitsize="16" type="code_ptr"/>
+  </feature>
+</target>
+`
+
+// Signals in stop replies
+const (
+	SIGINT  = 2 // interrupted by the client
+	SIGILL  = 4 // the cpu stopped with an error
+	SIGTRAP = 5 // breakpoint, watchpoint or step
+)
+
+var errDetach = errors.New("client detached")
+
+// Serves one client at a time; it owns the emulator while serving.
+type Server struct {
+	emu *gameboy.Emulator
+	log *slog.Logger
+
+	// the client's debugger, and the ids of the breakpoints and watchpoints
+	// it set with Z, by the packet's arguments; both last a session
+	dbg    *gameboy.Debugger
+	points map[string]int
+	// the reply to ?, i.e. why the target last stopped
+	stopped string
+}
+
+func NewServer(emu *gameboy.Emulator) *Server {
+	return &Server{
+		emu:     emu,
+		log:     slog.New(slog.DiscardHandler),
+		stopped: fmt.Sprintf("S%02x", SIGTRAP),
+	}
+}
+
+// Logs the packets at debug level.
+func (s *Server) WithLog(log *slog.Logger) *Server {
+	s.log = log
+	return s
+}
+
+func ListenAndServe(addr string, emu *gameboy.Emulator) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	return NewServer(emu).Serve(l)
+}
+
+// Accepts clients one after another, until the listener fails or is closed.
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		s.log.Info("client connected", "addr", c.RemoteAddr())
+		if err := s.ServeConn(c); err != nil {
+			s.log.Warn("client failed", "err", err)
+		}
+		c.Close()
+	}
+}
+
+// Handles a single client until it detaches or hangs up. Its breakpoints and
+// watchpoints are removed when it's gone.
+func (s *Server) ServeConn(rw io.ReadWriter) error {
+	s.dbg, s.points = gameboy.NewDebugger(s.emu), make(map[string]int)
+	defer s.dbg.Detach()
+	c := newConn(rw)
+	defer c.close()
+	for {
+		pkt, err := c.readPacket()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		s.log.Debug("packet", "data", pkt)
+
+		reply, err := s.handle(c, pkt)
+		if errors.Is(err, errDetach) {
+			if reply != "" {
+				return c.writePacket(reply)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		s.log.Debug("reply", "data", reply)
+		if err := c.writePacket(reply); err != nil {
+			return err
+		}
+		if pkt == "QStartNoAckMode" {
+			c.noAck = true
+		}
+	}
+}
+
+// Returns the reply to a packet; "" for packets that aren't supported.
+func (s *Server) handle(c *conn, pkt string) (string, error) {
+	if pkt == "" {
+		return "", nil
+	}
+	cmd, args := pkt[0], pkt[1:]
+	switch cmd {
+	case '?':
+		return s.stopped, nil
+	case 'g':
+		return s.readRegisters(), nil
+	case 'G':
+		return s.writeRegisters(args), nil
+	case 'p':
+		return s.readRegister(args), nil
+	case 'P':
+		return s.writeRegister(args), nil
+	case 'm':
+		return s.readMemory(args), nil
+	case 'M':
+		return s.writeMemory(args), nil
+	case 'Z':
+		return s.insertPoint(args), nil
+	case 'z':
+		return s.removePoint(args), nil
+	case 'c', 's':
+		if args != "" {
+			addr, err := strconv.ParseUint(args, 16, 16)
+			if err != nil {
+				return "E01", nil
+			}
+			s.emu.CPU.PC = uint16(addr)
+		}
+		return s.resume(c, cmd == 's'), nil
+	case 'H', 'T': // there's a single thread
+		return "OK", nil
+	case 'D':
+		return "OK", errDetach
+	case 'k':
+		s.emu.Reset()
+		return "", errDetach
+	}
+
+	switch {
+	case pkt == "vCont?":
+		return "vCont;c;s", nil
+	case strings.HasPrefix(pkt, "vCont;"):
+		// the first action applies to our only thread
+		action, _, _ := strings.Cut(strings.TrimPrefix(pkt, "vCont;"), ";")
+		action, _, _ = strings.Cut(action, ":")
+		switch action {
+		case "c", "s":
+			return s.resume(c, action == "s"), nil
+		}
+		return "E01", nil
+	case strings.HasPrefix(pkt, "vKill"):
+		s.emu.Reset()
+		return "OK", errDetach
+	case strings.HasPrefix(pkt, "qSupported"):
+		return fmt.Sprintf("PacketSize=%x;QStartNoAckMode+;qXfer:features:read+;swbreak+;hwbreak+;vContSupported+", PACKET_SIZE), nil
+	case pkt == "QStartNoAckMode":
+		return "OK", nil
+	case strings.HasPrefix(pkt, "qXfer:features:read:target.xml:"):
+		return readXfer(TARGET_XML, strings.TrimPrefix(pkt, "qXfer:features:read:target.xml:")), nil
+	case pkt == "qAttached":
+		return "1", nil
+	case pkt == "qC":
+		return "QC1", nil
+	case pkt == "qfThreadInfo":
+		return "m1", nil
+	case pkt == "qsThreadInfo":
+		return "l", nil
+	}
+	return "", nil
+}
+
+// Runs until the debugger stops, or the client interrupts.
+func (s *Server) resume(c *conn, step bool) string {
+	var stop gameboy.Stop
+	if step {
+		stop = s.dbg.StepInto()
+	} else {
+		stop = s.dbg.Continue()
+	}
+	for stop.Reason == gameboy.StopFrame {
+		if c.interrupted() {
+			s.stopped = fmt.Sprintf("S%02x", SIGINT)
+			return s.stopped
+		}
+		stop = s.dbg.Resume()
+	}
+	s.stopped = stopReply(stop)
+	return s.stopped
+}
+
+func stopReply(stop gameboy.Stop) string {
+	switch stop.Reason {
+	case gameboy.StopBreakpoint:
+		return fmt.Sprintf("T%02xswbreak:;", SIGTRAP)
+	case gameboy.StopWatchpoint:
+		kind := "awatch"
+		switch stop.Watchpoint.Kind {
+		case gameboy.WatchWrite:
+			kind = "watch"
+		case gameboy.WatchRead:
+			kind = "rwatch"
+		}
+		return fmt.Sprintf("T%02x%s:%04x;", SIGTRAP, kind, stop.Access.Addr)
+	case gameboy.StopError:
+		return fmt.Sprintf("S%02x", SIGILL)
+	}
+	return fmt.Sprintf("S%02x", SIGTRAP)
+}
+
+// The registers in the order of TARGET_XML
+func (s *Server) registers() []*uint16 {
+	cpu := s.emu.CPU
+	af, bc, de, hl := cpu.AF(), cpu.BC(), cpu.DE(), cpu.HL()
+	return []*uint16{&af, &bc, &de, &hl, &cpu.SP, &cpu.PC}
+}
+
+// Copies af, bc, de and hl back into the 8 bit registers
+func (s *Server) setRegisters(regs []*uint16) {
+	cpu := s.emu.CPU
+	// the low nibble of F is always 0
+	cpu.A, cpu.F = uint8(*regs[0]>>8), gameboy.FlagRegister(*regs[0]&0xF0)
+	cpu.B, cpu.C = uint8(*regs[1]>>8), uint8(*regs[1])
+	cpu.D, cpu.E = uint8(*regs[2]>>8), uint8(*regs[2])
+	cpu.H, cpu.L = uint8(*regs[3]>>8), uint8(*regs[3])
+}
+
+func (s *Server) readRegisters() string {
+	var b strings.Builder
+	for _, r := range s.registers() {
+		b.WriteString(encodeU16(*r))
+	}
+	return b.String()
+}
+
+func (s *Server) writeRegisters(args string) string {
+	regs := s.registers()
+	if len(args) != 4*len(regs) {
+		return "E01"
+	}
+	for i, r := range regs {
+		v, ok := decodeU16(args[4*i : 4*i+4])
+		if !ok {
+			return "E01"
+		}
+		*r = v
+	}
+	s.setRegisters(regs)
+	return "OK"
+}
+
+func (s *Server) readRegister(args string) string {
+	regs := s.registers()
+	n, err := strconv.ParseUint(args, 16, 8)
+	if err != nil || int(n) >= len(regs) {
+		return "E01"
+	}
+	return encodeU16(*regs[n])
+}
+
+func (s *Server) writeRegister(args string) string {
+	regs := s.registers()
+	num, value, _ := strings.Cut(args, "=")
+	n, err := strconv.ParseUint(num, 16, 8)
+	if err != nil || int(n) >= len(regs) {
+		return "E01"
+	}
+	v, ok := decodeU16(value)
+	if !ok {
+		return "E01"
+	}
+	*regs[n] = v
+	s.setRegisters(regs)
+	return "OK"
+}
+
+// m addr,length
+func (s *Server) readMemory(args string) string {
+	addr, n, ok := parseRange(args)
+	if !ok || 2*n > PACKET_SIZE {
+		return "E01"
+	}
+	mem := s.emu.Memory()
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = mem.Peek(addr + uint16(i))
+	}
+	return hex.EncodeToString(data)
+}
+
+// M addr,length:XX...
+func (s *Server) writeMemory(args string) string {
+	where, values, _ := strings.Cut(args, ":")
+	addr, n, ok := parseRange(where)
+	data, err := hex.DecodeString(values)
+	if !ok || err != nil || len(data) != n {
+		return "E01"
+	}
+	mem := s.emu.Memory()
+	for i, b := range data {
+		mem.WriteAt(addr+uint16(i), b)
+	}
+	return "OK"
+}
+
+// Z type,addr,kind: software (0) and hardware (1) breakpoints, and write (2),
+// read (3) and access (4) watchpoints, where kind is the number of bytes
+// watched.
+func (s *Server) insertPoint(args string) string {
+	args, _, _ = strings.Cut(args, ";") // conditions and commands aren't supported
+	if _, ok := s.points[args]; ok {
+		return "OK"
+	}
+	typ, where, _ := strings.Cut(args, ",")
+	addr, n, ok := parseRange(where)
+	if !ok {
+		return "E01"
+	}
+	end := addr + uint16(max(n, 1)) - 1
+
+	var id int
+	switch typ {
+	case "0", "1":
+		id = s.dbg.AddBreakpoint(addr).ID
+	case "2":
+		id = s.dbg.AddWatchpoint(addr, end, gameboy.WatchWrite).ID
+	case "3":
+		id = s.dbg.AddWatchpoint(addr, end, gameboy.WatchRead).ID
+	case "4":
+		id = s.dbg.AddWatchpoint(addr, end, gameboy.WatchRead|gameboy.WatchWrite).ID
+	default:
+		return ""
+	}
+	s.points[args] = id
+	return "OK"
+}
+
+func (s *Server) removePoint(args string) string {
+	args, _, _ = strings.Cut(args, ";")
+	if id, ok := s.points[args]; ok {
+		s.dbg.Remove(id)
+		delete(s.points, args)
+	}
+	return "OK"
+}
+
+// Replies to qXfer:object:read:annex:offset,length with a part of data
+func readXfer(data, args string) string {
+	off, n, ok := parseRange(args)
+	if !ok || int(off) > len(data) {
+		return "E01"
+	}
+	rest := data[off:]
+	if len(rest) <= n {
+		return "l" + rest
+	}
+	return "m" + rest[:n]
+}
+
+// Parses addr,length in hex, where the range is within the address space
+func parseRange(s string) (uint16, int, bool) {
+	a, n, ok := strings.Cut(s, ",")
+	if !ok {
+		return 0, 0, false
+	}
+	addr, err := strconv.ParseUint(a, 16, 16)
+	if err != nil {
+		return 0, 0, false
+	}
+	length, err := strconv.ParseUint(n, 16, 32)
+	if err != nil || addr+length > 0x10000 {
+		return 0, 0, false
+	}
+	return uint16(addr), int(length), true
+}
+
+// Registers go over the wire in target byte order, i.e. little endian
+func encodeU16(v uint16) string {
+	return fmt.Sprintf("%02x%02x", uint8(v), uint8(v>>8))
+}
+
+func decodeU16(s string) (uint16, bool) {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 2 {
+		return 0, false
+	}
+	return uint16(b[0]) | uint16(b[1])<<8, true
+}
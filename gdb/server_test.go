@@ -0,0 +1,218 @@
+package gdb
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/kvalv/gameboy"
+	"github.com/stretchr/testify/require"
+)
+
+// Counts in A, storing it at $C000 from a function
+var program = gameboy.MustAssemble(`
+SECTION "entry", ROM0[$0100]
+	nop
+	jp Main
+SECTION "main", ROM0[$0150]
+Main:
+	inc a            ; $0150
+	call Store       ; $0151
+	jr Main          ; $0154
+Store:
+	ld [$C000], a    ; $0156
+	ret              ; $0159
+`)
+
+// A fake client, talking to a server over a pipe
+type client struct {
+	t     *testing.T
+	conn  net.Conn
+	r     *bufio.Reader
+	noAck bool
+	done  chan error
+}
+
+func newClient(t *testing.T) (*client, *gameboy.Emulator) {
+	emu := newEmulator()
+	return connect(t, NewServer(emu)), emu
+}
+
+func newEmulator() *gameboy.Emulator {
+	rom := make([]byte, 32*1024)
+	for _, b := range program {
+		copy(rom[b.Offset:], b.Data)
+	}
+	return gameboy.NewEmulator(rom, gameboy.EmulatorOptions{Model: gameboy.DMG, SkipBoot: true})
+}
+
+// Starts a session of srv
+func connect(t *testing.T, srv *Server) *client {
+	server, conn := net.Pipe()
+	c := &client{t: t, conn: conn, r: bufio.NewReader(conn), done: make(chan error, 1)}
+	go func() {
+		c.done <- srv.ServeConn(server)
+		server.Close()
+	}()
+	t.Cleanup(func() { conn.Close() })
+	return c
+}
+
+func (c *client) write(pkt string) {
+	_, err := fmt.Fprintf(c.conn, "$%s#%02x", pkt, checksum([]byte(pkt)))
+	require.NoError(c.t, err)
+	if !c.noAck {
+		ack, err := c.r.ReadByte()
+		require.NoError(c.t, err)
+		require.Equal(c.t, byte('+'), ack, "ack of %q", pkt)
+	}
+}
+
+func (c *client) read() string {
+	req := require.New(c.t)
+	start, err := c.r.ReadByte()
+	req.NoError(err)
+	req.Equal(byte('$'), start)
+	data, err := c.r.ReadString('#')
+	req.NoError(err)
+	data = strings.TrimSuffix(data, "#")
+	sum := make([]byte, 2)
+	_, err = c.r.Read(sum[:1])
+	req.NoError(err)
+	_, err = c.r.Read(sum[1:])
+	req.NoError(err)
+	req.Equal(fmt.Sprintf("%02x", checksum([]byte(data))), string(sum))
+	if !c.noAck {
+		_, err = c.conn.Write([]byte{'+'})
+		req.NoError(err)
+	}
+	return unescape([]byte(data))
+}
+
+// Sends a packet and returns the reply
+func (c *client) send(pkt string) string {
+	c.write(pkt)
+	return c.read()
+}
+
+func TestHandshake(t *testing.T) {
+	req := require.New(t)
+	c, _ := newClient(t)
+	req.Contains(c.send("qSupported:multiprocess+;swbreak+"), "QStartNoAckMode+")
+	req.Equal("OK", c.send("QStartNoAckMode"))
+	c.noAck = true
+	req.Equal("", c.send("vMustReplyEmpty"))
+	req.Equal("S05", c.send("?"))
+	req.Equal("1", c.send("qAttached"))
+
+	xml := c.send("qXfer:features:read:target.xml:0,20")
+	req.Equal("m"+TARGET_XML[:0x20], xml)
+	xml = c.send("qXfer:features:read:target.xml:20,1000")
+	req.Equal("l"+TARGET_XML[0x20:], xml)
+
+	req.Equal("OK", c.send("D"))
+	req.NoError(<-c.done)
+}
+
+func TestRegisters(t *testing.T) {
+	req := require.New(t)
+	c, emu := newClient(t)
+	// the state the DMG boot ROM leaves behind; only Z is set, as the header
+	// checksum is 0
+	req.Equal("80011300d8004d01feff0001", c.send("g"))
+	req.Equal("0001", c.send("p5"))
+	req.Equal("E01", c.send("p6"))
+
+	req.Equal("OK", c.send("P0=ff12"))
+	req.Equal(uint8(0x12), emu.CPU.A)
+	req.Equal("f0", fmt.Sprintf("%02x", uint8(emu.CPU.F)), "the low nibble of F is 0")
+	req.Equal("OK", c.send("G"+"0000"+"3412"+"0000"+"0000"+"00d0"+"5001"))
+	req.Equal(uint16(0x1234), emu.CPU.BC())
+	req.Equal(uint16(0xD000), emu.CPU.SP)
+	req.Equal(uint16(0x0150), emu.CPU.PC)
+}
+
+func TestMemory(t *testing.T) {
+	req := require.New(t)
+	c, emu := newClient(t)
+	req.Equal("00c35001", c.send("m100,4"))
+	req.Equal("OK", c.send("Mc000,2:abcd"))
+	req.Equal(uint8(0xCD), emu.Memory().Read(0xC001))
+	req.Equal("abcd", c.send("mc000,2"))
+	req.Equal("E01", c.send("mfffe,4"))
+	req.Equal("E01", c.send("Mc000,2:ab"))
+
+	emu.Memory().WithStrict()
+	req.Equal("abcd", c.send("me000,2"), "echo RAM")
+	req.Empty(emu.Memory().Diagnostics(), "the game didn't read it")
+}
+
+func TestRun(t *testing.T) {
+	t.Run("breakpoint", func(t *testing.T) {
+		req := require.New(t)
+		c, emu := newClient(t)
+		req.Equal("OK", c.send("Z0,156,1"))
+		req.Equal("T05swbreak:;", c.send("c"))
+		req.Equal("5601", c.send("p5"))
+		req.Equal(uint8(0x02), emu.CPU.A, "A starts at 1")
+
+		req.Equal("T05swbreak:;", c.send("vCont;c:1"))
+		req.Equal(uint8(0x03), emu.CPU.A)
+
+		req.Equal("OK", c.send("z0,156,1"))
+		req.Equal("S05", c.send("s"))
+		req.Equal("5901", c.send("p5"))
+		req.Equal("S05", c.send("vCont;s:1"))
+		req.Equal("5401", c.send("p5"))
+	})
+
+	t.Run("watchpoint", func(t *testing.T) {
+		req := require.New(t)
+		c, _ := newClient(t)
+		req.Equal("OK", c.send("Z2,c000,1"))
+		req.Equal("T05watch:c000;", c.send("c"))
+		req.Equal("5901", c.send("p5"), "stops after the write")
+		req.Equal("02", c.send("mc000,1"))
+		req.Equal("T05watch:c000;", c.send("?"))
+	})
+
+	t.Run("interrupt", func(t *testing.T) {
+		req := require.New(t)
+		c, _ := newClient(t)
+		c.write("c")
+		_, err := c.conn.Write([]byte{INTERRUPT})
+		req.NoError(err)
+		req.Equal("S02", c.read())
+	})
+}
+
+func TestSessions(t *testing.T) {
+	req := require.New(t)
+	emu := newEmulator()
+	srv := NewServer(emu)
+
+	c := connect(t, srv)
+	req.Equal("OK", c.send("Z0,156,1"))
+	req.Equal("OK", c.send("Z2,c000,1"))
+	req.Equal("OK", c.send("D"))
+	req.NoError(<-c.done)
+
+	c = connect(t, srv)
+	req.Equal("OK", c.send("Z0,150,1"))
+	c.conn.Close()
+	req.NoError(<-c.done, "hung up")
+
+	c = connect(t, srv)
+	req.Equal("OK", c.send("Z0,154,1"))
+	req.Equal("T05swbreak:;", c.send("c"))
+	req.Equal("5401", c.send("p5"), "only the breakpoint of this session")
+	req.Equal(uint8(0x02), emu.Memory().Read(0xC000))
+}
+
+func TestEscape(t *testing.T) {
+	s := "a#b$c}d*e"
+	require.Equal(t, s, unescape([]byte(escape(s))))
+	require.Equal(t, "a}\x03b", escape("a#b"))
+}
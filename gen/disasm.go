@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"text/template"
+)
+
+// Writes the opcode tables of the disasm package, sorted by code so the file
+// only changes when Opcodes.json does.
+func generateDisasm(file string) error {
+	main, ext, err := loadOpcodes("gen/Opcodes.json")
+	if err != nil {
+		return err
+	}
+	byCode := func(a, b Opcode) int { return a.Code - b.Code }
+	slices.SortFunc(main, byCode)
+	slices.SortFunc(ext, byCode)
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	data := map[string][]Opcode{
+		"Main": main,
+		"Ext":  ext,
+	}
+	if err := disasmTmpl.Execute(f, data); err != nil {
+		return fmt.Errorf("failed to generate code: %w", err)
+	}
+	return nil
+}
+
+var disasmTmpl = template.Must(template.New("disasm").Parse(`// Code generated by go run ./gen; DO NOT EDIT.
+
+package disasm
+
+var unprefixed = [256]opcode{
+	{{ range .Main -}}
+	{{ printf "%#02x" .Code }}: {{ template "opcode" . }},
+	{{ end }}
+}
+
+var cbprefixed = [256]opcode{
+	{{ range .Ext -}}
+	{{ printf "%#02x" .Code }}: {{ template "opcode" . }},
+	{{ end }}
+}
+
+{{- define "opcode" -}}
+{mnemonic: "{{ .Mnemonic }}", bytes: {{ .Bytes }}, operands: []operand{
+	{{- range $i, $op := .Operands }}{{ if $i }}, {{ end }}{name: "{{ $op.Name }}"
+		{{- if not $op.Immediate }}, indirect: true{{ end }}
+		{{- if $op.Increment }}, increment: true{{ end }}
+		{{- if $op.Decrement }}, decrement: true{{ end }}}
+	{{- end -}}
+}}
+{{- end -}}
+`))
@@ -0,0 +1,17 @@
+package main
+
+import "text/template"
+
+var templHalt = template.Must(tmpl.New("halt").
+	Funcs(template.FuncMap{}).
+	Parse(`
+// the cpu idles until an interrupt is pending, see CPU.Step
+cpu.halted = true
+cpu.Cycles += 4
+`))
+
+type templDataHalt struct{}
+
+func (o Opcode) DataHalt() templDataHalt {
+	return templDataHalt{}
+}
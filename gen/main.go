@@ -10,15 +10,22 @@ import (
 
 func main() {
 	const DEST = "instructions.go"
+	const DISASM_DEST = "disasm/opcodes.go"
 
 	if err := generateInstructions(DEST); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-	if err := formatFile(DEST); err != nil {
-		fmt.Fprintf(os.Stderr, "failed to format %s: %v\n", DEST, err)
+	if err := generateDisasm(DISASM_DEST); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
-		return
+	}
+	for _, file := range []string{DEST, DISASM_DEST} {
+		if err := formatFile(file); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to format %s: %v\n", file, err)
+			os.Exit(1)
+			return
+		}
 	}
 
 	fmt.Printf("Code generated\n")
@@ -58,7 +65,10 @@ func generateInstructions(file string) error {
 }
 
 var tmpl = template.Must(template.New("main").Parse(`package gameboy
-import "fmt"
+import (
+	"fmt"
+	"os"
+)
 type Instruction interface {
 	Exec(cpu *CPU)
 	Code() uint8
@@ -135,6 +145,8 @@ func ({{.ID}}) Exec(cpu *CPU) {
 		{{ template "rlca" .DataRlca -}}
 	{{- else if eq "NOP" .Mnemonic -}}
 		{{ template "nop" .DataNop -}}
+	{{- else if eq "HALT" .Mnemonic -}}
+		{{ template "halt" .DataHalt -}}
 	{{/* CB-prefixed stuff */}}
 	{{- else if eq "BIT" .Mnemonic -}}
 		{{ template "bit" .DataBit -}}
@@ -147,7 +159,8 @@ func ({{.ID}}) Exec(cpu *CPU) {
 	{{- else if eq "RRC" .Mnemonic -}}
 		{{ template "rrc" .DataRrc -}}
 	{{else}}
-		fmt.Println("TODO: {{.ID}}")
+		{{/* stdout may be a protocol stream, e.g. gbdap's */ -}}
+		fmt.Fprintln(os.Stderr, "TODO: {{.ID}}")
 		// panic("TODO {{.ID}}")
 	{{end -}}
 }
@@ -0,0 +1,94 @@
+package gameboy
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The colors of the four shades, lightest first, see Frame.
+type Palette [4]color.RGBA
+
+var (
+	PaletteGray = Palette{
+		{0xFF, 0xFF, 0xFF, 0xFF},
+		{0xAA, 0xAA, 0xAA, 0xFF},
+		{0x55, 0x55, 0x55, 0xFF},
+		{0x00, 0x00, 0x00, 0xFF},
+	}
+	// The original Game Boy's greenish screen
+	PaletteGreen = Palette{
+		{0x9B, 0xBC, 0x0F, 0xFF},
+		{0x8B, 0xAC, 0x0F, 0xFF},
+		{0x30, 0x62, 0x30, 0xFF},
+		{0x0F, 0x38, 0x0F, 0xFF},
+	}
+)
+
+var paletteNames = map[string]Palette{
+	"gray":  PaletteGray,
+	"green": PaletteGreen,
+}
+
+// Parses a palette name ("gray" or "green"), or four comma separated hex
+// colors, lightest first, e.g. "ffffff,aaaaaa,555555,000000".
+func ParsePalette(s string) (Palette, error) {
+	if p, ok := paletteNames[strings.ToLower(s)]; ok {
+		return p, nil
+	}
+	var p Palette
+	parts := strings.Split(s, ",")
+	if len(parts) != len(p) {
+		return p, fmt.Errorf("unknown palette %q", s)
+	}
+	for i, part := range parts {
+		rgb, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimSpace(part), "#"), 16, 24)
+		if err != nil {
+			return p, fmt.Errorf("palette %q: %w", s, err)
+		}
+		p[i] = color.RGBA{uint8(rgb >> 16), uint8(rgb >> 8), uint8(rgb), 0xFF}
+	}
+	return p, nil
+}
+
+func (p Palette) colors() color.Palette {
+	return color.Palette{p[0], p[1], p[2], p[3]}
+}
+
+// The frame as an image whose color indices are the shades.
+func (f *Frame) Image(p Palette) *image.Paletted {
+	return f.ScaledImage(p, 1)
+}
+
+// Like Image, but every pixel becomes a scale x scale square.
+func (f *Frame) ScaledImage(p Palette, scale int) *image.Paletted {
+	scale = max(scale, 1)
+	img := image.NewPaletted(image.Rect(0, 0, SCREEN_WIDTH*scale, SCREEN_HEIGHT*scale), p.colors())
+	for y := range img.Rect.Dy() {
+		row := img.Pix[y*img.Stride : (y+1)*img.Stride]
+		for x := range row {
+			row[x] = f.At(x/scale, y/scale)
+		}
+	}
+	return img
+}
+
+type PNGOptions struct {
+	// Size of a Game Boy pixel; 0 means 1
+	Scale int
+	// Defaults to PaletteGray
+	Palette *Palette
+}
+
+// Writes the frame as a PNG.
+func EncodePNG(w io.Writer, f Frame, opts PNGOptions) error {
+	p := PaletteGray
+	if opts.Palette != nil {
+		p = *opts.Palette
+	}
+	return png.Encode(w, f.ScaledImage(p, opts.Scale))
+}
@@ -0,0 +1,57 @@
+package gameboy
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodePNG(t *testing.T) {
+	var frame Frame
+	for i := range frame {
+		frame[i] = uint8(i % 4)
+	}
+
+	t.Run("scaled", func(t *testing.T) {
+		req := require.New(t)
+		var b bytes.Buffer
+		req.NoError(EncodePNG(&b, frame, PNGOptions{Scale: 3, Palette: &PaletteGreen}))
+		img, err := png.Decode(&b)
+		req.NoError(err)
+		req.Equal(SCREEN_WIDTH*3, img.Bounds().Dx())
+		req.Equal(SCREEN_HEIGHT*3, img.Bounds().Dy())
+		for x := range 12 {
+			want := color.RGBAModel.Convert(PaletteGreen[x/3])
+			req.Equal(want, color.RGBAModel.Convert(img.At(x, 2)), "x=%d", x)
+		}
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		req := require.New(t)
+		var b bytes.Buffer
+		req.NoError(EncodePNG(&b, frame, PNGOptions{}))
+		img, err := png.Decode(&b)
+		req.NoError(err)
+		req.Equal(SCREEN_WIDTH, img.Bounds().Dx())
+		req.Equal(color.RGBAModel.Convert(PaletteGray[3]), color.RGBAModel.Convert(img.At(3, 0)))
+	})
+}
+
+func TestParsePalette(t *testing.T) {
+	req := require.New(t)
+	p, err := ParsePalette("Green")
+	req.NoError(err)
+	req.Equal(PaletteGreen, p)
+
+	p, err = ParsePalette("ffffff, #aaaaaa,555555,000000")
+	req.NoError(err)
+	req.Equal(PaletteGray, p)
+
+	_, err = ParsePalette("ffffff,aaaaaa")
+	req.Error(err)
+	_, err = ParsePalette("ffffff,aaaaaa,555555,nothex")
+	req.Error(err)
+}
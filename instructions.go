@@ -1,6 +1,9 @@
 package gameboy
 
-import "fmt"
+import (
+	"fmt"
+	"os"
+)
 
 type Instruction interface {
 	Exec(cpu *CPU)
@@ -555,8 +558,9 @@ func (LD_74) String() string {
 type HALT_76 struct{}
 
 func (HALT_76) Exec(cpu *CPU) {
-	fmt.Println("TODO: HALT_76")
-	// panic("TODO HALT_76")
+	// the cpu idles until an interrupt is pending, see CPU.Step
+	cpu.halted = true
+	cpu.Cycles += 4
 }
 func (HALT_76) Code() uint8 {
 	return 0x76
@@ -676,7 +680,7 @@ func (RST_DF) String() string {
 type ILLEGAL_FC_FC struct{}
 
 func (ILLEGAL_FC_FC) Exec(cpu *CPU) {
-	fmt.Println("TODO: ILLEGAL_FC_FC")
+	fmt.Fprintln(os.Stderr, "TODO: ILLEGAL_FC_FC")
 	// panic("TODO ILLEGAL_FC_FC")
 }
 func (ILLEGAL_FC_FC) Code() uint8 {
@@ -690,7 +694,7 @@ func (ILLEGAL_FC_FC) String() string {
 type SBC_99 struct{}
 
 func (SBC_99) Exec(cpu *CPU) {
-	fmt.Println("TODO: SBC_99")
+	fmt.Fprintln(os.Stderr, "TODO: SBC_99")
 	// panic("TODO SBC_99")
 }
 func (SBC_99) Code() uint8 {
@@ -953,7 +957,7 @@ func (RRCA_0F) String() string {
 type ILLEGAL_ED_ED struct{}
 
 func (ILLEGAL_ED_ED) Exec(cpu *CPU) {
-	fmt.Println("TODO: ILLEGAL_ED_ED")
+	fmt.Fprintln(os.Stderr, "TODO: ILLEGAL_ED_ED")
 	// panic("TODO ILLEGAL_ED_ED")
 }
 func (ILLEGAL_ED_ED) Code() uint8 {
@@ -1077,7 +1081,7 @@ func (RST_D7) String() string {
 type ILLEGAL_DD_DD struct{}
 
 func (ILLEGAL_DD_DD) Exec(cpu *CPU) {
-	fmt.Println("TODO: ILLEGAL_DD_DD")
+	fmt.Fprintln(os.Stderr, "TODO: ILLEGAL_DD_DD")
 	// panic("TODO ILLEGAL_DD_DD")
 }
 func (ILLEGAL_DD_DD) Code() uint8 {
@@ -1107,7 +1111,7 @@ func (RLA_17) String() string {
 type DAA_27 struct{}
 
 func (DAA_27) Exec(cpu *CPU) {
-	fmt.Println("TODO: DAA_27")
+	fmt.Fprintln(os.Stderr, "TODO: DAA_27")
 	// panic("TODO DAA_27")
 }
 func (DAA_27) Code() uint8 {
@@ -1140,7 +1144,7 @@ func (ADD_85) String() string {
 type ADC_89 struct{}
 
 func (ADC_89) Exec(cpu *CPU) {
-	fmt.Println("TODO: ADC_89")
+	fmt.Fprintln(os.Stderr, "TODO: ADC_89")
 	// panic("TODO ADC_89")
 }
 func (ADC_89) Code() uint8 {
@@ -1243,7 +1247,7 @@ func (LD_55) String() string {
 type SBC_98 struct{}
 
 func (SBC_98) Exec(cpu *CPU) {
-	fmt.Println("TODO: SBC_98")
+	fmt.Fprintln(os.Stderr, "TODO: SBC_98")
 	// panic("TODO SBC_98")
 }
 func (SBC_98) Code() uint8 {
@@ -1257,7 +1261,7 @@ func (SBC_98) String() string {
 type SBC_9D struct{}
 
 func (SBC_9D) Exec(cpu *CPU) {
-	fmt.Println("TODO: SBC_9D")
+	fmt.Fprintln(os.Stderr, "TODO: SBC_9D")
 	// panic("TODO SBC_9D")
 }
 func (SBC_9D) Code() uint8 {
@@ -1462,7 +1466,7 @@ func (LD_7C) String() string {
 type ADC_8C struct{}
 
 func (ADC_8C) Exec(cpu *CPU) {
-	fmt.Println("TODO: ADC_8C")
+	fmt.Fprintln(os.Stderr, "TODO: ADC_8C")
 	// panic("TODO ADC_8C")
 }
 func (ADC_8C) Code() uint8 {
@@ -1497,7 +1501,7 @@ func (XOR_AC) String() string {
 type ILLEGAL_EB_EB struct{}
 
 func (ILLEGAL_EB_EB) Exec(cpu *CPU) {
-	fmt.Println("TODO: ILLEGAL_EB_EB")
+	fmt.Fprintln(os.Stderr, "TODO: ILLEGAL_EB_EB")
 	// panic("TODO ILLEGAL_EB_EB")
 }
 func (ILLEGAL_EB_EB) Code() uint8 {
@@ -1802,7 +1806,7 @@ func (LD_44) String() string {
 type ILLEGAL_F4_F4 struct{}
 
 func (ILLEGAL_F4_F4) Exec(cpu *CPU) {
-	fmt.Println("TODO: ILLEGAL_F4_F4")
+	fmt.Fprintln(os.Stderr, "TODO: ILLEGAL_F4_F4")
 	// panic("TODO ILLEGAL_F4_F4")
 }
 func (ILLEGAL_F4_F4) Code() uint8 {
@@ -2425,7 +2429,7 @@ func (CP_BA) String() string {
 type ILLEGAL_E4_E4 struct{}
 
 func (ILLEGAL_E4_E4) Exec(cpu *CPU) {
-	fmt.Println("TODO: ILLEGAL_E4_E4")
+	fmt.Fprintln(os.Stderr, "TODO: ILLEGAL_E4_E4")
 	// panic("TODO ILLEGAL_E4_E4")
 }
 func (ILLEGAL_E4_E4) Code() uint8 {
@@ -2805,7 +2809,7 @@ func (LD_12) String() string {
 type SBC_9F struct{}
 
 func (SBC_9F) Exec(cpu *CPU) {
-	fmt.Println("TODO: SBC_9F")
+	fmt.Fprintln(os.Stderr, "TODO: SBC_9F")
 	// panic("TODO SBC_9F")
 }
 func (SBC_9F) Code() uint8 {
@@ -2880,7 +2884,7 @@ func (INC_1C) String() string {
 type ADC_8E struct{}
 
 func (ADC_8E) Exec(cpu *CPU) {
-	fmt.Println("TODO: ADC_8E")
+	fmt.Fprintln(os.Stderr, "TODO: ADC_8E")
 	// panic("TODO ADC_8E")
 }
 func (ADC_8E) Code() uint8 {
@@ -2962,7 +2966,7 @@ func (OR_B4) String() string {
 type ILLEGAL_E3_E3 struct{}
 
 func (ILLEGAL_E3_E3) Exec(cpu *CPU) {
-	fmt.Println("TODO: ILLEGAL_E3_E3")
+	fmt.Fprintln(os.Stderr, "TODO: ILLEGAL_E3_E3")
 	// panic("TODO ILLEGAL_E3_E3")
 }
 func (ILLEGAL_E3_E3) Code() uint8 {
@@ -3013,7 +3017,7 @@ func (DEC_35) String() string {
 type CCF_3F struct{}
 
 func (CCF_3F) Exec(cpu *CPU) {
-	fmt.Println("TODO: CCF_3F")
+	fmt.Fprintln(os.Stderr, "TODO: CCF_3F")
 	// panic("TODO CCF_3F")
 }
 func (CCF_3F) Code() uint8 {
@@ -3046,7 +3050,7 @@ func (ADD_87) String() string {
 type SBC_9B struct{}
 
 func (SBC_9B) Exec(cpu *CPU) {
-	fmt.Println("TODO: SBC_9B")
+	fmt.Fprintln(os.Stderr, "TODO: SBC_9B")
 	// panic("TODO SBC_9B")
 }
 func (SBC_9B) Code() uint8 {
@@ -3060,7 +3064,7 @@ func (SBC_9B) String() string {
 type ADC_CE struct{}
 
 func (ADC_CE) Exec(cpu *CPU) {
-	fmt.Println("TODO: ADC_CE")
+	fmt.Fprintln(os.Stderr, "TODO: ADC_CE")
 	// panic("TODO ADC_CE")
 }
 func (ADC_CE) Code() uint8 {
@@ -3183,7 +3187,7 @@ func (LD_01) String() string {
 type SCF_37 struct{}
 
 func (SCF_37) Exec(cpu *CPU) {
-	fmt.Println("TODO: SCF_37")
+	fmt.Fprintln(os.Stderr, "TODO: SCF_37")
 	// panic("TODO SCF_37")
 }
 func (SCF_37) Code() uint8 {
@@ -3348,7 +3352,7 @@ func (JR_28) String() string {
 type SBC_9E struct{}
 
 func (SBC_9E) Exec(cpu *CPU) {
-	fmt.Println("TODO: SBC_9E")
+	fmt.Fprintln(os.Stderr, "TODO: SBC_9E")
 	// panic("TODO SBC_9E")
 }
 func (SBC_9E) Code() uint8 {
@@ -3398,7 +3402,7 @@ func (RST_C7) String() string {
 type ILLEGAL_FD_FD struct{}
 
 func (ILLEGAL_FD_FD) Exec(cpu *CPU) {
-	fmt.Println("TODO: ILLEGAL_FD_FD")
+	fmt.Fprintln(os.Stderr, "TODO: ILLEGAL_FD_FD")
 	// panic("TODO ILLEGAL_FD_FD")
 }
 func (ILLEGAL_FD_FD) Code() uint8 {
@@ -3466,7 +3470,7 @@ func (LD_57) String() string {
 type ADC_88 struct{}
 
 func (ADC_88) Exec(cpu *CPU) {
-	fmt.Println("TODO: ADC_88")
+	fmt.Fprintln(os.Stderr, "TODO: ADC_88")
 	// panic("TODO ADC_88")
 }
 func (ADC_88) Code() uint8 {
@@ -3632,7 +3636,7 @@ func (ADD_81) String() string {
 type ADC_8B struct{}
 
 func (ADC_8B) Exec(cpu *CPU) {
-	fmt.Println("TODO: ADC_8B")
+	fmt.Fprintln(os.Stderr, "TODO: ADC_8B")
 	// panic("TODO ADC_8B")
 }
 func (ADC_8B) Code() uint8 {
@@ -3646,7 +3650,7 @@ func (ADC_8B) String() string {
 type SBC_9A struct{}
 
 func (SBC_9A) Exec(cpu *CPU) {
-	fmt.Println("TODO: SBC_9A")
+	fmt.Fprintln(os.Stderr, "TODO: SBC_9A")
 	// panic("TODO SBC_9A")
 }
 func (SBC_9A) Code() uint8 {
@@ -3660,7 +3664,7 @@ func (SBC_9A) String() string {
 type ILLEGAL_DB_DB struct{}
 
 func (ILLEGAL_DB_DB) Exec(cpu *CPU) {
-	fmt.Println("TODO: ILLEGAL_DB_DB")
+	fmt.Fprintln(os.Stderr, "TODO: ILLEGAL_DB_DB")
 	// panic("TODO ILLEGAL_DB_DB")
 }
 func (ILLEGAL_DB_DB) Code() uint8 {
@@ -3734,7 +3738,7 @@ func (XOR_A9) String() string {
 type ILLEGAL_D3_D3 struct{}
 
 func (ILLEGAL_D3_D3) Exec(cpu *CPU) {
-	fmt.Println("TODO: ILLEGAL_D3_D3")
+	fmt.Fprintln(os.Stderr, "TODO: ILLEGAL_D3_D3")
 	// panic("TODO ILLEGAL_D3_D3")
 }
 func (ILLEGAL_D3_D3) Code() uint8 {
@@ -3748,7 +3752,7 @@ func (ILLEGAL_D3_D3) String() string {
 type SBC_DE struct{}
 
 func (SBC_DE) Exec(cpu *CPU) {
-	fmt.Println("TODO: SBC_DE")
+	fmt.Fprintln(os.Stderr, "TODO: SBC_DE")
 	// panic("TODO SBC_DE")
 }
 func (SBC_DE) Code() uint8 {
@@ -3848,7 +3852,7 @@ func (LD_5F) String() string {
 type ILLEGAL_EC_EC struct{}
 
 func (ILLEGAL_EC_EC) Exec(cpu *CPU) {
-	fmt.Println("TODO: ILLEGAL_EC_EC")
+	fmt.Fprintln(os.Stderr, "TODO: ILLEGAL_EC_EC")
 	// panic("TODO ILLEGAL_EC_EC")
 }
 func (ILLEGAL_EC_EC) Code() uint8 {
@@ -4188,7 +4192,7 @@ func (DEC_2D) String() string {
 type SBC_9C struct{}
 
 func (SBC_9C) Exec(cpu *CPU) {
-	fmt.Println("TODO: SBC_9C")
+	fmt.Fprintln(os.Stderr, "TODO: SBC_9C")
 	// panic("TODO SBC_9C")
 }
 func (SBC_9C) Code() uint8 {
@@ -4277,7 +4281,7 @@ func (LD_11) String() string {
 type ADC_8D struct{}
 
 func (ADC_8D) Exec(cpu *CPU) {
-	fmt.Println("TODO: ADC_8D")
+	fmt.Fprintln(os.Stderr, "TODO: ADC_8D")
 	// panic("TODO ADC_8D")
 }
 func (ADC_8D) Code() uint8 {
@@ -4367,7 +4371,7 @@ func (LD_62) String() string {
 type ADC_8A struct{}
 
 func (ADC_8A) Exec(cpu *CPU) {
-	fmt.Println("TODO: ADC_8A")
+	fmt.Fprintln(os.Stderr, "TODO: ADC_8A")
 	// panic("TODO ADC_8A")
 }
 func (ADC_8A) Code() uint8 {
@@ -4553,7 +4557,7 @@ func (LD_7E) String() string {
 type ADC_8F struct{}
 
 func (ADC_8F) Exec(cpu *CPU) {
-	fmt.Println("TODO: ADC_8F")
+	fmt.Fprintln(os.Stderr, "TODO: ADC_8F")
 	// panic("TODO ADC_8F")
 }
 func (ADC_8F) Code() uint8 {
@@ -4715,7 +4719,7 @@ func (RL_17) String() string {
 type SLA_24 struct{}
 
 func (SLA_24) Exec(cpu *CPU) {
-	fmt.Println("TODO: SLA_24")
+	fmt.Fprintln(os.Stderr, "TODO: SLA_24")
 	// panic("TODO SLA_24")
 }
 func (SLA_24) Code() uint8 {
@@ -4759,7 +4763,7 @@ func (SET_FF) String() string {
 type SRA_2B struct{}
 
 func (SRA_2B) Exec(cpu *CPU) {
-	fmt.Println("TODO: SRA_2B")
+	fmt.Fprintln(os.Stderr, "TODO: SRA_2B")
 	// panic("TODO SRA_2B")
 }
 func (SRA_2B) Code() uint8 {
@@ -5468,7 +5472,7 @@ func (RR_1C) String() string {
 type SLA_21 struct{}
 
 func (SLA_21) Exec(cpu *CPU) {
-	fmt.Println("TODO: SLA_21")
+	fmt.Fprintln(os.Stderr, "TODO: SLA_21")
 	// panic("TODO SLA_21")
 }
 func (SLA_21) Code() uint8 {
@@ -5594,7 +5598,7 @@ func (SWAP_33) String() string {
 type SRA_29 struct{}
 
 func (SRA_29) Exec(cpu *CPU) {
-	fmt.Println("TODO: SRA_29")
+	fmt.Fprintln(os.Stderr, "TODO: SRA_29")
 	// panic("TODO SRA_29")
 }
 func (SRA_29) Code() uint8 {
@@ -5828,7 +5832,7 @@ func (SET_F1) String() string {
 type SLA_27 struct{}
 
 func (SLA_27) Exec(cpu *CPU) {
-	fmt.Println("TODO: SLA_27")
+	fmt.Fprintln(os.Stderr, "TODO: SLA_27")
 	// panic("TODO SLA_27")
 }
 func (SLA_27) Code() uint8 {
@@ -6063,7 +6067,7 @@ func (SET_C4) String() string {
 type SLA_25 struct{}
 
 func (SLA_25) Exec(cpu *CPU) {
-	fmt.Println("TODO: SLA_25")
+	fmt.Fprintln(os.Stderr, "TODO: SLA_25")
 	// panic("TODO SLA_25")
 }
 func (SLA_25) Code() uint8 {
@@ -6249,7 +6253,7 @@ func (RRC_08) String() string {
 type SRA_2A struct{}
 
 func (SRA_2A) Exec(cpu *CPU) {
-	fmt.Println("TODO: SRA_2A")
+	fmt.Fprintln(os.Stderr, "TODO: SRA_2A")
 	// panic("TODO SRA_2A")
 }
 func (SRA_2A) Code() uint8 {
@@ -6480,7 +6484,7 @@ func (SET_D1) String() string {
 type SRA_2C struct{}
 
 func (SRA_2C) Exec(cpu *CPU) {
-	fmt.Println("TODO: SRA_2C")
+	fmt.Fprintln(os.Stderr, "TODO: SRA_2C")
 	// panic("TODO SRA_2C")
 }
 func (SRA_2C) Code() uint8 {
@@ -7027,7 +7031,7 @@ func (SET_EE) String() string {
 type SLA_26 struct{}
 
 func (SLA_26) Exec(cpu *CPU) {
-	fmt.Println("TODO: SLA_26")
+	fmt.Fprintln(os.Stderr, "TODO: SLA_26")
 	// panic("TODO SLA_26")
 }
 func (SLA_26) Code() uint8 {
@@ -7253,7 +7257,7 @@ func (SET_CE) String() string {
 type SLA_23 struct{}
 
 func (SLA_23) Exec(cpu *CPU) {
-	fmt.Println("TODO: SLA_23")
+	fmt.Fprintln(os.Stderr, "TODO: SLA_23")
 	// panic("TODO SLA_23")
 }
 func (SLA_23) Code() uint8 {
@@ -7380,7 +7384,7 @@ func (RL_15) String() string {
 type SRA_2D struct{}
 
 func (SRA_2D) Exec(cpu *CPU) {
-	fmt.Println("TODO: SRA_2D")
+	fmt.Fprintln(os.Stderr, "TODO: SRA_2D")
 	// panic("TODO SRA_2D")
 }
 func (SRA_2D) Code() uint8 {
@@ -8107,7 +8111,7 @@ func (SET_E0) String() string {
 type SLA_20 struct{}
 
 func (SLA_20) Exec(cpu *CPU) {
-	fmt.Println("TODO: SLA_20")
+	fmt.Fprintln(os.Stderr, "TODO: SLA_20")
 	// panic("TODO SLA_20")
 }
 func (SLA_20) Code() uint8 {
@@ -8267,7 +8271,7 @@ func (RRC_09) String() string {
 type SLA_22 struct{}
 
 func (SLA_22) Exec(cpu *CPU) {
-	fmt.Println("TODO: SLA_22")
+	fmt.Fprintln(os.Stderr, "TODO: SLA_22")
 	// panic("TODO SLA_22")
 }
 func (SLA_22) Code() uint8 {
@@ -8281,7 +8285,7 @@ func (SLA_22) String() string {
 type SRA_2F struct{}
 
 func (SRA_2F) Exec(cpu *CPU) {
-	fmt.Println("TODO: SRA_2F")
+	fmt.Fprintln(os.Stderr, "TODO: SRA_2F")
 	// panic("TODO SRA_2F")
 }
 func (SRA_2F) Code() uint8 {
@@ -8772,7 +8776,7 @@ func (RLC_00) String() string {
 type SRA_2E struct{}
 
 func (SRA_2E) Exec(cpu *CPU) {
-	fmt.Println("TODO: SRA_2E")
+	fmt.Fprintln(os.Stderr, "TODO: SRA_2E")
 	// panic("TODO SRA_2E")
 }
 func (SRA_2E) Code() uint8 {
@@ -8974,7 +8978,7 @@ func (RES_BE) String() string {
 type SRA_28 struct{}
 
 func (SRA_28) Exec(cpu *CPU) {
-	fmt.Println("TODO: SRA_28")
+	fmt.Fprintln(os.Stderr, "TODO: SRA_28")
 	// panic("TODO SRA_28")
 }
 func (SRA_28) Code() uint8 {
@@ -0,0 +1,46 @@
+package gameboy
+
+// https://gbdev.io/pandocs/Interrupts.html
+const (
+	ADDR_IE = 0xFFFF // Interrupt enable
+
+	INT_VBLANK = 0x01
+	INT_STAT   = 0x02
+	INT_TIMER  = 0x04
+	INT_SERIAL = 0x08
+	INT_JOYPAD = 0x10
+)
+
+// Flags an interrupt in IF. It's serviced once both IE and IME allow it.
+func (m *Memory) RequestInterrupt(i uint8) {
+	m.data[ADDR_IF] |= i
+}
+
+// Services the highest priority pending interrupt, if any: pushes PC and jumps
+// to its handler at 0x40, 0x48, 0x50, 0x58 or 0x60. A pending interrupt also
+// wakes the cpu from HALT, even if IME is off.
+func (cpu *CPU) interrupt() bool {
+	mem := cpu.Mem
+	pending := mem.data[ADDR_IE] & mem.data[ADDR_IF] & 0x1F
+	if pending == 0 {
+		return false
+	}
+	cpu.halted = false
+	if !cpu.ime || cpu.prefix {
+		return false
+	}
+	for i := range 5 {
+		if pending&(1<<i) == 0 {
+			continue
+		}
+		mem.data[ADDR_IF] &^= 1 << i
+		cpu.ime = false
+		caller := cpu.PC
+		cpu.PushStack(cpu.PC)
+		cpu.PC = 0x0040 + uint16(i)*8
+		cpu.calls = append(cpu.calls, CallFrame{Func: cpu.PC, Caller: caller, SP: cpu.SP, Interrupt: true})
+		cpu.Cycles += 20
+		return true
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package gameboy
+
+// https://gbdev.io/pandocs/Joypad_Input.html
+const ADDR_P1 = 0xFF00
+
+// Set of pressed buttons
+type Buttons uint8
+
+const (
+	ButtonA Buttons = 1 << iota
+	ButtonB
+	ButtonSelect
+	ButtonStart
+	ButtonRight
+	ButtonLeft
+	ButtonUp
+	ButtonDown
+)
+
+// Sets which buttons are currently held down. Pressing a button requests the
+// joypad interrupt.
+func (m *Memory) SetButtons(b Buttons) {
+	if b&^m.buttons != 0 {
+		m.RequestInterrupt(INT_JOYPAD)
+	}
+	m.buttons = b
+}
+
+func (m *Memory) Buttons() Buttons { return m.buttons }
+
+// P1 only holds the two select bits; the lower nibble reflects the buttons in
+// the selected group(s), where 0 means pressed.
+func (m *Memory) readP1() byte {
+	sel := m.data[ADDR_P1] & 0x30
+	nibble := byte(0x0F)
+	if sel&0x20 == 0 { // action buttons
+		nibble &^= byte(m.buttons) & 0x0F
+	}
+	if sel&0x10 == 0 { // direction buttons
+		nibble &^= byte(m.buttons >> 4)
+	}
+	return 0xC0 | sel | nibble
+}
@@ -10,29 +10,134 @@ import (
 )
 
 type Memory struct {
-	i    int
-	data []byte
-	cart cartridge.Cartridge
-	boot []byte
+	i     int
+	data  []byte
+	cart  cartridge.Cartridge
+	boot  []byte
+	model Model
+
+	// receives bytes sent over the link port, see WithSerial
+	serial io.Writer
+	// if set, the whole address space is plain RAM, see NewFlatMemory
+	flat bool
+	// created without a cartridge, see NewMemory
+	noCart bool
+
+	// currently pressed buttons, read through P1
+	buttons Buttons
+	// channels (bit 0-3) restarted by writing to NRx4, handled by the APU
+	apuTrigger uint8
+	// DIV was written, which resets the timer's counter, see Timer.Step
+	divReset bool
+
+	// record suspicious accesses, see WithStrict
+	strict      bool
+	diagnostics []*AccessError
+	// called on suspicious accesses, see WithWarning
+	warn func(*AccessError)
+	// LY always reads 0x90, see WithDoctor
+	doctor bool
 }
 
+// Without a cartridge, i.e. a nil or empty one, the ROM area is an empty ROM
+// that can be written to like RAM, so tests can place data in it.
 func NewMemory(cart []byte) *Memory {
 	mem := &Memory{
-		data: make([]byte, 64*1024),
-		cart: cartridge.New(cart),
-		boot: BootROM,
+		data:   make([]byte, 64*1024),
+		cart:   cartridge.New(cart),
+		boot:   BootROM,
+		model:  DMG,
+		noCart: len(cart) == 0,
 	}
 	return mem
 }
 
+// Creates a memory without a cartridge, boot ROM or IO registers: all 64kB are
+// plain RAM. Used for running instructions in isolation, e.g. the single step
+// tests, which expect any address to be readable and writable.
+func NewFlatMemory() *Memory {
+	return &Memory{
+		data: make([]byte, 64*1024),
+		flat: true,
+	}
+}
+
+// Replaces the built-in boot ROM, e.g. with one loaded by LoadBootROM.
+func (m *Memory) WithBootROM(rom []byte, model Model) *Memory {
+	m.boot = rom
+	m.model = model
+	return m
+}
+
+// The hardware model being emulated, as given by the boot ROM.
+func (m *Memory) Model() Model { return m.model }
+
+// The ROM bank addr is read from: the switchable bank for 0x4000-0x7FFF, and 0
+// for everything else. A flat memory behaves like a cartridge without an MBC.
+func (m *Memory) Bank(addr uint16) int {
+	if !within(addr, 0x4000, 0x8000) {
+		return 0
+	}
+	if m.flat {
+		return 1
+	}
+	return m.cart.ROMBank()
+}
+
+// The number of ROM banks on the cartridge.
+func (m *Memory) ROMBanks() int {
+	if m.flat {
+		return 2
+	}
+	return m.cart.ROMBanks()
+}
+
+// Like Peek, but 0x4000-0x7FFF reads ROM bank rather than the mapped one, e.g.
+// to look at code in a bank that isn't switched in.
+func (m *Memory) ReadBank(bank int, addr uint16) byte {
+	if m.flat || !within(addr, 0x4000, 0x8000) {
+		return m.Peek(addr)
+	}
+	return m.cart.ReadROM(bank, addr)
+}
+
+// Like Read, but without side effects: reads of echo RAM and the unusable
+// area aren't diagnosed, see WithStrict and WithWarning. For tools looking at
+// memory, e.g. debuggers, whose reads the game didn't make.
+func (m *Memory) Peek(addr uint16) byte {
+	switch {
+	case m.flat:
+		return m.data[addr]
+	case within(addr, 0xE000, 0xFE00): // Echo RAM
+		return m.data[addr-0x2000]
+	case within(addr, 0xFEA0, 0xFF00): // Not Usable
+		return m.unusable(addr)
+	}
+	return m.Read(addr)
+}
+
+// Makes LY read 0x90, as if always in VBlank. gameboy-doctor's reference
+// traces are made that way, so without it they differ at the first LY poll.
+// The PPU itself is unaffected.
+func (m *Memory) WithDoctor() *Memory {
+	m.doctor = true
+	return m
+}
+
 func (m *Memory) Size() int {
 	return len(m.data)
 }
 
 func (m *Memory) Read(addr uint16) byte {
+	if m.flat {
+		return m.data[addr]
+	}
 	// https://gbdev.io/pandocs/Memory_Map.html
 	switch {
-	case within(addr, 0x0000, 0x00FF) && m.BootActive():
+	case within(addr, 0x0000, 0x0100) && m.BootActive():
+		return m.boot[addr]
+	case within(addr, 0x0200, 0x0900) && len(m.boot) > 0x200 && m.BootActive():
+		// The CGB boot ROM is larger, and skips the cartridge header
 		return m.boot[addr]
 	case within(addr, 0x0000, 0x8000): // Cartridge ROM
 		return m.cart.Read(addr)
@@ -44,47 +149,99 @@ func (m *Memory) Read(addr uint16) byte {
 		return m.data[addr]
 	case within(addr, 0xE000, 0xFE00): // Echo RAM, same as Internal
 		// All reads same as C000-DDFF
-		return m.data[addr-0x2000]
+		v := m.data[addr-0x2000]
+		m.diagnose(addr, v, false, ErrEchoRAM)
+		return v
 	case within(addr, 0xFE00, 0xFEA0): // Object Attribute Memory
 		return m.data[addr] // OAM
 	case within(addr, 0xFEA0, 0xFF00): // Not Usable
-		panic(fmt.Sprintf("unusable memory: illegal access %#4x", addr))
+		v := m.unusable(addr)
+		m.diagnose(addr, v, false, ErrUnusableMemory)
+		return v
+	case addr == ADDR_P1: // Joypad
+		return m.readP1()
+	case addr == ADDR_LY && m.doctor:
+		return 0x90
 	case within(addr, 0xFF00, 0xFF80): // IO Registers
 		return m.data[addr]
 	case within(addr, 0xFF80, 0xFFFF): // High RAM
 		return m.data[addr]
-	case addr == 0xFFFF: // Interrupt Enable Register
-		return m.data[addr]
 	}
-	panic(fmt.Sprintf("illegal memory access %#4x", addr))
+	// Interrupt Enable Register
+	return m.data[addr]
+}
+
+// What reads from the unusable area 0xFEA0-0xFEFF return. While the PPU is
+// scanning OAM or drawing, it's 0xFF on all models. Otherwise the monochrome
+// models return 0x00, and the CGB the high nibble of the address' low byte
+// twice.
+// https://gbdev.io/pandocs/Memory_Map.html#fea0feff-range
+func (m *Memory) unusable(addr uint16) byte {
+	if m.data[ADDR_LCDC]&0x80 != 0 && m.data[ADDR_STAT]&0x02 != 0 {
+		return 0xFF
+	}
+	if m.model == CGB {
+		lo := byte(addr) & 0xF0
+		return lo | lo>>4
+	}
+	return 0x00
 }
 
 func (m *Memory) WriteAt(addr uint16, b byte) *Memory {
+	if m.flat {
+		m.data[addr] = b
+		return m
+	}
 	switch {
-	case m.BootActive() && addr <= 0xFF:
-		panic("Write to boot")
 	case within(addr, 0x00, 0x8000): // cartridge ROM
-		m.cart.Write(addr, b)
+		// The boot ROM only covers reads; writes go to the cartridge.
+		if m.BootActive() && addr <= 0xFF {
+			m.diagnose(addr, b, true, ErrBootROMWrite)
+		}
+		if m.noCart {
+			m.cart.Poke(int(addr), b)
+			break
+		}
+		m.diagnose(addr, b, true, m.cart.Write(addr, b))
 	case within(addr, 0x8000, 0xA000): // VRAM
 		m.data[addr] = b
 	case within(addr, 0xa000, 0xc000): // Cartridge RAM
-		m.cart.Write(addr, b)
+		m.diagnose(addr, b, true, m.cart.Write(addr, b))
 	case within(addr, 0xC000, 0xE000): // Internal RAM
 		m.data[addr] = b
-	case within(addr, 0xE000, 0xFE00): // Echo RAM
-		panic("Write echo ram - not implemented")
+	case within(addr, 0xE000, 0xFE00): // Echo RAM, same as Internal
+		m.data[addr-0x2000] = b
+		m.diagnose(addr, b, true, ErrEchoRAM)
 	case within(addr, 0xFE00, 0xFEA0): // Object Attribute Memory
 		m.data[addr] = b
-	case within(addr, 0xFEA0, 0xFF00): // Not Usable
-		panic(fmt.Sprintf("unusable memory: illegal write: %#4x", addr))
+	case within(addr, 0xFEA0, 0xFF00): // Not Usable; writes are ignored
+		m.diagnose(addr, b, true, ErrUnusableMemory)
+	case addr == ADDR_P1: // Joypad, only the select bits are writable
+		m.data[addr] = b & 0x30
+	case addr == ADDR_SC: // Serial transfer control
+		m.data[addr] = b
+		if b&0x81 == 0x81 {
+			m.transferSerial()
+		}
+	case addr == ADDR_DIV: // Divider, any write resets it
+		m.data[addr] = 0
+		m.divReset = true
+	case addr == ADDR_DMA: // OAM DMA; copies 160 bytes from 0xXX00 into OAM
+		m.data[addr] = b
+		for i := range uint16(0xA0) {
+			m.data[ADDR_OAM+i] = m.Read(uint16(b)<<8 | i)
+		}
+	case within(addr, ADDR_NR10, ADDR_NR50) && (addr-ADDR_NR10)%5 == 4: // NRx4
+		m.data[addr] = b
+		if b&0x80 != 0 {
+			m.apuTrigger |= 1 << ((addr - ADDR_NR10) / 5)
+		}
 	case within(addr, 0xFF00, 0xFF80): // IO Registers
 		m.data[addr] = b
 	case within(addr, 0xFF80, 0xFFFF): // High RAM
 		m.data[addr] = b
 	case addr == 0xFFFF: // Interrupt Enable Register
 		m.data[addr] = b
-	default:
-		panic(fmt.Sprintf("Write: to address %#x - not supported", addr))
 	}
 	return m
 }
@@ -100,17 +257,15 @@ func (m *Memory) WriteInstr(v uint8) *Memory {
 	return m.Write(v)
 }
 
+// Writes the elements at the cursor, see CursorAt, and advances it. Unlike
+// WriteAt, this loads into the cartridge ROM directly rather than going
+// through the memory bank controller, so it can be used to place programs.
 func (m *Memory) Write(elems ...any) *Memory {
-	// well, we need to go via the mbc here...?
-	// TODO: this doesn't write via the mbc or anything, so
-	// we need to handle that! This is probably the issue :-)
-
 	for _, v := range elems {
 		switch v := v.(type) {
 		case []byte:
 			for _, b := range v {
-				m.WriteAt(uint16(m.i), b)
-				m.i++
+				m.poke(b)
 			}
 		case []Block:
 			for _, block := range v {
@@ -119,25 +274,32 @@ func (m *Memory) Write(elems ...any) *Memory {
 		case Block:
 			m.i = int(v.Offset)
 			for _, b := range v.Data {
-				m.WriteAt(uint16(m.i), b)
-				m.i++
+				m.poke(b)
 			}
 		case uint8:
-			m.WriteAt(uint16(m.i), v)
-			m.i++
+			m.poke(v)
 		case int:
-			m.WriteAt(uint16(m.i), uint8(v))
-			m.i++
+			m.poke(uint8(v))
 		case string:
 			// treat as code..
-			m.WriteAt(uint16(m.i), code(v))
-			m.i++
+			m.poke(code(v))
 		default:
 			panic(fmt.Sprintf("not implemented for %T", v))
 		}
 	}
 	return m
 }
+
+// writes b at the cursor and advances it
+func (m *Memory) poke(b byte) {
+	if !m.flat && m.i < 0x8000 {
+		m.cart.Poke(m.i, b)
+	} else {
+		m.WriteAt(uint16(m.i), b)
+	}
+	m.i++
+}
+
 func (m *Memory) CursorAt(p int) *Memory {
 	m.i = p
 	return m
@@ -3,6 +3,7 @@ package gameboy
 import (
 	"testing"
 
+	"github.com/kvalv/gameboy/cartridge"
 	"github.com/stretchr/testify/require"
 )
 
@@ -13,3 +14,122 @@ func TestDisableROM(t *testing.T) {
 	mem.WriteAt(0xFF50, 0x01)
 	req.False(mem.BootActive(), "boot should be disabled")
 }
+
+func TestUnusableMemory(t *testing.T) {
+	req := require.New(t)
+	mem := NewMemory(nil)
+	mem.WriteAt(0xFEA0, 0x12)
+	req.Equal(uint8(0x00), mem.Read(0xFEA0), "writes are ignored")
+
+	mem.WriteAt(ADDR_LCDC, 0x80)
+	mem.WriteAt(ADDR_STAT, 0x03) // drawing, OAM is in use
+	req.Equal(uint8(0xFF), mem.Read(0xFEA0))
+
+	cgb := NewMemory(nil).WithBootROM(make([]byte, CGB_BOOT_ROM_SIZE), CGB)
+	req.Equal(uint8(0xAA), cgb.Read(0xFEA0))
+	req.Equal(uint8(0xEE), cgb.Read(0xFEE3))
+}
+
+func TestStrict(t *testing.T) {
+	req := require.New(t)
+	// a cartridge without an MBC; see NewMemory for memory without one
+	mem := NewMemory(testROM(nil))
+	mem.Read(0xFEA0)
+	req.Empty(mem.Diagnostics(), "only recorded in strict mode")
+
+	mem.WithStrict()
+	mem.WriteAt(0x0010, 0x01) // during boot
+	mem.DisableBoot()
+	mem.WriteAt(0x2000, 0x01)
+	mem.Read(0xFEFF)
+	req.Equal(uint8(0x00), mem.Read(0x0010))
+
+	d := mem.Diagnostics()
+	req.Len(d, 4)
+	req.ErrorIs(d[0], ErrBootROMWrite)
+	req.ErrorIs(d[1], cartridge.ErrROMWrite)
+	req.Equal(uint16(0x0010), d[1].Addr)
+	req.ErrorIs(d[2], cartridge.ErrROMWrite)
+	req.Equal(&AccessError{Addr: 0xFEFF, Err: ErrUnusableMemory}, d[3])
+	req.Equal("read 0x00 at 0xfeff: access to unusable memory", d[3].Error())
+}
+
+func TestPeek(t *testing.T) {
+	req := require.New(t)
+	var warnings []*AccessError
+	mem := NewMemory(nil).WithStrict().WithWarning(func(e *AccessError) {
+		warnings = append(warnings, e)
+	})
+	mem.WriteAt(0xC001, 0x42)
+	req.Equal(uint8(0x42), mem.Peek(0xE001))
+	req.Equal(uint8(0x00), mem.Peek(0xFEA0))
+	req.Equal(uint8(0x42), mem.ReadBank(1, 0xE001))
+	req.Empty(warnings)
+	req.Empty(mem.Diagnostics())
+}
+
+func TestEchoRAM(t *testing.T) {
+	t.Run("write to echo", func(t *testing.T) {
+		req := require.New(t)
+		mem := NewMemory(nil)
+		mem.WriteAt(0xE000, 0x11)
+		mem.WriteAt(0xFDFF, 0x22)
+		req.Equal(uint8(0x11), mem.Read(0xC000))
+		req.Equal(uint8(0x22), mem.Read(0xDDFF))
+		req.Equal(uint8(0x11), mem.Read(0xE000))
+	})
+	t.Run("write to WRAM", func(t *testing.T) {
+		req := require.New(t)
+		mem := NewMemory(nil)
+		mem.WriteAt(0xC123, 0x33)
+		mem.WriteAt(0xDDFF, 0x44)
+		req.Equal(uint8(0x33), mem.Read(0xE123))
+		req.Equal(uint8(0x44), mem.Read(0xFDFF))
+	})
+	t.Run("not mirrored", func(t *testing.T) {
+		req := require.New(t)
+		mem := NewMemory(nil)
+		// 0xDE00-0xDFFF would be mirrored at 0xFE00, which is OAM instead
+		mem.WriteAt(0xDE00, 0x55)
+		req.Equal(uint8(0x00), mem.Read(ADDR_OAM))
+		mem.WriteAt(ADDR_OAM, 0x66)
+		req.Equal(uint8(0x55), mem.Read(0xDE00))
+	})
+	t.Run("warning", func(t *testing.T) {
+		req := require.New(t)
+		var warnings []*AccessError
+		mem := NewMemory(nil).WithWarning(func(e *AccessError) {
+			warnings = append(warnings, e)
+		})
+		mem.WriteAt(0xC000, 0x11)
+		mem.Read(0xC000)
+		req.Empty(warnings)
+
+		mem.WriteAt(0xE000, 0x22)
+		mem.Read(0xE001)
+		req.Equal([]*AccessError{
+			{Addr: 0xE000, Value: 0x22, Write: true, Err: ErrEchoRAM},
+			{Addr: 0xE001, Value: 0x00, Err: ErrEchoRAM},
+		}, warnings)
+		req.ErrorIs(warnings[0], ErrEchoRAM)
+		req.Empty(mem.Diagnostics(), "warnings aren't recorded outside strict mode")
+	})
+}
+
+func TestReadBank(t *testing.T) {
+	req := require.New(t)
+	rom := make([]byte, 4*0x4000)
+	rom[0x0147] = 0x01 // MBC1
+	rom[0x0148] = 0x01 // 64kB
+	for bank := 1; bank < 4; bank++ {
+		rom[bank*0x4000+0x0010] = byte(bank)
+	}
+	mem := NewMemory(rom)
+	mem.DisableBoot()
+	req.Equal(4, mem.ROMBanks())
+	mem.WriteAt(0x2000, 0x02)
+	req.Equal(uint8(2), mem.Read(0x4010))
+	req.Equal(uint8(3), mem.ReadBank(3, 0x4010))
+	req.Equal(2, mem.Bank(0x4010), "still mapped")
+	req.Equal(uint8(0x01), mem.ReadBank(3, 0x0147), "not banked")
+}
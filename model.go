@@ -0,0 +1,46 @@
+package gameboy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The hardware revision being emulated. They mostly differ in the state the
+// boot ROM leaves behind.
+// https://gbdev.io/pandocs/Power_Up_Sequence.html
+type Model int
+
+const (
+	DMG0 Model = iota // early original Game Boy
+	DMG               // original Game Boy
+	MGB               // Game Boy Pocket
+	SGB               // Super Game Boy
+	SGB2              // Super Game Boy 2
+	CGB               // Game Boy Color, running a DMG cartridge
+)
+
+var modelNames = map[Model]string{
+	DMG0: "DMG0",
+	DMG:  "DMG",
+	MGB:  "MGB",
+	SGB:  "SGB",
+	SGB2: "SGB2",
+	CGB:  "CGB",
+}
+
+func (m Model) String() string {
+	if s, ok := modelNames[m]; ok {
+		return s
+	}
+	return fmt.Sprintf("Model(%d)", int(m))
+}
+
+// Parses a model name such as "dmg" or "SGB2".
+func ParseModel(s string) (Model, error) {
+	for m, name := range modelNames {
+		if strings.EqualFold(s, name) {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown model %q", s)
+}
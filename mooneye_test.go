@@ -0,0 +1,91 @@
+package gameboy
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const MOONEYE_DIR = "testdata/mooneye"
+
+// Runs every ROM found below testdata/mooneye as a subtest.
+func TestMooneye(t *testing.T) {
+	var roms []string
+	filepath.WalkDir(MOONEYE_DIR, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && filepath.Ext(path) == ".gb" {
+			roms = append(roms, path)
+		}
+		return nil
+	})
+	if len(roms) == 0 {
+		t.Skipf("no test ROMs in %s", MOONEYE_DIR)
+	}
+
+	for _, path := range roms {
+		name, _ := filepath.Rel(MOONEYE_DIR, path)
+		name = strings.TrimSuffix(filepath.ToSlash(name), ".gb")
+		t.Run(name, func(t *testing.T) {
+			rom, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res := RunMooneye(rom, MooneyeOptions{
+				MaxCycles: 120 * CYCLES_PER_FRAME, // mooneye gives up after ~2 seconds
+			})
+			if res.Err != nil {
+				t.Fatalf("after %d cycles: %v", res.Cycles, res.Err)
+			}
+			if !res.Passed {
+				t.Fatalf("failed: registers % x", res.Registers)
+			}
+		})
+	}
+}
+
+// Loads the given values into B, C, D, E, H and L and hits the breakpoint
+func breakpointProgram(regs [6]uint8) []byte {
+	return []byte{
+		code("LD B,n8"), regs[0],
+		code("LD C,n8"), regs[1],
+		code("LD D,n8"), regs[2],
+		code("LD E,n8"), regs[3],
+		code("LD H,n8"), regs[4],
+		code("LD L,n8"), regs[5],
+		code("LD B,B"),
+		code("JR e8"), 0xFE,
+	}
+}
+
+func TestRunMooneye(t *testing.T) {
+	opts := MooneyeOptions{MaxCycles: 100_000}
+	t.Run("pass", func(t *testing.T) {
+		req := require.New(t)
+		res := RunMooneye(testROM(breakpointProgram(MooneyePass)), opts)
+		req.NoError(res.Err)
+		req.True(res.Finished)
+		req.True(res.Passed)
+	})
+	t.Run("fail", func(t *testing.T) {
+		req := require.New(t)
+		res := RunMooneye(testROM(breakpointProgram(MooneyeFail)), opts)
+		req.NoError(res.Err)
+		req.True(res.Finished)
+		req.False(res.Passed)
+	})
+	t.Run("garbage", func(t *testing.T) {
+		req := require.New(t)
+		res := RunMooneye(testROM(breakpointProgram([6]uint8{1, 2, 3, 4, 5, 6})), opts)
+		req.Error(res.Err)
+		req.False(res.Passed)
+	})
+	t.Run("budget", func(t *testing.T) {
+		req := require.New(t)
+		res := RunMooneye(testROM([]byte{code("JR e8"), 0xFE}), opts)
+		req.Error(res.Err)
+		req.False(res.Finished)
+	})
+}
@@ -0,0 +1,268 @@
+package gameboy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// Movies are recorded input: the buttons held on every frame, starting from
+// either power on or a save state. Played back on the same ROM, they recreate
+// the exact same run, which makes for reproducible bug reports.
+//
+// The file format is, little endian:
+//
+//	magic     [4]byte "GBMV"
+//	version   uint16
+//	checksum  uint32  CRC-32 of the ROM
+//	model     uint8
+//	skipBoot  bool
+//	interval  uint32  frames between framebuffer checksums
+//	stateLen  uint32  0 when starting from power on
+//	state     [stateLen]byte  see SaveState
+//	frames    uint32
+//	inputs    [frames]uint8  see Buttons
+//	checksums [frames/interval]uint32  CRC-32 of the framebuffer
+const MOVIE_VERSION = 1
+
+var movieMagic = [4]byte{'G', 'B', 'M', 'V'}
+
+var (
+	ErrMovieFormat  = errors.New("not a movie")
+	ErrMovieVersion = errors.New("unsupported movie version")
+	ErrMovieROM     = errors.New("movie is for a different ROM")
+	// The movie was recorded with another model, or with(out) skipping the
+	// boot ROM
+	ErrMovieOptions = errors.New("movie was recorded with different options")
+)
+
+type Movie struct {
+	ROMChecksum uint32
+	Model       Model
+	SkipBoot    bool
+	// Save state the movie starts from; nil means power on
+	State []byte
+	// Frames between checksums
+	Interval  int
+	Inputs    []Buttons
+	Checksums []uint32
+}
+
+type movieHeader struct {
+	Magic    [4]byte
+	Version  uint16
+	Checksum uint32
+	Model    uint8
+	SkipBoot bool
+	Interval uint32
+}
+
+func (m *Movie) Save(w io.Writer) error {
+	header := movieHeader{
+		Magic:    movieMagic,
+		Version:  MOVIE_VERSION,
+		Checksum: m.ROMChecksum,
+		Model:    uint8(m.Model),
+		SkipBoot: m.SkipBoot,
+		Interval: uint32(m.Interval),
+	}
+	inputs := make([]uint8, len(m.Inputs))
+	for i, b := range m.Inputs {
+		inputs[i] = uint8(b)
+	}
+	for _, v := range []any{
+		header,
+		uint32(len(m.State)), m.State,
+		uint32(len(inputs)), inputs,
+		m.Checksums,
+	} {
+		if err := binary.Write(w, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ReadMovie(r io.Reader) (*Movie, error) {
+	var header movieHeader
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMovieFormat, err)
+	}
+	switch {
+	case header.Magic != movieMagic:
+		return nil, ErrMovieFormat
+	case header.Version != MOVIE_VERSION:
+		return nil, fmt.Errorf("%w: %d", ErrMovieVersion, header.Version)
+	case header.Interval == 0:
+		return nil, fmt.Errorf("%w: checksum interval is 0", ErrMovieFormat)
+	}
+	m := &Movie{
+		ROMChecksum: header.Checksum,
+		Model:       Model(header.Model),
+		SkipBoot:    header.SkipBoot,
+		Interval:    int(header.Interval),
+	}
+
+	readBytes := func() ([]byte, error) {
+		var n uint32
+		if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+			return nil, err
+		}
+		var b bytes.Buffer
+		_, err := io.CopyN(&b, r, int64(n))
+		return b.Bytes(), err
+	}
+	state, err := readBytes()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMovieFormat, err)
+	}
+	if len(state) > 0 {
+		m.State = state
+	}
+	inputs, err := readBytes()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMovieFormat, err)
+	}
+	for _, b := range inputs {
+		m.Inputs = append(m.Inputs, Buttons(b))
+	}
+	m.Checksums = make([]uint32, len(inputs)/m.Interval)
+	if err := binary.Read(r, binary.LittleEndian, m.Checksums); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMovieFormat, err)
+	}
+	return m, nil
+}
+
+// Returned by MoviePlayer when the framebuffer doesn't match the recording,
+// i.e. the emulator doesn't behave the same as when the movie was recorded.
+type DesyncError struct {
+	Frame     int // frames into the movie
+	Want, Got uint32
+}
+
+func (e *DesyncError) Error() string {
+	return fmt.Sprintf("movie desync at frame %d: framebuffer checksum %08x, want %08x", e.Frame, e.Got, e.Want)
+}
+
+func frameChecksum(f Frame) uint32 {
+	return crc32.ChecksumIEEE(f[:])
+}
+
+// Records the buttons held on every frame run through it.
+type MovieRecorder struct {
+	emu   *Emulator
+	movie Movie
+}
+
+// Starts recording every interval frames. If powerOn is set, the emulator is
+// reset and the movie starts from there; otherwise it starts from the
+// current state.
+func NewMovieRecorder(e *Emulator, interval int, powerOn bool) (*MovieRecorder, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("checksum interval must be positive, got %d", interval)
+	}
+	r := &MovieRecorder{
+		emu: e,
+		movie: Movie{
+			ROMChecksum: crc32.ChecksumIEEE(e.rom),
+			Model:       e.opts.Model,
+			SkipBoot:    e.opts.SkipBoot,
+			Interval:    interval,
+		},
+	}
+	if powerOn {
+		e.Reset()
+		return r, nil
+	}
+	var state bytes.Buffer
+	if err := e.SaveState(&state); err != nil {
+		return nil, err
+	}
+	r.movie.State = state.Bytes()
+	return r, nil
+}
+
+// Runs a frame with the buttons currently held, see Emulator.SetButtons.
+func (r *MovieRecorder) RunFrame() error {
+	m := &r.movie
+	m.Inputs = append(m.Inputs, r.emu.CPU.Mem.Buttons())
+	if err := r.emu.RunFrame(); err != nil {
+		return err
+	}
+	if len(m.Inputs)%m.Interval == 0 {
+		m.Checksums = append(m.Checksums, frameChecksum(r.emu.Framebuffer()))
+	}
+	return nil
+}
+
+// The movie recorded so far
+func (r *MovieRecorder) Movie() *Movie { return &r.movie }
+
+// Drives the joypad from a movie.
+type MoviePlayer struct {
+	emu   *Emulator
+	movie *Movie
+	frame int
+}
+
+// Puts the emulator in the movie's starting state. The emulator must run the
+// same ROM, with the same options, as the one the movie was recorded on.
+func NewMoviePlayer(e *Emulator, m *Movie) (*MoviePlayer, error) {
+	switch {
+	case m.ROMChecksum != crc32.ChecksumIEEE(e.rom):
+		return nil, ErrMovieROM
+	case m.Model != e.opts.Model || m.SkipBoot != e.opts.SkipBoot:
+		return nil, fmt.Errorf("%w: model %v, skip boot %t", ErrMovieOptions, m.Model, m.SkipBoot)
+	}
+	if m.State == nil {
+		e.Reset()
+	} else if err := e.LoadState(bytes.NewReader(m.State)); err != nil {
+		return nil, err
+	}
+	return &MoviePlayer{emu: e, movie: m}, nil
+}
+
+// Runs the next frame of the movie. Returns a *DesyncError if the frame
+// doesn't match the recording, and io.EOF once the movie is over.
+func (p *MoviePlayer) RunFrame() error {
+	if err := p.Press(); err != nil {
+		return err
+	}
+	if err := p.emu.RunFrame(); err != nil {
+		return err
+	}
+	return p.EndFrame()
+}
+
+// Holds the buttons of the next frame of the movie, for running it some other
+// way than RunFrame, e.g. with Emulator.RunFrameUntil; call EndFrame once it's
+// complete. Returns io.EOF once the movie is over.
+func (p *MoviePlayer) Press() error {
+	if p.frame >= len(p.movie.Inputs) {
+		return io.EOF
+	}
+	p.emu.SetButtons(p.movie.Inputs[p.frame])
+	return nil
+}
+
+// Moves on to the next frame, after the one started with Press is complete.
+// Returns a *DesyncError if it doesn't match the recording.
+func (p *MoviePlayer) EndFrame() error {
+	m := p.movie
+	p.frame++
+	if p.frame%m.Interval == 0 {
+		want := m.Checksums[p.frame/m.Interval-1]
+		if got := frameChecksum(p.emu.Framebuffer()); got != want {
+			return &DesyncError{Frame: p.frame, Want: want, Got: got}
+		}
+	}
+	return nil
+}
+
+// Frames played so far
+func (p *MoviePlayer) Frame() int { return p.frame }
+
+func (p *MoviePlayer) Done() bool { return p.frame >= len(p.movie.Inputs) }
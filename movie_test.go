@@ -0,0 +1,102 @@
+package gameboy
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Uses the direction buttons as background palette, so the input shows on
+// screen: Right and Left pick the shade of the blank background.
+var paletteProgram = []byte{
+	code("LD A,n8"), 0x20,
+	code("LDH (a8),A"), 0x00,
+	code("LDH A,(a8)"), 0x00,
+	code("LDH (a8),A"), 0x47, // BGP
+	code("JR e8"), 0xF6,
+}
+
+func TestMovie(t *testing.T) {
+	const FRAMES = 60
+	newEmulator := func() *Emulator {
+		return NewEmulator(testROM(paletteProgram), EmulatorOptions{Model: DMG, SkipBoot: true})
+	}
+	record := func(emu *Emulator, powerOn bool) *Movie {
+		rec, err := NewMovieRecorder(emu, 10, powerOn)
+		require.NoError(t, err)
+		for f := range FRAMES {
+			emu.SetButtons(Buttons(f/4%16) << 4)
+			require.NoError(t, rec.RunFrame())
+		}
+		return rec.Movie()
+	}
+	play := func(emu *Emulator, m *Movie) error {
+		p, err := NewMoviePlayer(emu, m)
+		if err != nil {
+			return err
+		}
+		for {
+			if err := p.RunFrame(); err != nil {
+				return err
+			}
+		}
+	}
+
+	t.Run("play back", func(t *testing.T) {
+		req := require.New(t)
+		emu := newEmulator()
+		movie := record(emu, true)
+		req.Len(movie.Inputs, FRAMES)
+		req.Len(movie.Checksums, FRAMES/10)
+		want := emu.Framebuffer()
+
+		var b bytes.Buffer
+		req.NoError(movie.Save(&b))
+		movie, err := ReadMovie(&b)
+		req.NoError(err)
+
+		emu = newEmulator()
+		req.ErrorIs(play(emu, movie), io.EOF)
+		req.Equal(want, emu.Framebuffer())
+	})
+
+	t.Run("from save state", func(t *testing.T) {
+		req := require.New(t)
+		emu := newEmulator()
+		emu.SetButtons(ButtonDown)
+		for range 7 {
+			req.NoError(emu.RunFrame())
+		}
+		movie := record(emu, false)
+		req.NotNil(movie.State)
+		want := emu.Framebuffer()
+
+		emu = newEmulator()
+		req.ErrorIs(play(emu, movie), io.EOF)
+		req.Equal(want, emu.Framebuffer())
+	})
+
+	t.Run("desync", func(t *testing.T) {
+		req := require.New(t)
+		movie := record(newEmulator(), true)
+		movie.Inputs[29] ^= ButtonRight // shows on the frame checked after 30
+
+		err := play(newEmulator(), movie)
+		var desync *DesyncError
+		req.ErrorAs(err, &desync)
+		req.Equal(30, desync.Frame)
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		req := require.New(t)
+		movie := record(newEmulator(), true)
+		_, err := NewMoviePlayer(NewEmulator(testROM(loopProgram), EmulatorOptions{Model: DMG, SkipBoot: true}), movie)
+		req.ErrorIs(err, ErrMovieROM)
+		_, err = NewMoviePlayer(NewEmulator(testROM(paletteProgram), EmulatorOptions{Model: MGB, SkipBoot: true}), movie)
+		req.ErrorIs(err, ErrMovieOptions)
+		_, err = ReadMovie(bytes.NewReader([]byte("GBSS")))
+		req.ErrorIs(err, ErrMovieFormat)
+	})
+}
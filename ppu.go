@@ -1,28 +1,242 @@
 package gameboy
 
+import "slices"
+
+const (
+	SCREEN_WIDTH  = 160
+	SCREEN_HEIGHT = 144
+
+	CYCLES_PER_LINE = 456
+	// Number of cycles in a single frame, i.e. 154 lines of 456 cycles each.
+	CYCLES_PER_FRAME = 154 * CYCLES_PER_LINE
+)
+
+const (
+	ADDR_BGP  = 0xFF47 // Background palette
+	ADDR_OBP0 = 0xFF48 // Sprite palette 0
+	ADDR_OBP1 = 0xFF49 // Sprite palette 1
+	ADDR_DMA  = 0xFF46 // OAM DMA source address
+	ADDR_OAM  = 0xFE00
+)
+
+// A single screen worth of pixels, row by row. Each pixel is a shade from 0
+// (lightest) to 3 (darkest), i.e. the palettes have already been applied.
+type Frame [SCREEN_WIDTH * SCREEN_HEIGHT]uint8
+
+func (f *Frame) At(x, y int) uint8 { return f[y*SCREEN_WIDTH+x] }
+
 // Pixel-Processing Unit -- the thing that is responsible for drawing on the screen
 // https://gbdev.io/pandocs/Rendering.html
 type PPU struct {
-	prev int
+	prev int // cpu cycles at the start of the current line
+	line int // 0 - 153; same as LY unless the LCD is off
+
+	back  Frame // being drawn
+	front Frame // last complete frame
+
+	// the window has its own line counter, which only advances on lines where
+	// the window is visible
+	windowLine int
+
+	// set when entering VBlank; cleared by whoever waits for it
+	vblank bool
 }
 
 func (p *PPU) Step(cpu *CPU) {
+	mem := cpu.Mem
+	lcdOn := bit(mem.data[ADDR_LCDC], 7) == 1
 
-	// ppuFreq := 59.7 // fps
-
-	// period := CPU_FREQUENCY / ppuFreq
-	// n := cpu.Cycles / int(period)
+	// 0 - 143 -> drawing lines
+	// 144 - 153 -> vblank
+	for cpu.Cycles-p.prev >= CYCLES_PER_LINE {
+		p.prev += CYCLES_PER_LINE
+		if lcdOn && p.line < SCREEN_HEIGHT {
+			p.renderLine(mem, p.line)
+		}
+		p.line = (p.line + 1) % 154
+		switch p.line {
+		case 0:
+			p.windowLine = 0
+		case SCREEN_HEIGHT:
+			if !lcdOn {
+				p.back = Frame{}
+			}
+			p.front = p.back
+			p.vblank = true
+			if lcdOn {
+				mem.RequestInterrupt(INT_VBLANK)
+			}
+		}
+	}
 
-	// increments every 456 cycles
-	if cpu.Cycles-p.prev < 456 {
+	if !lcdOn {
+		mem.data[ADDR_LY] = 0
+		mem.data[ADDR_STAT] = 0x80 | mem.data[ADDR_STAT]&0x78
 		return
 	}
+	mem.data[ADDR_LY] = uint8(p.line)
+	p.updateSTAT(mem, cpu.Cycles-p.prev)
+}
 
-	p.prev = cpu.Cycles
-	// otherwise increase
+// Updates the mode and LY=LYC bits of STAT, and requests the STAT interrupt
+// when one of the enabled conditions becomes true.
+func (p *PPU) updateSTAT(mem *Memory, dot int) {
+	var mode uint8
+	switch {
+	case p.line >= SCREEN_HEIGHT:
+		mode = 1 // vblank
+	case dot < 80:
+		mode = 2 // OAM scan
+	case dot < 252:
+		mode = 3 // drawing
+	default:
+		mode = 0 // hblank
+	}
+	var coincidence uint8
+	if mem.data[ADDR_LY] == mem.data[ADDR_LYC] {
+		coincidence = 0x04
+	}
 
-	// 0 - 143 -> drawing lines
-	// 144 - 153 -> vblank
-	LY := (cpu.Mem.LY() + 1) % (154)
-	cpu.Mem.WriteAt(ADDR_LY, LY)
+	old := mem.data[ADDR_STAT]
+	stat := 0x80 | old&0x78 | coincidence | mode
+	mem.data[ADDR_STAT] = stat
+
+	if coincidence != 0 && old&0x04 == 0 && stat&0x40 != 0 {
+		mem.RequestInterrupt(INT_STAT)
+	}
+	// mode 0, 1 and 2 have an enable bit each: bit 3, 4 and 5
+	if mode != old&0x03 && mode != 3 && stat&(0x08<<mode) != 0 {
+		mem.RequestInterrupt(INT_STAT)
+	}
+}
+
+func (p *PPU) renderLine(mem *Memory, ly int) {
+	lcdc := mem.data[ADDR_LCDC]
+	row := p.back[ly*SCREEN_WIDTH : (ly+1)*SCREEN_WIDTH]
+
+	// color indices before the palette is applied; sprites need them to
+	// decide whether they are hidden behind the background
+	var colors [SCREEN_WIDTH]uint8
+
+	if bit(lcdc, 0) == 1 { // background and window enabled
+		bgMap := 0x9800
+		if bit(lcdc, 3) == 1 {
+			bgMap = 0x9C00
+		}
+		y := (ly + int(mem.SCY())) & 0xFF
+		for x := range SCREEN_WIDTH {
+			colors[x] = tilePixel(mem, lcdc, bgMap, (x+int(mem.SCX()))&0xFF, y)
+		}
+
+		wx, wy := int(mem.WX())-7, int(mem.WY())
+		if bit(lcdc, 5) == 1 && ly >= wy && wx < SCREEN_WIDTH {
+			windowMap := 0x9800
+			if bit(lcdc, 6) == 1 {
+				windowMap = 0x9C00
+			}
+			for x := max(wx, 0); x < SCREEN_WIDTH; x++ {
+				colors[x] = tilePixel(mem, lcdc, windowMap, x-wx, p.windowLine)
+			}
+			p.windowLine++
+		}
+	}
+
+	bgp := mem.data[ADDR_BGP]
+	for x := range SCREEN_WIDTH {
+		row[x] = shade(bgp, colors[x])
+	}
+
+	if bit(lcdc, 1) == 1 {
+		renderSprites(mem, lcdc, ly, row, &colors)
+	}
+}
+
+// Color index (0-3) of the pixel at x, y in the 256x256 tile map at mapAddr.
+func tilePixel(mem *Memory, lcdc uint8, mapAddr, x, y int) uint8 {
+	index := mem.data[mapAddr+(y/8)*32+x/8]
+
+	// LCDC bit 4 selects between two addressing modes: 0x8000 with an
+	// unsigned index, or 0x9000 with a signed one.
+	var addr int
+	if bit(lcdc, 4) == 1 {
+		addr = 0x8000 + int(index)*TILE_DATA_SIZE
+	} else {
+		addr = 0x9000 + int(int8(index))*TILE_DATA_SIZE
+	}
+	addr += (y % 8) * 2
+	return tileColor(mem.data[addr], mem.data[addr+1], 7-x%8)
+}
+
+// For each line of a tile, the first byte holds the low bit of the color index
+// and the second byte the high bit. Bit 7 is the leftmost pixel.
+func tileColor(lo, hi byte, b int) uint8 {
+	return uint8(bit(lo, b) | bit(hi, b)<<1)
+}
+
+// Maps a color index to a shade through one of the palette registers.
+func shade(palette, color uint8) uint8 {
+	return (palette >> (2 * color)) & 0x03
+}
+
+// https://gbdev.io/pandocs/OAM.html
+func renderSprites(mem *Memory, lcdc uint8, ly int, row []uint8, bg *[SCREEN_WIDTH]uint8) {
+	height := 8
+	if bit(lcdc, 2) == 1 {
+		height = 16
+	}
+
+	// at most 10 sprites per line, the first ones in OAM order
+	var visible []int
+	for i := 0; i < 40 && len(visible) < 10; i++ {
+		y := int(mem.data[ADDR_OAM+i*4]) - 16
+		if y <= ly && ly < y+height {
+			visible = append(visible, ADDR_OAM+i*4)
+		}
+	}
+	// When sprites overlap, the one with the lower X wins, and then the one
+	// first in OAM. Draw the winners last.
+	slices.SortStableFunc(visible, func(a, b int) int {
+		return int(mem.data[a+1]) - int(mem.data[b+1])
+	})
+	slices.Reverse(visible)
+
+	for _, addr := range visible {
+		y := int(mem.data[addr]) - 16
+		x := int(mem.data[addr+1]) - 8
+		tile := int(mem.data[addr+2])
+		flags := mem.data[addr+3]
+
+		line := ly - y
+		if bit(flags, 6) == 1 { // y flip
+			line = height - 1 - line
+		}
+		if height == 16 {
+			tile &^= 1
+		}
+		data := 0x8000 + tile*TILE_DATA_SIZE + line*2
+		lo, hi := mem.data[data], mem.data[data+1]
+
+		palette := mem.data[ADDR_OBP0]
+		if bit(flags, 4) == 1 {
+			palette = mem.data[ADDR_OBP1]
+		}
+		for px := range 8 {
+			sx := x + px
+			if sx < 0 || sx >= SCREEN_WIDTH {
+				continue
+			}
+			b := 7 - px
+			if bit(flags, 5) == 1 { // x flip
+				b = px
+			}
+			c := tileColor(lo, hi, b)
+			if c == 0 { // transparent
+				continue
+			}
+			if bit(flags, 7) == 1 && bg[sx] != 0 { // behind the background
+				continue
+			}
+			row[sx] = shade(palette, c)
+		}
+	}
 }
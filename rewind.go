@@ -0,0 +1,188 @@
+package gameboy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+var ErrRewind = errors.New("can't rewind that far")
+
+type RewindOptions struct {
+	// Frames between snapshots. Rewinding restores the nearest snapshot and
+	// then runs up to this many frames to land on the exact frame.
+	Interval int
+	// Number of snapshots kept; the oldest are dropped. Together with
+	// Interval, this decides how far back one can go.
+	Capacity int
+}
+
+var DefaultRewindOptions = RewindOptions{
+	Interval: 10,
+	Capacity: 600, // 100 seconds
+}
+
+// Periodic snapshots of the machine, with the buttons held on every frame
+// since the oldest one so any frame in between can be recreated.
+//
+// Only the newest snapshot is kept whole. Each older one is stored as the
+// difference to the one after it, which is mostly zeros and compresses well.
+type rewindBuffer struct {
+	opts RewindOptions
+
+	latest      []byte // save state of the newest snapshot
+	latestFrame int
+	deltas      []snapshotDelta // oldest first
+
+	// buttons held on each frame, starting at first
+	first  int
+	inputs []Buttons
+}
+
+type snapshotDelta struct {
+	frame int
+	data  []byte // turns the next snapshot into this one, see diff
+}
+
+// The frame of the oldest snapshot, i.e. how far back one can go.
+func (r *rewindBuffer) oldest() int {
+	if len(r.deltas) > 0 {
+		return r.deltas[0].frame
+	}
+	return r.latestFrame
+}
+
+func (r *rewindBuffer) push(frame int, state []byte) {
+	if r.latest != nil {
+		r.deltas = append(r.deltas, snapshotDelta{
+			frame: r.latestFrame,
+			data:  diff(state, r.latest),
+		})
+	}
+	r.latest, r.latestFrame = state, frame
+
+	if n := len(r.deltas) + 1 - r.opts.Capacity; n > 0 {
+		r.deltas = r.deltas[n:]
+	}
+	if n := r.oldest() - r.first; n > 0 {
+		r.inputs = r.inputs[n:]
+		r.first += n
+	}
+}
+
+// Returns the newest snapshot taken at or before frame, and drops all that
+// come after it.
+func (r *rewindBuffer) restore(frame int) (state []byte, at int) {
+	for r.latestFrame > frame {
+		d := r.deltas[len(r.deltas)-1]
+		r.deltas = r.deltas[:len(r.deltas)-1]
+		r.latest, r.latestFrame = patch(r.latest, d.data), d.frame
+	}
+	return r.latest, r.latestFrame
+}
+
+// Encodes the difference between a and b, which have the same length, so
+// that patch(a, diff(a, b)) == b. The XOR of the two is stored as runs of
+// zeros and literal bytes, each run prefixed with its length.
+func diff(a, b []byte) []byte {
+	var out []byte
+	for i := 0; i < len(a); {
+		zeros := i
+		for zeros < len(a) && a[zeros] == b[zeros] {
+			zeros++
+		}
+		lit := zeros
+		// a single equal byte in between is cheaper to keep as a literal
+		for lit < len(a) && (a[lit] != b[lit] || lit+1 < len(a) && a[lit+1] != b[lit+1]) {
+			lit++
+		}
+		out = binary.AppendUvarint(out, uint64(zeros-i))
+		out = binary.AppendUvarint(out, uint64(lit-zeros))
+		for j := zeros; j < lit; j++ {
+			out = append(out, a[j]^b[j])
+		}
+		i = lit
+	}
+	return out
+}
+
+func patch(a, delta []byte) []byte {
+	b := bytes.Clone(a)
+	r := bytes.NewReader(delta)
+	for i := 0; r.Len() > 0; {
+		zeros, _ := binary.ReadUvarint(r)
+		lit, _ := binary.ReadUvarint(r)
+		i += int(zeros)
+		for range lit {
+			x, _ := r.ReadByte()
+			b[i] ^= x
+			i++
+		}
+	}
+	return b
+}
+
+// Keeps snapshots so the machine can be run backwards, see Rewind. Zero
+// options are taken from DefaultRewindOptions.
+func (e *Emulator) WithRewind(opts RewindOptions) *Emulator {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultRewindOptions.Interval
+	}
+	if opts.Capacity <= 0 {
+		opts.Capacity = DefaultRewindOptions.Capacity
+	}
+	e.rewind = &rewindBuffer{opts: opts, first: e.frame}
+	return e
+}
+
+// Frames completed since power on
+func (e *Emulator) Frame() int { return e.frame }
+
+// Goes back n frames, see RewindTo.
+func (e *Emulator) Rewind(n int) error {
+	return e.RewindTo(e.frame - n)
+}
+
+// Puts the machine back in the state it was in at the start of the given
+// frame: restores the nearest snapshot before it, and runs the frames in
+// between with the buttons that were held back then. Everything after the
+// frame is forgotten, including the buttons held on it: set them before
+// running on. Returns ErrRewind if rewinding isn't enabled or the frame is
+// older than the oldest snapshot.
+func (e *Emulator) RewindTo(frame int) error {
+	r := e.rewind
+	if r == nil || r.latest == nil || frame < r.oldest() || frame > e.frame {
+		return ErrRewind
+	}
+	state, at := r.restore(frame)
+	if err := e.loadState(bytes.NewReader(state)); err != nil {
+		return err
+	}
+	e.frame = at
+	r.inputs = r.inputs[:frame-r.first]
+	for e.frame < frame {
+		e.SetButtons(r.inputs[e.frame-r.first])
+		if _, err := e.runFrame(nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Takes a snapshot if one is due, and records the buttons held on this frame.
+func (e *Emulator) recordFrame() error {
+	r := e.rewind
+	if r == nil {
+		return nil
+	}
+	// after rewinding onto a snapshot, it's already there
+	if e.frame%r.opts.Interval == 0 && (r.latest == nil || r.latestFrame != e.frame) {
+		var state bytes.Buffer
+		if err := e.SaveState(&state); err != nil {
+			return err
+		}
+		r.push(e.frame, state.Bytes())
+	}
+	r.inputs = append(r.inputs, e.CPU.Mem.Buttons())
+	return nil
+}
@@ -0,0 +1,137 @@
+package gameboy
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Stores the direction buttons into WRAM over and over, so the input of every
+// frame leaves a trace.
+var inputProgram = []byte{
+	code("LD HL,n16"), 0x00, 0xC0,
+	code("LD A,n8"), 0x20, // 0x153
+	code("LDH (a8),A"), 0x00,
+	code("LDH A,(a8)"), 0x00,
+	code("LD (HL),A"),
+	code("INC L"),
+	code("JR e8"), 0xF5,
+}
+
+func TestDiff(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	for range 100 {
+		a := make([]byte, rng.IntN(200))
+		for i := range a {
+			a[i] = byte(rng.IntN(256))
+		}
+		b := bytes.Clone(a)
+		for range rng.IntN(20) {
+			if len(b) > 0 {
+				b[rng.IntN(len(b))] = byte(rng.IntN(256))
+			}
+		}
+		require.Equal(t, b, patch(a, diff(a, b)))
+		require.Equal(t, a, patch(b, diff(b, a)))
+	}
+	require.Len(t, diff(make([]byte, 1000), make([]byte, 1000)), 3, "equal input is a single run")
+}
+
+func TestRewind(t *testing.T) {
+	const FRAMES = 100
+	input := func(frame int) Buttons {
+		return Buttons(frame/3%16) << 4
+	}
+	newEmulator := func() *Emulator {
+		return NewEmulator(testROM(inputProgram), EmulatorOptions{Model: DMG, SkipBoot: true})
+	}
+
+	// the state at the start of every frame, once its buttons are pressed
+	var want [][]byte
+	emu := newEmulator()
+	for f := range FRAMES {
+		emu.SetButtons(input(f))
+		var state bytes.Buffer
+		require.NoError(t, emu.SaveState(&state))
+		want = append(want, state.Bytes())
+		require.NoError(t, emu.RunFrame())
+	}
+
+	t.Run("exact frame", func(t *testing.T) {
+		req := require.New(t)
+		emu := newEmulator().WithRewind(RewindOptions{Interval: 10, Capacity: 100})
+		for f := range FRAMES {
+			emu.SetButtons(input(f))
+			req.NoError(emu.RunFrame())
+		}
+		for _, frame := range []int{93, 90, 57, 3, 0} {
+			req.NoError(emu.RewindTo(frame))
+			req.Equal(frame, emu.Frame())
+			emu.SetButtons(input(frame))
+			var got bytes.Buffer
+			req.NoError(emu.SaveState(&got))
+			requireSameState(t, want[frame], got.Bytes(), "frame %d", frame)
+		}
+	})
+
+	t.Run("run again", func(t *testing.T) {
+		req := require.New(t)
+		emu := newEmulator().WithRewind(RewindOptions{Interval: 10, Capacity: 100})
+		for f := range 50 {
+			emu.SetButtons(input(f) ^ ButtonDown)
+			req.NoError(emu.RunFrame())
+		}
+		// a different past
+		req.NoError(emu.RewindTo(0))
+		for f := range 50 {
+			emu.SetButtons(input(f))
+			req.NoError(emu.RunFrame())
+		}
+		req.NoError(emu.Rewind(15))
+		emu.SetButtons(input(35))
+		var got bytes.Buffer
+		req.NoError(emu.SaveState(&got))
+		requireSameState(t, want[35], got.Bytes())
+	})
+
+	t.Run("capacity", func(t *testing.T) {
+		req := require.New(t)
+		emu := newEmulator().WithRewind(RewindOptions{Interval: 10, Capacity: 3})
+		for f := range FRAMES {
+			emu.SetButtons(input(f))
+			req.NoError(emu.RunFrame())
+		}
+		// snapshots at 70, 80 and 90
+		req.ErrorIs(emu.RewindTo(69), ErrRewind)
+		req.Len(emu.rewind.inputs, 30)
+		req.NoError(emu.RewindTo(75))
+		emu.SetButtons(input(75))
+		var got bytes.Buffer
+		req.NoError(emu.SaveState(&got))
+		requireSameState(t, want[75], got.Bytes())
+
+		for _, d := range emu.rewind.deltas {
+			req.Less(len(d.data), len(emu.rewind.latest)/10, "deltas are compact")
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		emu := newEmulator()
+		require.NoError(t, emu.RunFrame())
+		require.ErrorIs(t, emu.Rewind(1), ErrRewind)
+	})
+}
+
+// Like require.Equal, but points at the first difference instead of dumping
+// two 100kB states.
+func requireSameState(t *testing.T, want, got []byte, msg ...any) {
+	t.Helper()
+	require.Equal(t, len(want), len(got), msg...)
+	for i := range want {
+		if want[i] != got[i] {
+			t.Fatalf("states differ at offset %d: want %#02x, got %#02x %v", i, want[i], got[i], msg)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package gameboy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// Save states are a header followed by the state of each component, in the
+// order of machineState, little endian:
+//
+//	magic    [4]byte "GBSS"
+//	version  uint16
+//	checksum uint32  CRC-32 of the ROM the state was saved from
+//	...      cpu, memory, ppu, timer and apu; see machineState
+//	cartLen  uint32
+//	cart     [cartLen]byte  MBC registers and RAM, see cartridge.MarshalBinary
+//
+// Bump STATE_VERSION whenever the layout changes; older states are rejected
+// rather than misread.
+const STATE_VERSION = 1
+
+var stateMagic = [4]byte{'G', 'B', 'S', 'S'}
+
+var (
+	ErrStateFormat  = errors.New("not a save state")
+	ErrStateVersion = errors.New("unsupported save state version")
+	ErrStateROM     = errors.New("save state is for a different ROM")
+)
+
+type stateHeader struct {
+	Magic    [4]byte
+	Version  uint16
+	Checksum uint32
+}
+
+// The fields are fixed size so encoding/binary can handle them.
+type machineState struct {
+	CPU struct {
+		A, F, B, C, D, E, H, L uint8
+		PC, SP                 uint16
+		Prefix, IME, Halted    bool
+		Cycles, InstrCount     int64
+	}
+	Memory struct {
+		Model      uint8
+		Buttons    uint8
+		APUTrigger uint8
+		Data       [64 * 1024]byte
+	}
+	PPU struct {
+		Prev, Line  int64
+		Back, Front Frame
+		WindowLine  int64
+		VBlank      bool
+	}
+	Timer struct {
+		Prev, Div, TIMA int64
+	}
+	APU struct {
+		Prev, SeqTimer, SeqStep, SampleTimer int64
+		Channels                             [4]struct {
+			Enabled          bool
+			Timer, Pos       int64
+			Volume           uint8
+			EnvTimer, Length int64
+			LFSR             uint16
+		}
+	}
+}
+
+// Writes the complete machine state, see LoadState.
+func (e *Emulator) SaveState(w io.Writer) error {
+	cpu, mem := e.CPU, e.CPU.Mem
+	var s machineState
+
+	s.CPU.A, s.CPU.F = cpu.A, uint8(cpu.F)
+	s.CPU.B, s.CPU.C = cpu.B, cpu.C
+	s.CPU.D, s.CPU.E = cpu.D, cpu.E
+	s.CPU.H, s.CPU.L = cpu.H, cpu.L
+	s.CPU.PC, s.CPU.SP = cpu.PC, cpu.SP
+	s.CPU.Prefix, s.CPU.IME, s.CPU.Halted = cpu.prefix, cpu.ime, cpu.halted
+	s.CPU.Cycles, s.CPU.InstrCount = int64(cpu.Cycles), int64(cpu.InstrCount)
+
+	s.Memory.Model = uint8(mem.model)
+	s.Memory.Buttons = uint8(mem.buttons)
+	s.Memory.APUTrigger = mem.apuTrigger
+	copy(s.Memory.Data[:], mem.data)
+
+	ppu := &cpu.ppu
+	s.PPU.Prev, s.PPU.Line = int64(ppu.prev), int64(ppu.line)
+	s.PPU.Back, s.PPU.Front = ppu.back, ppu.front
+	s.PPU.WindowLine, s.PPU.VBlank = int64(ppu.windowLine), ppu.vblank
+
+	timer := &cpu.timer
+	s.Timer.Prev, s.Timer.Div, s.Timer.TIMA = int64(timer.prev), int64(timer.div), int64(timer.tima)
+
+	apu := &cpu.apu
+	s.APU.Prev, s.APU.SeqTimer = int64(apu.prev), int64(apu.seqTimer)
+	s.APU.SeqStep, s.APU.SampleTimer = int64(apu.seqStep), int64(apu.sampleTimer)
+	for i, ch := range apu.ch {
+		c := &s.APU.Channels[i]
+		c.Enabled, c.Timer, c.Pos = ch.enabled, int64(ch.timer), int64(ch.pos)
+		c.Volume, c.EnvTimer, c.Length = ch.volume, int64(ch.envTimer), int64(ch.length)
+		c.LFSR = ch.lfsr
+	}
+
+	cart, err := mem.cart.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	header := stateHeader{
+		Magic:    stateMagic,
+		Version:  STATE_VERSION,
+		Checksum: crc32.ChecksumIEEE(e.rom),
+	}
+	for _, v := range []any{header, &s, uint32(len(cart)), cart} {
+		if err := binary.Write(w, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Restores a state written by SaveState. The state must come from the same
+// ROM; if anything is wrong with it, the machine is left untouched. The
+// machine is restored in place, so the cpu keeps its hooks. The rewind
+// snapshots are dropped, as they belong to another timeline.
+func (e *Emulator) LoadState(r io.Reader) error {
+	if err := e.loadState(r); err != nil {
+		return err
+	}
+	if e.rewind != nil {
+		e.WithRewind(e.rewind.opts)
+	}
+	return nil
+}
+
+func (e *Emulator) loadState(r io.Reader) error {
+	var header stateHeader
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return fmt.Errorf("%w: %w", ErrStateFormat, err)
+	}
+	switch {
+	case header.Magic != stateMagic:
+		return ErrStateFormat
+	case header.Version != STATE_VERSION:
+		return fmt.Errorf("%w: %d", ErrStateVersion, header.Version)
+	case header.Checksum != crc32.ChecksumIEEE(e.rom):
+		return ErrStateROM
+	}
+
+	var (
+		s       machineState
+		cartLen uint32
+	)
+	if err := binary.Read(r, binary.LittleEndian, &s); err != nil {
+		return fmt.Errorf("%w: %w", ErrStateFormat, err)
+	}
+	if err := binary.Read(r, binary.LittleEndian, &cartLen); err != nil {
+		return fmt.Errorf("%w: %w", ErrStateFormat, err)
+	}
+	var cart bytes.Buffer
+	if _, err := io.CopyN(&cart, r, int64(cartLen)); err != nil {
+		return fmt.Errorf("%w: %w", ErrStateFormat, err)
+	}
+
+	// The cartridge goes first: it's the only part that can still fail, and
+	// it's left untouched if it does.
+	cpu, mem := e.CPU, e.CPU.Mem
+	if err := mem.cart.UnmarshalBinary(cart.Bytes()); err != nil {
+		return err
+	}
+	cpu.err, cpu.calls = nil, nil
+	e.midFrame = false
+
+	cpu.A, cpu.F = s.CPU.A, FlagRegister(s.CPU.F)
+	cpu.B, cpu.C = s.CPU.B, s.CPU.C
+	cpu.D, cpu.E = s.CPU.D, s.CPU.E
+	cpu.H, cpu.L = s.CPU.H, s.CPU.L
+	cpu.PC, cpu.SP = s.CPU.PC, s.CPU.SP
+	cpu.prefix, cpu.ime, cpu.halted = s.CPU.Prefix, s.CPU.IME, s.CPU.Halted
+	cpu.Cycles, cpu.InstrCount = int(s.CPU.Cycles), int(s.CPU.InstrCount)
+
+	mem.model = Model(s.Memory.Model)
+	mem.buttons = Buttons(s.Memory.Buttons)
+	mem.apuTrigger = s.Memory.APUTrigger
+	copy(mem.data, s.Memory.Data[:])
+
+	ppu := &cpu.ppu
+	ppu.prev, ppu.line = int(s.PPU.Prev), int(s.PPU.Line)
+	ppu.back, ppu.front = s.PPU.Back, s.PPU.Front
+	ppu.windowLine, ppu.vblank = int(s.PPU.WindowLine), s.PPU.VBlank
+
+	timer := &cpu.timer
+	timer.prev, timer.div, timer.tima = int(s.Timer.Prev), int(s.Timer.Div), int(s.Timer.TIMA)
+
+	apu := &cpu.apu
+	apu.prev, apu.seqTimer = int(s.APU.Prev), int(s.APU.SeqTimer)
+	apu.seqStep, apu.sampleTimer = int(s.APU.SeqStep), int(s.APU.SampleTimer)
+	apu.samples = nil
+	for i, c := range s.APU.Channels {
+		apu.ch[i] = channel{
+			enabled:  c.Enabled,
+			timer:    int(c.Timer),
+			pos:      int(c.Pos),
+			volume:   c.Volume,
+			envTimer: int(c.EnvTimer),
+			length:   int(c.Length),
+			lfsr:     c.LFSR,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package gameboy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Keeps changing memory, scrolling and running the timer, so that a state
+// restored at the wrong point shows.
+var busyProgram = []byte{
+	code("LD A,n8"), 0x05,
+	code("LDH (a8),A"), 0x07, // timer on
+	code("INC A"), // 0x154
+	code("LD (a16),A"), 0x00, 0xC0,
+	code("LDH (a8),A"), 0x42, // SCY
+	code("JR e8"), 0xF8,
+}
+
+func TestSaveState(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		req := require.New(t)
+		emu := NewEmulator(testROM(busyProgram), EmulatorOptions{Model: DMG, SkipBoot: true})
+		for range 3 {
+			req.NoError(emu.RunFrame())
+		}
+		var state bytes.Buffer
+		req.NoError(emu.SaveState(&state))
+		saved := bytes.Clone(state.Bytes())
+
+		run := func() (CPU, []byte, Frame) {
+			for range 5 {
+				req.NoError(emu.RunFrame())
+			}
+			emu.AudioSamples()
+			cpu := *emu.CPU
+			cpu.hooks, cpu.Mem = nil, nil
+			return cpu, bytes.Clone(emu.Memory().data), emu.Framebuffer()
+		}
+		wantCPU, wantMem, wantFrame := run()
+
+		req.NoError(emu.LoadState(&state))
+		gotCPU, gotMem, gotFrame := run()
+		req.Equal(wantCPU, gotCPU)
+		req.Equal(wantMem, gotMem)
+		req.Equal(wantFrame, gotFrame)
+
+		// loading into a new machine works the same
+		emu = NewEmulator(testROM(busyProgram), EmulatorOptions{Model: DMG, SkipBoot: true})
+		req.NoError(emu.LoadState(bytes.NewReader(saved)))
+		gotCPU, gotMem, _ = run()
+		req.Equal(wantCPU, gotCPU)
+		req.Equal(wantMem, gotMem)
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		emu := NewEmulator(testROM(busyProgram), EmulatorOptions{Model: DMG, SkipBoot: true})
+		require.NoError(t, emu.RunFrame())
+		var state bytes.Buffer
+		require.NoError(t, emu.SaveState(&state))
+
+		cases := []struct {
+			desc    string
+			rom     []byte
+			modify  func(b []byte) []byte
+			wantErr error
+		}{
+			{desc: "other ROM", rom: testROM(loopProgram), wantErr: ErrStateROM},
+			{desc: "magic", modify: func(b []byte) []byte { b[0] = 'X'; return b }, wantErr: ErrStateFormat},
+			{desc: "version", modify: func(b []byte) []byte {
+				binary.LittleEndian.PutUint16(b[4:], STATE_VERSION+1)
+				return b
+			}, wantErr: ErrStateVersion},
+			{desc: "truncated", modify: func(b []byte) []byte { return b[:1000] }, wantErr: ErrStateFormat},
+			{desc: "empty", modify: func(b []byte) []byte { return nil }, wantErr: ErrStateFormat},
+		}
+		for _, tc := range cases {
+			t.Run(tc.desc, func(t *testing.T) {
+				req := require.New(t)
+				rom := tc.rom
+				if rom == nil {
+					rom = testROM(busyProgram)
+				}
+				b := bytes.Clone(state.Bytes())
+				if tc.modify != nil {
+					b = tc.modify(b)
+				}
+				other := NewEmulator(rom, EmulatorOptions{Model: DMG, SkipBoot: true})
+				req.ErrorIs(other.LoadState(bytes.NewReader(b)), tc.wantErr)
+				req.Equal(uint16(0x0100), other.CPU.PC, "left untouched")
+			})
+		}
+	})
+}
@@ -1,12 +1,31 @@
 package gameboy
 
-import "fmt"
+import "io"
 
-func checkSerial(mem *Memory) (byte, bool) {
-	b := mem.Read(0xFF01)
-	sc := mem.Read(0xFF02)
-	if b > 0 {
-		fmt.Printf("yay b=%x sc=%x\n", b, sc)
+// https://gbdev.io/pandocs/Serial_Data_Transfer_(Link_Cable).html
+const (
+	ADDR_SB = 0xFF01 // Serial transfer data
+	ADDR_SC = 0xFF02 // Serial transfer control
+	ADDR_IF = 0xFF0F // Interrupt flag
+)
+
+// Attach a writer that receives every byte shifted out over the link port.
+// Test ROMs (e.g. Blargg's) print their results this way.
+func (m *Memory) WithSerial(w io.Writer) *Memory {
+	m.serial = w
+	return m
+}
+
+// Writing 0x81 to SC starts a transfer using the internal clock. Nobody is
+// connected on the other end, so we finish the transfer immediately: the byte
+// in SB is handed to the serial writer, SB reads back 0xFF (no peer), bit 7 of
+// SC is cleared and the serial interrupt is requested.
+func (m *Memory) transferSerial() {
+	b := m.data[ADDR_SB]
+	if m.serial != nil {
+		m.serial.Write([]byte{b})
 	}
-	return b, sc>>7 == 1
+	m.data[ADDR_SB] = 0xFF
+	m.data[ADDR_SC] &^= 0x80
+	m.data[ADDR_IF] |= 0x08
 }
@@ -0,0 +1,185 @@
+package gameboy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const SM83_DIR = "testdata/sm83"
+
+// A single test vector in the SM83 "single step" format: the state before and
+// after running one instruction, and the bus activity for each M-cycle.
+// https://github.com/SingleStepTests/sm83
+type sm83Vector struct {
+	Name    string            `json:"name"`
+	Initial sm83State         `json:"initial"`
+	Final   sm83State         `json:"final"`
+	Cycles  []json.RawMessage `json:"cycles"`
+}
+
+type sm83State struct {
+	PC  uint16 `json:"pc"`
+	SP  uint16 `json:"sp"`
+	A   uint8  `json:"a"`
+	B   uint8  `json:"b"`
+	C   uint8  `json:"c"`
+	D   uint8  `json:"d"`
+	E   uint8  `json:"e"`
+	F   uint8  `json:"f"`
+	H   uint8  `json:"h"`
+	L   uint8  `json:"l"`
+	IME uint8  `json:"ime"`
+	// pairs of [address, value]
+	RAM [][2]uint16 `json:"ram"`
+}
+
+// Runs the vector on a flat memory, and returns a description of everything
+// that differs from the expected final state.
+func runVector(v sm83Vector) (diffs []string) {
+	in := v.Initial
+	mem := NewFlatMemory()
+	for _, kv := range in.RAM {
+		mem.WriteAt(kv[0], uint8(kv[1]))
+	}
+	cpu := &CPU{
+		Mem: mem,
+		A:   in.A, F: Flags(in.F),
+		B: in.B, C: in.C,
+		D: in.D, E: in.E,
+		H: in.H, L: in.L,
+		PC: in.PC, SP: in.SP,
+		ime: in.IME == 1,
+		log: logger(),
+	}
+
+	defer func() {
+		if err := recover(); err != nil {
+			diffs = append(diffs, fmt.Sprintf("panic: %v", err))
+		}
+	}()
+
+	// CB-prefixed instructions take two steps: the prefix and the instruction
+	ok := cpu.Step()
+	if ok && cpu.prefix {
+		ok = cpu.Step()
+	}
+	if !ok {
+		diffs = append(diffs, fmt.Sprintf("step failed: %v", cpu.Err()))
+	}
+
+	want := v.Final
+	check := func(name string, want, got any) {
+		if want != got {
+			diffs = append(diffs, fmt.Sprintf("%s: want=%#x got=%#x", name, want, got))
+		}
+	}
+	check("A", want.A, cpu.A)
+	check("F", want.F, uint8(cpu.F))
+	check("B", want.B, cpu.B)
+	check("C", want.C, cpu.C)
+	check("D", want.D, cpu.D)
+	check("E", want.E, cpu.E)
+	check("H", want.H, cpu.H)
+	check("L", want.L, cpu.L)
+	check("PC", want.PC, cpu.PC)
+	check("SP", want.SP, cpu.SP)
+	check("IME", want.IME == 1, cpu.ime)
+	for _, kv := range want.RAM {
+		check(fmt.Sprintf("mem[%#04x]", kv[0]), uint8(kv[1]), mem.Read(kv[0]))
+	}
+	check("cycles", 4*len(v.Cycles), cpu.Cycles)
+	return diffs
+}
+
+// Runs every vector file found below testdata/sm83, one subtest per opcode.
+func TestSingleStep(t *testing.T) {
+	files := map[string]string{} // opcode name -> path
+	filepath.WalkDir(SM83_DIR, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && filepath.Ext(path) == ".json" {
+			files[strings.TrimSuffix(d.Name(), ".json")] = path
+		}
+		return nil
+	})
+	if len(files) == 0 {
+		t.Skipf("no test vectors in %s", SM83_DIR)
+	}
+
+	// report opcodes we implement, but have no vectors for
+	var missing []string
+	for code, instr := range ops {
+		if _, ok := instr.(PREFIX_CB); ok || strings.HasPrefix(instr.String(), "ILLEGAL") {
+			continue
+		}
+		if _, ok := files[fmt.Sprintf("%02x", code)]; !ok {
+			missing = append(missing, fmt.Sprintf("%02x", code))
+		}
+	}
+	for code := range extOps {
+		if _, ok := files[fmt.Sprintf("cb %02x", code)]; !ok {
+			missing = append(missing, fmt.Sprintf("cb %02x", code))
+		}
+	}
+	if len(missing) > 0 {
+		t.Logf("no vectors for %d opcodes: %v", len(missing), missing)
+	}
+
+	for name, path := range files {
+		t.Run(name, func(t *testing.T) {
+			b, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var vectors []sm83Vector
+			if err := json.Unmarshal(b, &vectors); err != nil {
+				t.Fatalf("failed to parse %s: %v", path, err)
+			}
+
+			// one broken opcode tends to fail every vector; only show a few
+			const MAX_REPORTED = 3
+			var failed int
+			for _, v := range vectors {
+				diffs := runVector(v)
+				if len(diffs) == 0 {
+					continue
+				}
+				if failed++; failed <= MAX_REPORTED {
+					t.Errorf("%s:\n\t%s", v.Name, strings.Join(diffs, "\n\t"))
+				}
+			}
+			if failed > 0 {
+				t.Errorf("%d/%d vectors failed", failed, len(vectors))
+			}
+		})
+	}
+}
+
+func TestRunVector(t *testing.T) {
+	req := require.New(t)
+	var v sm83Vector
+	err := json.Unmarshal([]byte(`{
+		"name": "3e 0001",
+		"initial": {
+			"pc": 4660, "sp": 65534, "a": 0, "b": 1, "c": 2, "d": 3, "e": 4,
+			"f": 0, "h": 5, "l": 6, "ime": 0, "ie": 0,
+			"ram": [[4660, 62], [4661, 171]]
+		},
+		"final": {
+			"pc": 4662, "sp": 65534, "a": 171, "b": 1, "c": 2, "d": 3, "e": 4,
+			"f": 0, "h": 5, "l": 6, "ime": 0,
+			"ram": [[4660, 62], [4661, 171]]
+		},
+		"cycles": [[4661, 171, "r-m"], [4662, 0, "r-m"]]
+	}`), &v)
+	req.NoError(err)
+	req.Empty(runVector(v))
+
+	v.Final.A = 0xAC
+	req.Equal([]string{"A: want=0xac got=0xab"}, runVector(v))
+}
@@ -0,0 +1,268 @@
+package gameboy
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrSymbols  = errors.New("no symbols found")
+	ErrLocation = errors.New("unknown label or address")
+)
+
+// A label, or a place in the ROM given relative to one.
+type Symbol struct {
+	Name string
+	Bank int
+	Addr uint16
+}
+
+// The labels of a ROM, as written by rgblink with -n game.sym or -m game.map.
+// A nil *Symbols has no labels.
+type Symbols struct {
+	byName map[string]Symbol
+	// sorted by address
+	byArea map[area][]Symbol
+}
+
+// A part of the address space that labels are relative to: the ROM bank at
+// 0x4000-0x7FFF is told apart by its number, the other areas aren't banked.
+type area struct {
+	bank  int
+	start uint16
+}
+
+// ROM0, ROMX, VRAM, SRAM, WRAM0, WRAMX, echo RAM, OAM, unusable, IO, HRAM, IE
+var areaStarts = []uint16{0x0000, 0x4000, 0x8000, 0xA000, 0xC000, 0xD000, 0xE000, 0xFE00, 0xFEA0, 0xFF00, 0xFF80, 0xFFFF}
+
+func areaOf(bank int, addr uint16) area {
+	i := sort.Search(len(areaStarts), func(i int) bool { return areaStarts[i] > addr }) - 1
+	a := area{start: areaStarts[i]}
+	if a.start == 0x4000 {
+		a.bank = bank
+	}
+	return a
+}
+
+var (
+	// .sym: "01:4000 Main.loop"
+	symLine = regexp.MustCompile(`^([0-9A-Fa-f]+):([0-9A-Fa-f]{4})\s+(\S+)$`)
+	// .map: "ROMX bank #1:", followed by "$4000 = Main.loop" lines
+	mapBank   = regexp.MustCompile(`(?i)\bbank #(\d+)`)
+	mapSymbol = regexp.MustCompile(`^\$([0-9A-Fa-f]{4}) = (\S+)$`)
+)
+
+func LoadSymbols(path string) (*Symbols, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	syms, err := ReadSymbols(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return syms, nil
+}
+
+// Reads a .sym or a .map file; lines that aren't labels are skipped.
+func ReadSymbols(r io.Reader) (*Symbols, error) {
+	syms := &Symbols{byName: make(map[string]Symbol)}
+	bank := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if m := symLine.FindStringSubmatch(line); m != nil {
+			b, _ := strconv.ParseUint(m[1], 16, 16)
+			addr, _ := strconv.ParseUint(m[2], 16, 16)
+			syms.add(Symbol{Name: m[3], Bank: int(b), Addr: uint16(addr)})
+		} else if m := mapBank.FindStringSubmatch(line); m != nil {
+			bank, _ = strconv.Atoi(m[1])
+		} else if m := mapSymbol.FindStringSubmatch(line); m != nil {
+			addr, _ := strconv.ParseUint(m[1], 16, 16)
+			syms.add(Symbol{Name: m[2], Bank: bank, Addr: uint16(addr)})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(syms.byName) == 0 {
+		return nil, ErrSymbols
+	}
+	for _, list := range syms.byArea {
+		// the first label listed names an address
+		sort.SliceStable(list, func(i, j int) bool { return list[i].Addr < list[j].Addr })
+	}
+	return syms, nil
+}
+
+func (s *Symbols) add(sym Symbol) {
+	if _, dup := s.byName[sym.Name]; dup {
+		return
+	}
+	s.byName[sym.Name] = sym
+	if s.byArea == nil {
+		s.byArea = make(map[area][]Symbol)
+	}
+	a := areaOf(sym.Bank, sym.Addr)
+	s.byArea[a] = append(s.byArea[a], sym)
+}
+
+// Finds a label by its full name, e.g. "Main" or "Main.loop".
+func (s *Symbols) Lookup(name string) (Symbol, bool) {
+	if s == nil {
+		return Symbol{}, false
+	}
+	sym, ok := s.byName[name]
+	return sym, ok
+}
+
+// Names addr in bank relative to the closest label at or before it, e.g.
+// "Main.loop+3". The bank only matters for 0x4000-0x7FFF, see Memory.Bank.
+// Labels don't reach into other areas of memory, e.g. from ROM0 into ROMX.
+func (s *Symbols) Resolve(bank int, addr uint16) (Symbol, bool) {
+	if s == nil {
+		return Symbol{}, false
+	}
+	syms := s.byArea[areaOf(bank, addr)]
+	i := sort.Search(len(syms), func(i int) bool { return syms[i].Addr > addr }) - 1
+	if i < 0 {
+		return Symbol{}, false
+	}
+	for i > 0 && syms[i-1].Addr == syms[i].Addr {
+		i--
+	}
+	return syms[i].plus(int(addr - syms[i].Addr)), true
+}
+
+// Like Resolve, but falls back to the address, e.g. "01:4003".
+func (s *Symbols) Format(bank int, addr uint16) string {
+	if sym, ok := s.Resolve(bank, addr); ok {
+		return sym.Name
+	}
+	return fmt.Sprintf("%02X:%04X", bank, addr)
+}
+
+// Names the addresses as seen through mem, i.e. in the ROM bank it has
+// mapped, for disasm.Instruction.Format. Gives "" for addresses without a
+// label before them.
+func (s *Symbols) Labeler(mem *Memory) func(addr uint16) string {
+	return func(addr uint16) string {
+		sym, _ := s.Resolve(mem.Bank(addr), addr)
+		return sym.Name
+	}
+}
+
+// Parses a location as given to a debugger: a label, with an optional
+// offset as in "Main.loop+3", or an address, written as "$0150", "0x0150" or
+// with a bank as in "02:4000". Addresses without a bank are in ANY_BANK.
+func (s *Symbols) Locate(spec string) (Symbol, error) {
+	spec = strings.TrimSpace(spec)
+	name, offset, hasOffset := strings.Cut(spec, "+")
+	if sym, ok := s.Lookup(name); ok {
+		if !hasOffset {
+			return sym, nil
+		}
+		n, err := strconv.ParseUint(offset, 0, 16)
+		if err != nil {
+			return Symbol{}, fmt.Errorf("%w: %q", ErrLocation, spec)
+		}
+		return sym.plus(int(n)), nil
+	}
+
+	bank, addr := ANY_BANK, spec
+	if b, a, ok := strings.Cut(spec, ":"); ok {
+		n, err := strconv.ParseUint(b, 16, 16)
+		if err != nil {
+			return Symbol{}, fmt.Errorf("%w: %q", ErrLocation, spec)
+		}
+		bank, addr = int(n), strings.TrimPrefix(a, "$")
+	} else if strings.HasPrefix(addr, "$") {
+		addr = addr[1:]
+	} else if !strings.HasPrefix(addr, "0x") {
+		// otherwise labels such as "Add" would be addresses
+		return Symbol{}, fmt.Errorf("%w: %q", ErrLocation, spec)
+	}
+	n, err := strconv.ParseUint(strings.TrimPrefix(addr, "0x"), 16, 16)
+	if err != nil {
+		return Symbol{}, fmt.Errorf("%w: %q", ErrLocation, spec)
+	}
+	return Symbol{Name: spec, Bank: bank, Addr: uint16(n)}, nil
+}
+
+// Maps the lines of an rgbds source file to where they were assembled: the
+// address of the label before a line, from syms, plus the sizes of the
+// statements in between. Lines after a statement that can't be sized, e.g. a
+// macro, are left out up to the next label. The names are label+offset.
+func LineAddresses(src string, syms *Symbols) map[int]Symbol {
+	lines := make(map[int]Symbol)
+	var (
+		label  Symbol // the label the following lines are relative to
+		known  bool   // whether label is in syms
+		offset int
+		scope  string
+	)
+	for i, text := range strings.Split(src, "\n") {
+		line := strings.TrimSpace(stripComment(text))
+		labelled := false
+		for {
+			name, rest, ok := cutLabel(line)
+			if !ok {
+				break
+			}
+			if !strings.HasPrefix(name, ".") {
+				scope = name
+			}
+			label, known = syms.Lookup(qualify(name, scope))
+			offset, labelled = 0, true
+			line = strings.TrimSpace(rest)
+		}
+		if !known {
+			continue
+		}
+		if strings.HasPrefix(strings.ToUpper(line), "SECTION") {
+			known = false
+			continue
+		}
+		size, err := lineSize(line)
+		if err != nil {
+			known = false
+			continue
+		}
+		if line != "" || labelled {
+			lines[i+1] = label.plus(offset)
+		}
+		offset += size
+	}
+	return lines
+}
+
+func (sym Symbol) plus(offset int) Symbol {
+	if offset == 0 {
+		return sym
+	}
+	return Symbol{Name: fmt.Sprintf("%s+%d", sym.Name, offset), Bank: sym.Bank, Addr: sym.Addr + uint16(offset)}
+}
+
+// The number of bytes a statement assembles to, without resolving the labels
+// it refers to.
+func lineSize(line string) (int, error) {
+	a := &assembler{labels: make(map[string]int)}
+	if err := a.parse(line); err != nil {
+		return 0, err
+	}
+	size := 0
+	for _, sec := range a.sections {
+		for _, s := range sec.statements {
+			size += s.size
+		}
+	}
+	return size, nil
+}
@@ -0,0 +1,130 @@
+package gameboy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadSymbols(t *testing.T) {
+	t.Run("sym", func(t *testing.T) {
+		req := require.New(t)
+		syms, err := ReadSymbols(strings.NewReader(`; File generated by rgblink
+00:0150 Main
+00:0152 Main.loop
+02:4000 Banked
+00:c000 wCounter
+`))
+		req.NoError(err)
+		sym, ok := syms.Lookup("Main.loop")
+		req.True(ok)
+		req.Equal(Symbol{Name: "Main.loop", Bank: 0, Addr: 0x0152}, sym)
+		sym, _ = syms.Lookup("Banked")
+		req.Equal(2, sym.Bank)
+		_, ok = syms.Lookup("Nowhere")
+		req.False(ok)
+	})
+
+	t.Run("map", func(t *testing.T) {
+		req := require.New(t)
+		syms, err := ReadSymbols(strings.NewReader(`ROM0 bank #0:
+	SECTION: $0150-$0160 ($0011 bytes) ["main"]
+	         $0150 = Main
+	         $0152 = Main.loop
+	EMPTY: $0161-$3fff ($3e9f bytes)
+
+ROMX bank #2:
+	SECTION: $4000-$4001 ($0002 bytes) ["banked"]
+	         $4000 = Banked
+`))
+		req.NoError(err)
+		sym, _ := syms.Lookup("Main.loop")
+		req.Equal(Symbol{Name: "Main.loop", Bank: 0, Addr: 0x0152}, sym)
+		sym, _ = syms.Lookup("Banked")
+		req.Equal(Symbol{Name: "Banked", Bank: 2, Addr: 0x4000}, sym)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		_, err := ReadSymbols(strings.NewReader("; nothing\n"))
+		require.ErrorIs(t, err, ErrSymbols)
+	})
+}
+
+func TestLineAddresses(t *testing.T) {
+	req := require.New(t)
+	syms, err := ReadSymbols(strings.NewReader("00:0150 Main\n00:0152 Main.loop\n"))
+	req.NoError(err)
+	lines := LineAddresses(`INCLUDE "hardware.inc"
+SECTION "main", ROM0[$0150]
+Main:
+	ld a, 0
+
+.loop:  inc a
+	call Store       ; a label defined elsewhere
+	MY_MACRO
+	jr .loop
+`, syms)
+	req.Equal(map[int]Symbol{
+		3: {Name: "Main", Addr: 0x0150},
+		4: {Name: "Main", Addr: 0x0150},
+		6: {Name: "Main.loop", Addr: 0x0152},
+		7: {Name: "Main.loop+1", Addr: 0x0153},
+		// the size of the macro is unknown
+	}, lines)
+}
+
+func TestResolve(t *testing.T) {
+	req := require.New(t)
+	syms, err := ReadSymbols(strings.NewReader(`00:0150 Main
+00:0150 Main.start
+00:0152 Main.loop
+01:4000 BankOne
+02:4000 BankTwo
+00:c000 wCounter
+`))
+	req.NoError(err)
+
+	sym, ok := syms.Resolve(0, 0x0150)
+	req.True(ok)
+	req.Equal(Symbol{Name: "Main", Addr: 0x0150}, sym, "the first label listed")
+	sym, _ = syms.Resolve(0, 0x0155)
+	req.Equal(Symbol{Name: "Main.loop+3", Addr: 0x0155}, sym)
+
+	sym, _ = syms.Resolve(2, 0x4010)
+	req.Equal(Symbol{Name: "BankTwo+16", Bank: 2, Addr: 0x4010}, sym)
+	_, ok = syms.Resolve(3, 0x4010)
+	req.False(ok, "no labels in bank 3")
+	_, ok = syms.Resolve(0, 0x0100)
+	req.False(ok)
+	_, ok = syms.Resolve(0, 0xFF44)
+	req.False(ok, "WRAM labels don't reach IO")
+
+	req.Equal("wCounter+1", syms.Format(0, 0xC001))
+	req.Equal("03:4010", syms.Format(3, 0x4010))
+
+	var none *Symbols
+	req.Equal("00:0150", none.Format(0, 0x0150))
+}
+
+func TestLocate(t *testing.T) {
+	syms, err := ReadSymbols(strings.NewReader("00:0152 Main.loop\n02:4000 Add\n"))
+	require.NoError(t, err)
+	for spec, want := range map[string]Symbol{
+		"Main.loop":   {Name: "Main.loop", Addr: 0x0152},
+		"Main.loop+3": {Name: "Main.loop+3", Addr: 0x0155},
+		"Add":         {Name: "Add", Bank: 2, Addr: 0x4000},
+		"$0150":       {Name: "$0150", Bank: ANY_BANK, Addr: 0x0150},
+		"0x0150":      {Name: "0x0150", Bank: ANY_BANK, Addr: 0x0150},
+		"02:4000":     {Name: "02:4000", Bank: 2, Addr: 0x4000},
+		"0a:$4000":    {Name: "0a:$4000", Bank: 10, Addr: 0x4000},
+	} {
+		sym, err := syms.Locate(spec)
+		require.NoError(t, err, spec)
+		require.Equal(t, want, sym, spec)
+	}
+	for _, spec := range []string{"Nowhere", "0150", "Main.loop+x", "$10000"} {
+		_, err := syms.Locate(spec)
+		require.ErrorIs(t, err, ErrLocation, spec)
+	}
+}
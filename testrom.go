@@ -0,0 +1,249 @@
+package gameboy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Blargg's test ROMs report their results as text. It is written both to the
+// serial port and to the background tilemap, and ends with either "Passed" or
+// "Failed".
+// https://github.com/retrio/gb-test-roms
+
+// Where to read the text printed by a test ROM from.
+type TextSource int
+
+const (
+	SourceSerial TextSource = iota // bytes shifted out over the link port
+	SourceScreen                   // characters in the background tilemap
+)
+
+type BlarggOptions struct {
+	// Where to read the output from. Defaults to serial.
+	Source TextSource
+	// Give up once this many cycles have run. Zero means no limit, which is
+	// only safe if the ROM is known to terminate.
+	MaxCycles int
+	// Optional logger passed on to the cpu
+	Log *slog.Logger
+}
+
+// The result of a single subtest, as printed by the multi-ROMs such as
+// cpu_instrs.gb: "01:ok  02:ok  03:01 ...". Code is "ok" when the subtest
+// passed and the failure code otherwise.
+type BlarggSection struct {
+	ID     int
+	Code   string
+	Passed bool
+}
+
+type BlarggResult struct {
+	Passed bool
+	// Whether "Passed" or "Failed" was seen before the cpu stopped
+	Finished bool
+	// Everything printed by the ROM so far
+	Output   string
+	Sections []BlarggSection
+	Cycles   int
+	// Set if the cpu stopped (or panicked) before the ROM reported a result
+	Err error
+}
+
+// Returns the section with the given id, e.g. 2 for "02-interrupts".
+func (r BlarggResult) Section(id int) (BlarggSection, bool) {
+	for _, s := range r.Sections {
+		if s.ID == id {
+			return s, true
+		}
+	}
+	return BlarggSection{}, false
+}
+
+// Runs a Blargg test ROM without a screen until it reports "Passed" or
+// "Failed", the cpu stops, or the cycle budget is spent.
+func RunBlargg(rom []byte, opts BlarggOptions) (res BlarggResult) {
+	var serial bytes.Buffer
+	emu := NewEmulator(rom, EmulatorOptions{
+		Model:    DMG,
+		SkipBoot: true,
+		Serial:   &serial,
+		Log:      opts.Log,
+	})
+	cpu, mem := emu.CPU, emu.Memory()
+
+	output := func() string {
+		if opts.Source == SourceScreen {
+			return ScreenText(mem)
+		}
+		return serial.String()
+	}
+	defer func() {
+		if err := recover(); err != nil {
+			res.Err = fmt.Errorf("panic at %#04x: %v", cpu.PC, err)
+		}
+		res.Cycles = cpu.Cycles
+		res.Output = output()
+		res.Sections = parseBlarggSections(res.Output)
+		res.Finished, res.Passed = blarggVerdict(res.Output)
+		if !res.Finished && res.Err == nil {
+			res.Err = fmt.Errorf("no result after %d cycles", cpu.Cycles)
+		}
+	}()
+
+	// The screen is only checked once per frame; reading the tilemap on every
+	// instruction is needlessly slow.
+	var (
+		seen      int
+		nextCheck int
+	)
+	for opts.MaxCycles == 0 || cpu.Cycles < opts.MaxCycles {
+		if !cpu.Step() {
+			res.Err = cpu.Err()
+			return
+		}
+		switch opts.Source {
+		case SourceSerial:
+			if serial.Len() == seen {
+				continue
+			}
+			seen = serial.Len()
+		case SourceScreen:
+			if cpu.Cycles < nextCheck {
+				continue
+			}
+			nextCheck = cpu.Cycles + CYCLES_PER_FRAME
+		}
+		if finished, _ := blarggVerdict(output()); finished {
+			return
+		}
+	}
+	return
+}
+
+// Reads the visible part of the background tilemap as text. The Blargg ROMs
+// load their font so that the tile index equals the ASCII code, so anything
+// outside the printable range is shown as a space.
+func ScreenText(mem *Memory) string {
+	const (
+		ROWS = 18
+		COLS = 20
+	)
+	view := mem.VRAM().TileView1
+	if bit(byte(mem.LCDC()), 3) == 1 {
+		view = mem.VRAM().TileView2
+	}
+	scx, scy := int(mem.SCX())/8, int(mem.SCY())/8
+
+	var lines []string
+	for y := range ROWS {
+		var line strings.Builder
+		for x := range COLS {
+			c := view[((scy+y)%32)*32+(scx+x)%32]
+			if c < 0x20 || c > 0x7E {
+				c = ' '
+			}
+			line.WriteByte(c)
+		}
+		lines = append(lines, strings.TrimRight(line.String(), " "))
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
+
+var blarggSection = regexp.MustCompile(`(\d\d):(ok|\d\d)`)
+
+func parseBlarggSections(s string) []BlarggSection {
+	var sections []BlarggSection
+	for _, m := range blarggSection.FindAllStringSubmatch(s, -1) {
+		id, _ := strconv.Atoi(m[1])
+		sections = append(sections, BlarggSection{
+			ID:     id,
+			Code:   m[2],
+			Passed: m[2] == "ok",
+		})
+	}
+	return sections
+}
+
+func blarggVerdict(s string) (finished, passed bool) {
+	switch {
+	case strings.Contains(s, "Passed"):
+		return true, true
+	case strings.Contains(s, "Failed"):
+		return true, false
+	}
+	return false, false
+}
+
+// Mooneye's test ROMs signal completion by executing the `LD B,B` software
+// breakpoint. On success, the registers hold the Fibonacci numbers
+// B/C/D/E/H/L = 3/5/8/13/21/34, and on failure they are all 0x42.
+// https://github.com/Gekkio/mooneye-test-suite
+
+var (
+	MooneyePass = [6]uint8{3, 5, 8, 13, 21, 34}
+	MooneyeFail = [6]uint8{0x42, 0x42, 0x42, 0x42, 0x42, 0x42}
+)
+
+var errTestROMDone = errors.New("test ROM done")
+
+type MooneyeOptions struct {
+	// Give up once this many cycles have run. Zero means no limit.
+	MaxCycles int
+	// Optional logger passed on to the cpu
+	Log *slog.Logger
+}
+
+type MooneyeResult struct {
+	Passed bool
+	// Whether the LD B,B breakpoint was reached
+	Finished bool
+	// B, C, D, E, H and L at the breakpoint
+	Registers [6]uint8
+	Cycles    int
+	// Set if the cpu stopped (or panicked) before reaching the breakpoint, or
+	// if the registers hold neither the pass nor the fail signature.
+	Err error
+}
+
+// Runs a Mooneye test ROM without a screen until it executes LD B,B, the cpu
+// stops, or the cycle budget is spent.
+func RunMooneye(rom []byte, opts MooneyeOptions) (res MooneyeResult) {
+	cpu := NewEmulator(rom, EmulatorOptions{Model: DMG, SkipBoot: true, Log: opts.Log}).CPU
+	cpu.WithHook(func(cpu *CPU, loc int, instr Instruction, log *slog.Logger) {
+		if _, ok := instr.(LD_40); !ok {
+			return
+		}
+		res.Finished = true
+		res.Registers = [6]uint8{cpu.B, cpu.C, cpu.D, cpu.E, cpu.H, cpu.L}
+		cpu.err = errTestROMDone
+	})
+
+	defer func() {
+		if err := recover(); err != nil {
+			res.Err = fmt.Errorf("panic at %#04x: %v", cpu.PC, err)
+		}
+		res.Cycles = cpu.Cycles
+	}()
+
+	for opts.MaxCycles == 0 || cpu.Cycles < opts.MaxCycles {
+		if cpu.Step() {
+			continue
+		}
+		switch {
+		case !res.Finished:
+			res.Err = cpu.Err()
+		case res.Registers == MooneyePass:
+			res.Passed = true
+		case res.Registers != MooneyeFail:
+			res.Err = fmt.Errorf("unexpected register signature % x", res.Registers)
+		}
+		return
+	}
+	res.Err = fmt.Errorf("no result after %d cycles", cpu.Cycles)
+	return
+}
@@ -0,0 +1,54 @@
+package gameboy
+
+// https://gbdev.io/pandocs/Timer_and_Divider_Registers.html
+const (
+	ADDR_DIV  = 0xFF04 // Divider, increments at 16384Hz
+	ADDR_TIMA = 0xFF05 // Timer counter
+	ADDR_TMA  = 0xFF06 // Timer modulo, loaded into TIMA when it overflows
+	ADDR_TAC  = 0xFF07 // Timer control
+)
+
+// TIMA increments every n cycles, as selected by the lower two bits of TAC
+var timerPeriods = [4]int{1024, 16, 64, 256}
+
+type Timer struct {
+	prev int // cpu cycles at the previous step
+	div  int // cycles since DIV last incremented
+	tima int // cycles since TIMA last incremented
+}
+
+func (t *Timer) Step(cpu *CPU) {
+	mem := cpu.Mem
+	delta := cpu.Cycles - t.prev
+	t.prev = cpu.Cycles
+
+	for t.div += delta; t.div >= 256; t.div -= 256 {
+		mem.data[ADDR_DIV]++
+	}
+	defer t.reset(mem)
+
+	tac := mem.data[ADDR_TAC]
+	if tac&0x04 == 0 { // timer disabled
+		t.tima = 0
+		return
+	}
+	period := timerPeriods[tac&0x03]
+	for t.tima += delta; t.tima >= period; t.tima -= period {
+		mem.data[ADDR_TIMA]++
+		if mem.data[ADDR_TIMA] == 0 {
+			mem.data[ADDR_TIMA] = mem.data[ADDR_TMA]
+			mem.RequestInterrupt(INT_TIMER)
+		}
+	}
+}
+
+// DIV is the top of the counter TIMA is clocked from, so writing it restarts
+// both. The write comes at the end of the instruction, after its cycles.
+func (t *Timer) reset(mem *Memory) {
+	if !mem.divReset {
+		return
+	}
+	mem.divReset = false
+	mem.data[ADDR_DIV] = 0
+	t.div, t.tima = 0, 0
+}
@@ -0,0 +1,36 @@
+package gameboy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimerDIVWrite(t *testing.T) {
+	req := require.New(t)
+	cpu := &CPU{Mem: NewMemory(nil)}
+	mem := cpu.Mem
+	run := func(cycles int) {
+		cpu.Cycles += cycles
+		cpu.timer.Step(cpu)
+	}
+	mem.WriteAt(ADDR_TAC, 0x05) // TIMA every 16 cycles
+
+	run(196)
+	req.Equal(uint8(12), mem.Read(ADDR_TIMA))
+	// an instruction that writes DIV partway through both periods
+	mem.WriteAt(ADDR_DIV, 0x12)
+	run(4)
+	req.Equal(uint8(0), mem.Read(ADDR_DIV))
+	req.Equal(uint8(12), mem.Read(ADDR_TIMA))
+
+	run(15)
+	req.Equal(uint8(12), mem.Read(ADDR_TIMA), "16 cycles after the write")
+	run(1)
+	req.Equal(uint8(13), mem.Read(ADDR_TIMA))
+
+	run(256 - 16 - 1)
+	req.Equal(uint8(0), mem.Read(ADDR_DIV), "256 cycles after the write")
+	run(1)
+	req.Equal(uint8(1), mem.Read(ADDR_DIV))
+}
@@ -0,0 +1,117 @@
+package gameboy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Execution traces in the gameboy-doctor format, one line per instruction
+// with the state before it is executed:
+//
+//	A:01 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0100 PCMEM:00,C3,13,02
+//
+// With symbols, see CPU.WithSymbols, a comment names PC, as in "; Main+3".
+// https://github.com/robert/gameboy-doctor
+
+// Formats the state of the cpu, as it is right before running the instruction
+// at pc.
+func TraceLine(cpu *CPU, pc uint16) string {
+	mem := cpu.Mem
+	return fmt.Sprintf(
+		"A:%02X F:%02X B:%02X C:%02X D:%02X E:%02X H:%02X L:%02X SP:%04X PC:%04X PCMEM:%02X,%02X,%02X,%02X",
+		cpu.A, uint8(cpu.F), cpu.B, cpu.C, cpu.D, cpu.E, cpu.H, cpu.L, cpu.SP, pc,
+		mem.Read(pc), mem.Read(pc+1), mem.Read(pc+2), mem.Read(pc+3),
+	)
+}
+
+// Writes a line to w for every instruction that runs after the boot ROM has
+// been unmapped. CB-prefixed instructions count as a single instruction.
+// Lines get a comment with the label of PC if the cpu has symbols.
+func (cpu *CPU) WithTrace(w io.Writer) *CPU {
+	var prefixed bool
+	return cpu.WithHook(func(cpu *CPU, loc int, instr Instruction, log *slog.Logger) {
+		if prefixed {
+			// second half of a CB-prefixed instruction, which was already
+			// traced together with the prefix
+			prefixed = false
+			return
+		}
+		_, prefixed = instr.(PREFIX_CB)
+		if cpu.Mem.BootActive() {
+			return
+		}
+		line := TraceLine(cpu, uint16(loc))
+		if sym, ok := cpu.syms.Resolve(cpu.Mem.Bank(uint16(loc)), uint16(loc)); ok {
+			line += " ; " + sym.Name
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			cpu.err = fmt.Errorf("trace: %w", err)
+		}
+	})
+}
+
+// The first line where two traces differ.
+type TraceMismatch struct {
+	Line int    // 1-based line number
+	Want string // "" if the reference ended first
+	Got  string // "" if our trace ended first
+	// Lines leading up to the mismatch (which were equal in both traces)
+	Context []string
+}
+
+func (m *TraceMismatch) Error() string {
+	var s strings.Builder
+	fmt.Fprintf(&s, "trace mismatch at line %d\n", m.Line)
+	for _, line := range m.Context {
+		fmt.Fprintf(&s, "        %s\n", line)
+	}
+	fmt.Fprintf(&s, "  want: %s\n", m.Want)
+	fmt.Fprintf(&s, "  got:  %s", m.Got)
+	return s.String()
+}
+
+// Compares a reference trace against ours, line by line, and returns the first
+// mismatch together with the n lines before it. Returns nil if both traces
+// are equal. Comments, i.e. anything after a ';', don't count.
+func CompareTrace(want, got io.Reader, n int) (*TraceMismatch, error) {
+	wantLines := bufio.NewScanner(want)
+	gotLines := bufio.NewScanner(got)
+
+	var context []string
+	for line := 1; ; line++ {
+		okWant := wantLines.Scan()
+		okGot := gotLines.Scan()
+		if err := wantLines.Err(); err != nil {
+			return nil, fmt.Errorf("read reference: %w", err)
+		}
+		if err := gotLines.Err(); err != nil {
+			return nil, fmt.Errorf("read trace: %w", err)
+		}
+		if !okWant && !okGot {
+			return nil, nil
+		}
+		w := strings.TrimSpace(wantLines.Text())
+		g := strings.TrimSpace(gotLines.Text())
+		if !okWant || !okGot || uncommented(w) != uncommented(g) {
+			return &TraceMismatch{
+				Line:    line,
+				Want:    w,
+				Got:     g,
+				Context: context,
+			}, nil
+		}
+		if n > 0 {
+			context = append(context, w)
+			if len(context) > n {
+				context = context[1:]
+			}
+		}
+	}
+}
+
+func uncommented(line string) string {
+	return strings.TrimSpace(stripComment(line))
+}
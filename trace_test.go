@@ -0,0 +1,90 @@
+package gameboy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrace(t *testing.T) {
+	req := require.New(t)
+	rom := testROM([]byte{
+		code("LD A,n8"), 0x12,
+		code("PREFIX"), code("SWAP A"),
+		code("JR e8"), 0xFE,
+	})
+	var trace strings.Builder
+	cpu := NewEmulator(rom, EmulatorOptions{Model: DMG, SkipBoot: true}).CPU.WithTrace(&trace)
+	for range 5 {
+		cpu.Step()
+	}
+	req.Equal([]string{
+		"A:01 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0100 PCMEM:00,C3,50,01",
+		"A:01 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0101 PCMEM:C3,50,01,00",
+		"A:01 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0150 PCMEM:3E,12,CB,37",
+		"A:12 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0152 PCMEM:CB,37,18,FE",
+	}, strings.Split(strings.TrimSpace(trace.String()), "\n"))
+}
+
+func TestTraceSymbols(t *testing.T) {
+	req := require.New(t)
+	syms, err := ReadSymbols(strings.NewReader("00:0100 Entry\n00:0150 Main\n"))
+	req.NoError(err)
+	rom := testROM([]byte{code("LD A,n8"), 0x12})
+	var trace strings.Builder
+	cpu := NewEmulator(rom, EmulatorOptions{Model: DMG, SkipBoot: true}).CPU.WithSymbols(syms).WithTrace(&trace)
+	for range 3 {
+		cpu.Step()
+	}
+	req.Equal([]string{
+		"A:01 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0100 PCMEM:00,C3,50,01 ; Entry",
+		"A:01 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0101 PCMEM:C3,50,01,00 ; Entry+1",
+		"A:01 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0150 PCMEM:3E,12,00,00 ; Main",
+	}, strings.Split(strings.TrimSpace(trace.String()), "\n"))
+
+	var dump strings.Builder
+	cpu.Dump(&dump)
+	req.Contains(dump.String(), "PC: 0x0152 Main+2\n")
+}
+
+func TestTraceDoctor(t *testing.T) {
+	req := require.New(t)
+	rom := testROM([]byte{code("LDH A,(a8)"), 0x44})
+	var trace strings.Builder
+	emu := NewEmulator(rom, EmulatorOptions{Model: DMG, SkipBoot: true})
+	cpu := emu.CPU.WithTrace(&trace)
+	cpu.Mem.WithDoctor()
+	for range 4 {
+		cpu.Step()
+	}
+	req.Equal(uint8(0x90), cpu.A)
+	req.Contains(trace.String(), "A:90 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0152")
+	req.NotEqual(uint8(0x90), cpu.Mem.LY(), "the PPU runs as usual")
+}
+
+func TestCompareTrace(t *testing.T) {
+	req := require.New(t)
+	ref := "line 1\nline 2\nline 3\nline 4\n"
+
+	m, err := CompareTrace(strings.NewReader(ref), strings.NewReader(ref), 2)
+	req.NoError(err)
+	req.Nil(m)
+
+	m, err = CompareTrace(strings.NewReader(ref), strings.NewReader("line 1\nline 2\nline 3\nline X\n"), 2)
+	req.NoError(err)
+	req.Equal(&TraceMismatch{
+		Line:    4,
+		Want:    "line 4",
+		Got:     "line X",
+		Context: []string{"line 2", "line 3"},
+	}, m)
+
+	m, err = CompareTrace(strings.NewReader(ref), strings.NewReader("line 1 ; Main\nline 2\nline 3\nline 4 ; Main+3\n"), 2)
+	req.NoError(err)
+	req.Nil(m, "comments don't count")
+
+	m, err = CompareTrace(strings.NewReader(ref), strings.NewReader("line 1\n"), 0)
+	req.NoError(err)
+	req.Equal(&TraceMismatch{Line: 2, Want: "line 2"}, m)
+}
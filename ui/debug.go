@@ -1,9 +1,48 @@
 package ui
 
+import "github.com/kvalv/gameboy"
+
+// The UI side of the debugger. While paused, the emulator only moves on the
+// step commands; otherwise frames run through the debugger, so that a
+// breakpoint or watchpoint pauses it.
 type Debugger struct {
-	Enabled bool
-	// if specified, run until here
-	BreakPoint uint16
+	*gameboy.Debugger
+	Paused bool
+	// why the debugger last stopped
+	Stop gameboy.Stop
+}
+
+// Runs until the end of the frame, or until something stops the debugger.
+func (d *Debugger) RunFrame() error {
+	return d.handle(d.Continue())
+}
+
+// Runs the command of the keys pressed, if any, or carries on with a step
+// that takes more than a frame.
+func (d *Debugger) Command(input *Input) error {
+	switch {
+	case input.Continue:
+		d.Paused = false
+		return d.RunFrame()
+	case d.Stepping():
+		return d.handle(d.Resume())
+	case input.StepInto:
+		return d.handle(d.StepInto())
+	case input.StepOver:
+		return d.handle(d.StepOver())
+	case input.StepOut:
+		return d.handle(d.StepOut())
+	}
+	return nil
+}
 
-	Stepped bool
+func (d *Debugger) handle(stop gameboy.Stop) error {
+	if stop.Reason == gameboy.StopFrame {
+		return nil
+	}
+	d.Stop, d.Paused = stop, true
+	if stop.Reason == gameboy.StopError {
+		return stop.Err
+	}
+	return nil
 }
@@ -1,75 +1,139 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
 	"image"
 	"image/color"
+	"io"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/ebitengine/debugui"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/kvalv/gameboy"
+	"github.com/kvalv/gameboy/disasm"
 )
 
-const CYCLES_TO_RUN = 70000 // should be approx 70k
-
 type Game struct {
 	offset int
-	cpu    *gameboy.CPU
+	emu    *gameboy.Emulator
+	// the ROM; save states are stored next to it
+	file string
+
+	// at most one of these is set, see Options.Record and Options.Play
+	recorder   *gameboy.MovieRecorder
+	recordFile string
+	player     *gameboy.MoviePlayer
+
+	// the GIF being recorded, if any, see toggleCapture
+	capture     gameboy.VideoWriter
+	captureFile *os.File
 
 	input       *Input
 	debugui     debugui.DebugUI
 	displayVRAM *DisplayVRAM
+	memoryView  *MemoryView
 
-	debugger       Debugger
+	debugger Debugger
+	// typed into the debugger's break field, and why it didn't work
+	breakSpec      string
+	breakErr       string
 	cyclesPerFrame int
 
 	// reference to the screen screen
 	screen *Screen
 }
 
-func NewGame(file string) *Game {
+type Options struct {
+	// Boot ROM to run, see gameboy.LoadBootROM. Defaults to the built-in one.
+	BootROM []byte
+	Model   gameboy.Model
+	// Start at 0x0100 in the state the boot ROM of Model leaves behind,
+	// instead of running the boot ROM.
+	SkipBoot bool
+	// Record the input into this movie file, which is written when quitting
+	Record string
+	// Play back this movie file instead of reading the keyboard
+	Play string
+	// Labels to show addresses by and set breakpoints at, see
+	// gameboy.LoadSymbols
+	Symbols *gameboy.Symbols
+	// Where to pause, as understood by gameboy.Debugger.Break, e.g.
+	// "Main.loop" or "02:4000"
+	Breakpoints []string
+}
+
+// Frames between framebuffer checksums in recorded movies
+const MOVIE_CHECKSUM_INTERVAL = 60
+
+// Size of a Game Boy pixel in screenshots and captures
+const SCREENSHOT_SCALE = 2
+
+func NewGame(file string, opts Options) *Game {
 	b, err := os.ReadFile(file)
 	if err != nil {
 		panic(err)
 	}
 	fmt.Printf("opening game %q\n", file)
 
-	cpu := gameboy.CPU{
-		Mem: gameboy.NewMemory(b),
-	}
-	cpu.WithLog(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
-		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
-			if a.Key == slog.LevelKey || a.Key == slog.TimeKey {
-				return slog.Attr{}
-			}
-			return a
-		},
-	})))
-	// cpu.Mem.CursorAt(0x0104)
-	// cpu.Mem.Write(gameboy.BootLogo)
+	emu := gameboy.NewEmulator(b, gameboy.EmulatorOptions{
+		BootROM:  opts.BootROM,
+		Model:    opts.Model,
+		SkipBoot: opts.SkipBoot,
+		Log: slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.LevelKey || a.Key == slog.TimeKey {
+					return slog.Attr{}
+				}
+				return a
+			},
+		})),
+	}).WithRewind(gameboy.DefaultRewindOptions)
+	emu.CPU.WithSymbols(opts.Symbols)
 
 	game := &Game{
-		displayVRAM:    NewDisplayVRAM(cpu.Mem),
+		displayVRAM:    NewDisplayVRAM(emu),
+		memoryView:     NewMemoryView(emu),
 		input:          NewInput(),
 		cyclesPerFrame: 1,
-		screen:         NewScreen(&cpu),
-		cpu:            &cpu,
+		screen:         NewScreen(emu),
+		emu:            emu,
+		file:           file,
 	}
 
-	var didBreak bool
-	cpu.WithHook(func(cpu *gameboy.CPU, loc int, instr gameboy.Instruction, log *slog.Logger) {
-		if cpu.Mem.Read(0xFF50) > 0 {
-			panic("X")
+	if opts.Play != "" {
+		f, err := os.Open(opts.Play)
+		if err != nil {
+			panic(err)
 		}
-		if !cpu.Mem.BootActive() && !didBreak {
-			game.EnableBreakpoint()
-			didBreak = true
+		movie, err := gameboy.ReadMovie(f)
+		f.Close()
+		if err != nil {
+			panic(err)
 		}
-	})
+		if game.player, err = gameboy.NewMoviePlayer(emu, movie); err != nil {
+			panic(err)
+		}
+	} else if opts.Record != "" {
+		if game.recorder, err = gameboy.NewMovieRecorder(emu, MOVIE_CHECKSUM_INTERVAL, true); err != nil {
+			panic(err)
+		}
+		game.recordFile = opts.Record
+	}
+
+	// pause when the cartridge takes over from the boot ROM
+	game.debugger = Debugger{Debugger: gameboy.NewDebugger(emu)}
+	game.BreakPointAt(0x0100)
+	for _, spec := range opts.Breakpoints {
+		if _, err := game.debugger.Break(spec); err != nil {
+			panic(err)
+		}
+	}
 
 	// "Double up" all the bits of the graphics data
 	// game.BreakPointAt(0x0095) // logo to vram routine
@@ -84,109 +148,277 @@ var _ ebiten.Game = (*Game)(nil)
 
 // Update implements ebiten.Game.
 func (g *Game) Update() error {
-	cpu := g.cpu
 	g.input.Update()
-	// mem := cpu.Mem
+	capturing, err := g.debugui.Update(g.updateDebugUI)
+	if err != nil {
+		return err
+	}
+	if capturing&debugui.InputCapturingStateFocus != 0 {
+		// typing into a text field, not playing
+		*g.input = Input{}
+	}
+	if g.player == nil {
+		// a movie sets the buttons as recorded
+		g.emu.SetButtons(g.input.Buttons)
+	}
+
+	if g.input.Screenshot {
+		if err := g.screenshot(); err != nil {
+			fmt.Printf("screenshot: %v\n", err)
+		}
+	}
+	if g.input.Capture {
+		if err := g.toggleCapture(); err != nil {
+			fmt.Printf("capture: %v\n", err)
+		}
+	}
+	if n := g.input.SaveSlot; n > 0 {
+		if err := g.saveState(n); err != nil {
+			fmt.Printf("save state %d: %v\n", n, err)
+		}
+	}
+	if n := g.input.LoadSlot; n > 0 && g.movieActive() {
+		fmt.Printf("load state %d: not while a movie is recorded or played\n", n)
+	} else if n > 0 {
+		if err := g.loadState(n); err != nil {
+			fmt.Printf("load state %d: %v\n", n, err)
+		}
+	}
 
 	if g.input.KeyQ {
+		if g.capture != nil {
+			if err := g.toggleCapture(); err != nil {
+				fmt.Printf("capture: %v\n", err)
+			}
+		}
+		if g.recorder != nil {
+			if err := g.saveMovie(); err != nil {
+				return err
+			}
+		}
 		return ebiten.Termination
 	}
-	if g.input.KeyN {
-		fmt.Printf("key n pressed - stepped is true (prev %t)\n", g.debugger.Stepped)
-		g.debugger.Stepped = true
+	if g.debugger.Paused {
+		if g.movieActive() {
+			return nil // stepping would desync the movie
+		}
+		if err := g.debugger.Command(g.input); err != nil {
+			return fmt.Errorf("stopped execution: %w", err)
+		}
+		return nil
+	}
+
+	if g.input.Rewind && !g.movieActive() {
+		// one frame per tick; stays put once the oldest snapshot is reached
+		if err := g.emu.Rewind(1); err != nil && !errors.Is(err, gameboy.ErrRewind) {
+			return err
+		}
+		return nil
+	}
+
+	// otherwise just run regularly...
+	if err := g.runFrame(); err != nil {
+		return fmt.Errorf("stopped execution: %w", err)
+	}
+	if g.capture != nil {
+		if err := g.capture.WriteFrame(g.emu.Framebuffer()); err != nil {
+			return err
+		}
 	}
+	g.offset++
+	return nil
+}
 
-	_, err := g.debugui.Update(func(ctx *debugui.Context) error {
-		ctx.Window("Info", image.Rect(250, 10, 580, 490), func(layout debugui.ContainerLayout) {
-			ctx.Header("info", true, func() {
-				ctx.SetGridLayout([]int{-2, -1}, nil)
+func (g *Game) updateDebugUI(ctx *debugui.Context) error {
+	cpu := g.emu.CPU
+	ctx.Window("Info", image.Rect(250, 10, 580, 490), func(layout debugui.ContainerLayout) {
+		ctx.Header("info", true, func() {
+			ctx.SetGridLayout([]int{-2, -1}, nil)
 
-				ctx.Text("next")
-				ctx.Text(nextInstr(cpu))
+			ctx.Text("next")
+			ctx.Text(nextInstr(cpu))
 
-				ctx.Text("TPS")
-				ctx.Text(fmt.Sprintf("%0.2f", ebiten.ActualTPS()))
+			ctx.Text("TPS")
+			ctx.Text(fmt.Sprintf("%0.2f", ebiten.ActualTPS()))
 
-				ctx.Text("boot active")
-				ctx.Text(fmt.Sprintf("%t", cpu.Mem.BootActive()))
-			})
-			ctx.Header("PPU Registers", false, func() {
-				ctx.SetGridLayout([]int{-2, -1}, nil)
-				ctx.Text("SCY")
-				ctx.Text(fmt.Sprintf("%#2x", g.cpu.Mem.SCY()))
+			ctx.Text("boot active")
+			ctx.Text(fmt.Sprintf("%t", cpu.Mem.BootActive()))
+		})
+		ctx.Header("PPU Registers", false, func() {
+			ctx.SetGridLayout([]int{-2, -1}, nil)
+			ctx.Text("SCY")
+			ctx.Text(fmt.Sprintf("%#2x", cpu.Mem.SCY()))
 
-				ctx.Text("LY")
-				ctx.Text(fmt.Sprintf("%#2x", g.cpu.Mem.LY()))
-			})
-			ctx.Header("Debugger", false, func() {
-				ctx.SetGridLayout([]int{-2, -1}, nil)
-				ctx.Checkbox(&g.debugger.Enabled, "Debug enabled").On(func() {
-					fmt.Printf("Enabled")
-				})
+			ctx.Text("LY")
+			ctx.Text(fmt.Sprintf("%#2x", cpu.Mem.LY()))
+		})
+		ctx.Header("Debugger", false, func() {
+			ctx.SetGridLayout([]int{-2, -1}, nil)
+			ctx.Checkbox(&g.debugger.Paused, "Paused")
+			ctx.Text("C/S/N/O: continue, step into/over/out")
+
+			ctx.Text("stopped")
+			if g.debugger.Paused {
+				ctx.Text(fmt.Sprintf("%s (%s)", g.debugger.Stop, cpu.Location(g.debugger.Stop.PC)))
+			} else {
+				ctx.Text("-")
+			}
+
+			ctx.Text("break at")
+			ctx.TextField(&g.breakSpec).On(func() {
+				g.breakErr = ""
+				if _, err := g.debugger.Break(g.breakSpec); err != nil {
+					g.breakErr = err.Error()
+				}
 			})
+			if g.breakErr != "" {
+				ctx.Text("")
+				ctx.Text(g.breakErr)
+			}
+			for _, bp := range g.debugger.Breakpoints() {
+				ctx.Text(fmt.Sprintf("breakpoint %d", bp.ID))
+				ctx.Text(fmt.Sprintf("%s, %d hits", g.location(bp.Addr, bp.Bank), bp.Hits))
+			}
 
+			// innermost first
+			calls := cpu.CallStack()
+			for i := len(calls) - 1; i >= 0; i-- {
+				ctx.Text(fmt.Sprintf("#%d %s", len(calls)-1-i, g.location(calls[i].Func, calls[i].Bank)))
+				ctx.Text("from " + cpu.Location(calls[i].Caller))
+			}
 		})
-		return nil
 	})
+	g.memoryView.Update(ctx)
+	return nil
+}
 
-	if err != nil {
-		return err
+// Where addr is, by label if there is one
+func (g *Game) location(addr uint16, bank int) string {
+	if bank == gameboy.ANY_BANK {
+		bank = g.emu.Memory().Bank(addr)
 	}
+	return g.emu.CPU.Symbols().Format(bank, addr)
+}
 
-	// are we in debug mode?
-	if dbg := g.debugger; dbg.Enabled {
-		if dbg.BreakPoint > 0 {
-			// run until we reach this step
-			for cpu.PC != dbg.BreakPoint {
-				cpu.Step()
-			}
-			g.debugger.BreakPoint = 0
+func (g *Game) runFrame() error {
+	switch {
+	case g.player != nil:
+		err := g.player.RunFrame()
+		if errors.Is(err, io.EOF) {
+			fmt.Printf("movie finished after %d frames\n", g.player.Frame())
+			g.player = nil
 			return nil
 		}
-		if !dbg.Stepped {
-			return nil
-		}
-		n0 := g.cpu.Cycles
-		for g.cpu.Cycles-n0 < CYCLES_TO_RUN {
-			g.cpu.Step()
-		}
-		g.debugger.Stepped = false
-		return nil
+		return err
+	case g.recorder != nil:
+		return g.recorder.RunFrame()
 	}
+	return g.debugger.RunFrame()
+}
 
-	// otherwise just run regularly...
-	n0 := g.cpu.Cycles
-	limit := 70000
-	for g.cpu.Cycles-n0 < CYCLES_TO_RUN {
-		limit--
-		if limit == 0 {
-			panic(fmt.Sprintf("too many steps in a single cycle: %s", cpu.CurrentInstr()))
-		}
-		ok := g.cpu.Step()
-		if !ok {
-			return fmt.Errorf("stopped execution: %w", g.cpu.Err())
-		}
+// Jumping around in time would make the movie meaningless
+func (g *Game) movieActive() bool {
+	return g.recorder != nil || g.player != nil
+}
+
+func (g *Game) saveMovie() error {
+	f, err := os.Create(g.recordFile)
+	if err != nil {
+		return err
 	}
-	limit = 70000 * 10 // Skip the loader...
-	for cpu.Mem.BootActive() && limit > 0 {
-		g.cpu.Step()
-		limit--
+	if err := g.recorder.Movie().Save(f); err != nil {
+		f.Close()
+		return err
 	}
+	fmt.Printf("saved movie %q\n", g.recordFile)
+	return f.Close()
+}
 
-	g.offset++
+// A file in the working directory, named after the ROM and the current time
+func (g *Game) outputName(ext string) string {
+	base := strings.TrimSuffix(filepath.Base(g.file), filepath.Ext(g.file))
+	return fmt.Sprintf("%s-%s%s", base, time.Now().Format("20060102-150405.000"), ext)
+}
+
+// Saves the last frame, see outputName.
+func (g *Game) screenshot() error {
+	name := g.outputName(".png")
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	err = gameboy.EncodePNG(f, g.emu.Framebuffer(), gameboy.PNGOptions{
+		Scale:   SCREENSHOT_SCALE,
+		Palette: &gameboy.PaletteGreen,
+	})
+	if err != nil {
+		f.Close()
+		return err
+	}
+	fmt.Printf("saved screenshot %q\n", name)
+	return f.Close()
+}
+
+// Starts recording the frames that run into a GIF, see outputName, or saves
+// the one being recorded.
+func (g *Game) toggleCapture() error {
+	if g.capture != nil {
+		w, f := g.capture, g.captureFile
+		g.capture, g.captureFile = nil, nil
+		if err := w.Close(); err != nil {
+			f.Close()
+			return err
+		}
+		fmt.Printf("saved capture %q\n", f.Name())
+		return f.Close()
+	}
+
+	name := g.outputName(".gif")
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	g.capture = gameboy.NewGIFWriter(f, gameboy.VideoOptions{
+		Scale:   SCREENSHOT_SCALE,
+		Palette: &gameboy.PaletteGreen,
+	})
+	g.captureFile = f
+	fmt.Printf("recording %q\n", name)
 	return nil
 }
 
-func (g *Game) BreakPointAt(loc uint16) *Game {
-	g.debugger = Debugger{
-		Enabled:    true,
-		BreakPoint: loc,
+func (g *Game) stateFile(slot int) string {
+	return fmt.Sprintf("%s.ss%d", g.file, slot)
+}
+
+func (g *Game) saveState(slot int) error {
+	f, err := os.Create(g.stateFile(slot))
+	if err != nil {
+		return err
 	}
-	return g
+	if err := g.emu.SaveState(f); err != nil {
+		f.Close()
+		return err
+	}
+	fmt.Printf("saved state %d\n", slot)
+	return f.Close()
 }
 
-func (g *Game) EnableBreakpoint() *Game {
-	g.debugger.Enabled = true
+func (g *Game) loadState(slot int) error {
+	f, err := os.Open(g.stateFile(slot))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := g.emu.LoadState(f); err != nil {
+		return err
+	}
+	fmt.Printf("loaded state %d\n", slot)
+	return nil
+}
+
+func (g *Game) BreakPointAt(loc uint16) *Game {
+	g.debugger.AddBreakpoint(loc)
 	return g
 }
 
@@ -207,7 +439,7 @@ func (g *Game) Draw(screen *ebiten.Image) {
 
 	// debug stuff 1
 	var b strings.Builder
-	g.cpu.Dump(&b)
+	g.emu.CPU.Dump(&b)
 	s := strings.ReplaceAll(b.String(), "DE", "  DE")
 	s = strings.ReplaceAll(s, "HL", "  HL")
 	ebitenutil.DebugPrint(screen, s)
@@ -231,35 +463,10 @@ func drawRelative(container, img *ebiten.Image, dx, dy float64) {
 	op.GeoM.Translate(x, y)
 	container.DrawImage(img, op)
 }
-func hexstr[V uint8 | uint16 | int | int8](v V, n ...int) string {
-	if len(n) > 0 {
-		// if n is provided, use it as the width
-		return fmt.Sprintf("%0*x", n[0], v)
-	}
-	return fmt.Sprintf("%#x", v)
-}
 
-// creates a human-readable (assembly-ish) representation of the next instruction
+// creates a human-readable (assembly) representation of the next instruction,
+// e.g. "Main.loop+2: jr nz, Main.loop"
 func nextInstr(cpu *gameboy.CPU) string {
-	s := cpu.CurrentInstr().String()
-	if strings.HasSuffix(s, "n8") {
-		n := cpu.Mem.Read(cpu.PC + 1)
-		// ADD A,0x04
-		return fmt.Sprintf("%s%s", strings.TrimSuffix(s, "n8"), hexstr(n))
-	}
-	if strings.HasSuffix(s, "n16") {
-		n := cpu.Mem.AccessU16(cpu.PC + 1)
-		// ADD A,0x04
-		return fmt.Sprintf("%s%s", strings.TrimSuffix(s, "n16"), hexstr(n))
-	}
-	if strings.HasSuffix(s, "a16") {
-		n := cpu.Mem.AccessU16(cpu.PC + 1)
-		// ADD A,0x04
-		return fmt.Sprintf("%s%s", strings.TrimSuffix(s, "a16"), hexstr(n))
-	}
-	if strings.Contains(s, "a8") {
-		n := cpu.Mem.Read(cpu.PC + 1)
-		return strings.ReplaceAll(s, "a8", hexstr(n))
-	}
-	return s
+	in := disasm.Decode(cpu.Mem, cpu.PC)
+	return fmt.Sprintf("%s: %s", cpu.Location(cpu.PC), in.Format(cpu.Symbols().Labeler(cpu.Mem)))
 }
@@ -3,11 +3,42 @@ package ui
 import (
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/kvalv/gameboy"
 )
 
 type Input struct {
-	KeyN bool
 	KeyQ bool
+	// debugger commands while paused, see Debugger.Command
+	Continue bool
+	StepInto bool
+	StepOver bool
+	StepOut  bool
+	// held down to run backwards
+	Rewind bool
+	// save a screenshot
+	Screenshot bool
+	// start or stop recording a GIF
+	Capture bool
+
+	// Game Boy buttons currently held down
+	Buttons gameboy.Buttons
+
+	// Save state slot (1-8) to save to (shift+F1-F8) or load from (F1-F8);
+	// 0 if none
+	SaveSlot int
+	LoadSlot int
+}
+
+// Keyboard layout of the Game Boy buttons
+var buttonKeys = map[gameboy.Buttons]ebiten.Key{
+	gameboy.ButtonA:      ebiten.KeyX,
+	gameboy.ButtonB:      ebiten.KeyZ,
+	gameboy.ButtonSelect: ebiten.KeyBackspace,
+	gameboy.ButtonStart:  ebiten.KeyEnter,
+	gameboy.ButtonRight:  ebiten.KeyArrowRight,
+	gameboy.ButtonLeft:   ebiten.KeyArrowLeft,
+	gameboy.ButtonUp:     ebiten.KeyArrowUp,
+	gameboy.ButtonDown:   ebiten.KeyArrowDown,
 }
 
 func NewInput() *Input {
@@ -16,5 +47,31 @@ func NewInput() *Input {
 
 func (i *Input) Update() {
 	i.KeyQ = inpututil.IsKeyJustPressed(ebiten.KeyQ)
-	i.KeyN = inpututil.IsKeyJustPressed(ebiten.KeyN)
+	i.Continue = inpututil.IsKeyJustPressed(ebiten.KeyC)
+	i.StepInto = inpututil.IsKeyJustPressed(ebiten.KeyS)
+	i.StepOver = inpututil.IsKeyJustPressed(ebiten.KeyN)
+	i.StepOut = inpututil.IsKeyJustPressed(ebiten.KeyO)
+	i.Rewind = ebiten.IsKeyPressed(ebiten.KeyR)
+	i.Screenshot = inpututil.IsKeyJustPressed(ebiten.KeyF12)
+	i.Capture = inpututil.IsKeyJustPressed(ebiten.KeyF10)
+
+	i.SaveSlot, i.LoadSlot = 0, 0
+	shift := ebiten.IsKeyPressed(ebiten.KeyShift)
+	for n := 1; n <= 8; n++ {
+		if !inpututil.IsKeyJustPressed(ebiten.KeyF1 + ebiten.Key(n-1)) {
+			continue
+		}
+		if shift {
+			i.SaveSlot = n
+		} else {
+			i.LoadSlot = n
+		}
+	}
+
+	i.Buttons = 0
+	for b, key := range buttonKeys {
+		if ebiten.IsKeyPressed(key) {
+			i.Buttons |= b
+		}
+	}
 }
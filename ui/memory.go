@@ -0,0 +1,254 @@
+package ui
+
+import (
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+
+	"github.com/ebitengine/debugui"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/kvalv/gameboy"
+)
+
+const (
+	MEMORY_COLUMNS = 8  // bytes per row
+	MEMORY_ROWS    = 16 // rows per page
+	MEMORY_PAGE    = MEMORY_COLUMNS * MEMORY_ROWS
+)
+
+// Drawn over bytes the game changed, and over the last search match
+var (
+	COLOR_CHANGED = color.RGBA{0x80, 0x50, 0x00, 0x80}
+	COLOR_FOUND   = color.RGBA{0x00, 0x40, 0x80, 0x80}
+)
+
+// A part of the memory map the view can jump to
+type memoryRegion struct {
+	name       string
+	start, end int // end is exclusive
+}
+
+var memoryRegions = []memoryRegion{
+	{"ROM0", 0x0000, 0x4000},
+	{"ROMX", 0x4000, 0x8000},
+	{"VRAM", 0x8000, 0xA000},
+	{"WRAM", 0xC000, 0xE000},
+	{"OAM", 0xFE00, 0xFEA0},
+	{"IO", 0xFF00, 0xFF80},
+	{"HRAM", 0xFF80, 0xFFFF},
+}
+
+// A hex viewer and editor of the memory map, a page at a time. Bytes that
+// changed the last time the emulator ran are highlighted. Typing a value into
+// a byte writes it through Memory.WriteAt, as if the game did, so writing to
+// ROM sets the MBC registers rather than patching the ROM.
+type MemoryView struct {
+	emu *gameboy.Emulator
+	// the first address shown
+	start int
+	// the ROM bank shown at 0x4000-0x7FFF, rather than the mapped one
+	bank int
+
+	// the page when the emulator last ran, and which bytes it changed
+	shown      [MEMORY_PAGE]byte
+	shownStart int
+	shownBank  int
+	shownAt    int // the instruction count
+	changed    [MEMORY_PAGE]bool
+
+	// the bytes as typed into their fields
+	cells [MEMORY_PAGE]string
+
+	// the bytes to look for, where they were last found, and why not
+	search    string
+	found     int
+	foundLen  int
+	searchErr string
+}
+
+func NewMemoryView(emu *gameboy.Emulator) *MemoryView {
+	return &MemoryView{emu: emu, bank: 1, shownAt: -1, found: -1}
+}
+
+// Adds the view's window.
+func (v *MemoryView) Update(ctx *debugui.Context) {
+	mem := v.emu.Memory()
+	ctx.Window("Memory", image.Rect(590, 380, 890, 595), func(layout debugui.ContainerLayout) {
+		ctx.SetGridLayout([]int{-1, -1, -1, -1, -1, -1, -1}, nil)
+		for _, region := range memoryRegions {
+			ctx.IDScope(region.name, func() {
+				ctx.Button(region.name).On(func() { v.start = region.start })
+			})
+		}
+
+		ctx.SetGridLayout([]int{-2, -1, -1, -1, -1}, nil)
+		ctx.Text(v.where())
+		ctx.Text("bank")
+		ctx.NumberField(&v.bank, 1).On(func() {
+			v.bank = min(max(v.bank, 1), max(mem.ROMBanks()-1, 1))
+		})
+		ctx.Button("<").On(func() { v.start = max(v.start-MEMORY_PAGE, 0) })
+		ctx.Button(">").On(func() { v.start = min(v.start+MEMORY_PAGE, 0x10000-MEMORY_PAGE) })
+
+		ctx.SetGridLayout([]int{-1, -2}, nil)
+		ctx.TextField(&v.search).On(v.find)
+		if v.searchErr != "" {
+			ctx.Text(v.searchErr)
+		} else {
+			ctx.Text("search, e.g. 3E 01")
+		}
+
+		widths := []int{36}
+		for range MEMORY_COLUMNS {
+			widths = append(widths, 22)
+		}
+		ctx.SetGridLayout(append(widths, -1), nil)
+		v.track()
+		var marks []mark
+		for row := range MEMORY_ROWS {
+			addr := v.start + row*MEMORY_COLUMNS
+			ctx.Text(fmt.Sprintf("%04X", addr))
+			var ascii strings.Builder
+			for col := range MEMORY_COLUMNS {
+				i := row*MEMORY_COLUMNS + col
+				b := v.shown[i]
+				ascii.WriteByte(printable(b))
+				ctx.GridCell(func(bounds image.Rectangle) {
+					switch {
+					case v.found >= 0 && addr+col >= v.found && addr+col < v.found+v.foundLen:
+						marks = append(marks, mark{bounds, COLOR_FOUND})
+					case v.changed[i]:
+						marks = append(marks, mark{bounds, COLOR_CHANGED})
+					}
+					// while focused, the field keeps what's typed instead
+					v.cells[i] = fmt.Sprintf("%02X", b)
+					ctx.IDScope(strconv.Itoa(i), func() {
+						ctx.TextField(&v.cells[i]).On(func() { v.write(i, v.cells[i]) })
+					})
+				})
+			}
+			ctx.Text(ascii.String())
+		}
+
+		// highlights go over the fields, so they're drawn after them
+		ctx.SetGridLayout([]int{-1}, []int{1})
+		scale := float32(ctx.Scale())
+		ctx.DrawOnlyWidget(func(screen *ebiten.Image) {
+			for _, m := range marks {
+				r := m.bounds
+				vector.DrawFilledRect(screen, float32(r.Min.X)*scale, float32(r.Min.Y)*scale, float32(r.Dx())*scale, float32(r.Dy())*scale, m.color, false)
+			}
+		})
+	})
+}
+
+type mark struct {
+	bounds image.Rectangle
+	color  color.Color
+}
+
+// Reads the page, and notes what changed if the emulator ran since the last
+// time. Going to another page starts over.
+func (v *MemoryView) track() {
+	count := v.emu.CPU.InstrCount
+	if v.start != v.shownStart || v.bank != v.shownBank {
+		v.shownStart, v.shownBank, v.shownAt = v.start, v.bank, count
+		v.changed = [MEMORY_PAGE]bool{}
+		for i := range v.shown {
+			v.shown[i] = v.read(v.start + i)
+		}
+		return
+	}
+	if count == v.shownAt {
+		return
+	}
+	v.shownAt = count
+	for i := range v.shown {
+		b := v.read(v.start + i)
+		v.changed[i] = b != v.shown[i]
+		v.shown[i] = b
+	}
+}
+
+// Reads without diagnosing echo RAM and the unusable area, as the game didn't
+// make the read; see Memory.Peek.
+func (v *MemoryView) read(addr int) byte {
+	return v.emu.Memory().ReadBank(v.bank, uint16(addr))
+}
+
+// Writes the byte typed into cell i, if it was changed.
+func (v *MemoryView) write(i int, s string) {
+	s = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(s), "$"), "0x")
+	b, err := strconv.ParseUint(s, 16, 8)
+	if err != nil || byte(b) == v.shown[i] {
+		return
+	}
+	addr := v.start + i
+	v.emu.Memory().WriteAt(uint16(addr), byte(b))
+	// not a change by the game
+	v.shown[i] = v.read(addr)
+}
+
+// Which region the page is in, e.g. "ROMX 03:4000"
+func (v *MemoryView) where() string {
+	for _, region := range memoryRegions {
+		if v.start < region.start || v.start >= region.end {
+			continue
+		}
+		if region.name == "ROMX" {
+			return fmt.Sprintf("%s %02X:%04X", region.name, v.bank, v.start)
+		}
+		return fmt.Sprintf("%s %04X", region.name, v.start)
+	}
+	return fmt.Sprintf("%04X", v.start)
+}
+
+// Goes to the next match of the search after the last one, or after the
+// start of the page, wrapping around at the end of memory.
+func (v *MemoryView) find() {
+	v.searchErr = ""
+	pattern, err := hex.DecodeString(strings.Join(strings.Fields(v.search), ""))
+	if err != nil || len(pattern) == 0 {
+		v.searchErr = "expected hex bytes"
+		return
+	}
+	from := v.found
+	if from < 0 || from < v.start || from >= v.start+MEMORY_PAGE {
+		from = v.start - 1
+	}
+	for i := 1; i <= 0x10000; i++ {
+		addr := (from + i) % 0x10000
+		if v.matches(addr, pattern) {
+			v.found, v.foundLen = addr, len(pattern)
+			v.start = min(addr&^(MEMORY_COLUMNS-1), 0x10000-MEMORY_PAGE)
+			return
+		}
+	}
+	v.found = -1
+	v.searchErr = "not found"
+}
+
+func (v *MemoryView) matches(addr int, pattern []byte) bool {
+	for j, b := range pattern {
+		a := addr + j
+		// echo RAM is a copy of WRAM, and the unusable area isn't memory
+		if a > 0xFFFF || a >= 0xE000 && a < 0xFE00 || a >= 0xFEA0 && a < 0xFF00 {
+			return false
+		}
+		if v.read(a) != b {
+			return false
+		}
+	}
+	return true
+}
+
+func printable(b byte) byte {
+	if b < 0x20 || b > 0x7E {
+		return '.'
+	}
+	return b
+}
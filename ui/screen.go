@@ -7,47 +7,24 @@ import (
 
 // Represents the Screen screen for the game boy
 type Screen struct {
-	cpu *gameboy.CPU
+	emu *gameboy.Emulator
 }
 
-func NewScreen(cpu *gameboy.CPU) *Screen {
-	return &Screen{cpu: cpu}
+func NewScreen(emu *gameboy.Emulator) *Screen {
+	return &Screen{emu: emu}
 }
 
 func (s *Screen) Size() (int, int) {
-	return 160, 144
+	return gameboy.SCREEN_WIDTH, gameboy.SCREEN_HEIGHT
 }
 
+// Draws the last complete frame
 func (s *Screen) Draw(img *ebiten.Image) {
-	// W, H := s.Size()
-	mem := s.cpu.Mem
-	vram := mem.VRAM()
-
-	// draw tile window 1
-	// 32 x 32 view, and each tile is 8 x 8 -> 256 x 256
-	// 32 x 32 = 1024 = 1kB
-	// each tile is 16 bytes, and tile data1 has 4096 bytes -> 256 tiles
-	// .. so each byte in tile view 1 is just an index into one of the 256 tiles
-
-	topLeftX := int(mem.SCX())
-	topLeftY := int(mem.SCY())
-	// botRightX := uint8((int(mem.SCX()) + 159) % 256)
-	// botRightY := uint8((int(mem.SCY()) + 143) % 256)
-
-	for i, index := range vram.TileView1 {
-		tile := vram.Tile(gameboy.TilesetBackground, index)
-
-		x0 := (i % 32) * gameboy.TILE_WIDTH_PX
-		y0 := (i / 32) * gameboy.TILE_WIDTH_PX
-
-		for x := range 8 {
-			for y := range 8 {
-				color := tile.PixelAt(x, y, PALETTE)
-				img.Set(topLeftX+x0+x, topLeftY+y0+y, color)
-			}
+	frame := s.emu.Framebuffer()
+	for y := range gameboy.SCREEN_HEIGHT {
+		for x := range gameboy.SCREEN_WIDTH {
+			// shade 0 is the lightest, PALETTE goes from dark to light
+			img.Set(x, y, PALETTE[3-frame.At(x, y)])
 		}
 	}
-
-	// TODO: foreground...
-
 }
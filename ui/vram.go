@@ -14,13 +14,14 @@ import (
 // - 0x9c00 - 0x9fff - 1kB - Tile view 2
 
 type DisplayVRAM struct {
-	mem          *gameboy.Memory
+	// read through the emulator, as a reset replaces its memory
+	emu          *gameboy.Emulator
 	nrows, ncols int
 }
 
-func NewDisplayVRAM(mem *gameboy.Memory) *DisplayVRAM {
+func NewDisplayVRAM(emu *gameboy.Emulator) *DisplayVRAM {
 	return &DisplayVRAM{
-		mem:   mem,
+		emu:   emu,
 		nrows: 16,
 		ncols: 16,
 	}
@@ -45,7 +46,7 @@ func (d *DisplayVRAM) Size() (width, height int) {
 }
 
 func (d *DisplayVRAM) Draw(img *ebiten.Image) {
-	vram := d.mem.VRAM()
+	vram := d.emu.Memory().VRAM()
 
 	// W, H := d.Size()
 
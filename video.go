@@ -0,0 +1,156 @@
+package gameboy
+
+import (
+	"errors"
+	"fmt"
+	"image/gif"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+var ErrVideoFormat = errors.New("unknown video format")
+
+// Records frames into a video, e.g. to share a bug.
+type VideoWriter interface {
+	// Adds a frame; frames are shown at the Game Boy's rate of about 59.7
+	// per second.
+	WriteFrame(f Frame) error
+	// Finishes the video. The underlying writer isn't closed.
+	Close() error
+}
+
+type VideoOptions struct {
+	// Size of a Game Boy pixel; 0 means 1
+	Scale int
+	// Defaults to PaletteGray
+	Palette *Palette
+}
+
+func (o VideoOptions) palette() Palette {
+	if o.Palette != nil {
+		return *o.Palette
+	}
+	return PaletteGray
+}
+
+// Picks the format from the extension of name: .gif or .y4m.
+func NewVideoWriter(w io.Writer, name string, opts VideoOptions) (VideoWriter, error) {
+	switch ext := strings.ToLower(filepath.Ext(name)); ext {
+	case ".gif":
+		return NewGIFWriter(w, opts), nil
+	case ".y4m":
+		return NewY4MWriter(w, opts), nil
+	default:
+		return nil, fmt.Errorf("%w %q, want .gif or .y4m", ErrVideoFormat, ext)
+	}
+}
+
+// Writes an animated GIF. The four shades map straight onto a four color
+// palette, so nothing is lost, but GIF has two limitations: frames last a
+// whole number of hundredths of a second, and most viewers treat anything
+// shorter than two as ten. Frames are therefore dropped to keep at most 50
+// per second. The whole animation is kept in memory until Close.
+type GIFWriter struct {
+	w    io.Writer
+	opts VideoOptions
+	anim gif.GIF
+
+	frames    int   // frames written, including dropped ones
+	last      Frame // the last frame in the animation
+	lastStamp int   // when the last frame starts, in hundredths of a second
+}
+
+// Shortest delay, in hundredths of a second, that viewers respect
+const GIF_MIN_DELAY = 2
+
+func NewGIFWriter(w io.Writer, opts VideoOptions) *GIFWriter {
+	return &GIFWriter{w: w, opts: opts}
+}
+
+// When the nth frame starts, in hundredths of a second
+func gifStamp(n int) int {
+	return (n*100*CYCLES_PER_FRAME + CPU_FREQUENCY/2) / CPU_FREQUENCY
+}
+
+func (g *GIFWriter) WriteFrame(f Frame) error {
+	stamp := gifStamp(g.frames)
+	g.frames++
+	if n := len(g.anim.Image); n > 0 {
+		// an unchanged frame just shows the last one for longer
+		if f == g.last || stamp-g.lastStamp < GIF_MIN_DELAY {
+			return nil
+		}
+		g.anim.Delay[n-1] = stamp - g.lastStamp
+	}
+	g.anim.Image = append(g.anim.Image, f.ScaledImage(g.opts.palette(), g.opts.Scale))
+	g.anim.Delay = append(g.anim.Delay, 0)
+	g.last, g.lastStamp = f, stamp
+	return nil
+}
+
+func (g *GIFWriter) Close() error {
+	n := len(g.anim.Image)
+	if n == 0 {
+		return errors.New("gif: no frames")
+	}
+	g.anim.Delay[n-1] = max(gifStamp(g.frames)-g.lastStamp, GIF_MIN_DELAY)
+	return gif.EncodeAll(g.w, &g.anim)
+}
+
+// Writes a raw YUV4MPEG2 stream, which ffmpeg and friends read, e.g.
+//
+//	ffmpeg -i capture.y4m -vf scale=640:-1:flags=neighbor capture.mp4
+//
+// Every frame is kept, at the exact frame rate, as 4:4:4 BT.601 video.
+type Y4MWriter struct {
+	w      io.Writer
+	opts   VideoOptions
+	header bool // written along with the first frame
+
+	// Y, Cb and Cr of each shade
+	shades [4][3]byte
+	buf    []byte
+}
+
+func NewY4MWriter(w io.Writer, opts VideoOptions) *Y4MWriter {
+	y := &Y4MWriter{w: w, opts: opts}
+	for i, c := range opts.palette() {
+		r, g, b := int(c.R), int(c.G), int(c.B)
+		y.shades[i] = [3]byte{
+			byte((66*r+129*g+25*b+128)>>8 + 16),
+			byte((-38*r-74*g+112*b+128)>>8 + 128),
+			byte((112*r-94*g-18*b+128)>>8 + 128),
+		}
+	}
+	return y
+}
+
+func (y *Y4MWriter) WriteFrame(f Frame) error {
+	img := f.ScaledImage(PaletteGray, y.opts.Scale)
+	if !y.header {
+		w, h := img.Rect.Dx(), img.Rect.Dy()
+		_, err := fmt.Fprintf(y.w, "YUV4MPEG2 W%d H%d F%d:%d Ip A1:1 C444\n", w, h, CPU_FREQUENCY, CYCLES_PER_FRAME)
+		if err != nil {
+			return err
+		}
+		y.header = true
+	}
+
+	// a header and three planes of one byte per pixel
+	y.buf = append(y.buf[:0], "FRAME\n"...)
+	for plane := range 3 {
+		for _, shade := range img.Pix {
+			y.buf = append(y.buf, y.shades[shade][plane])
+		}
+	}
+	_, err := y.w.Write(y.buf)
+	return err
+}
+
+func (y *Y4MWriter) Close() error { return nil }
+
+var (
+	_ VideoWriter = (*GIFWriter)(nil)
+	_ VideoWriter = (*Y4MWriter)(nil)
+)
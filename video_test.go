@@ -0,0 +1,78 @@
+package gameboy
+
+import (
+	"bufio"
+	"bytes"
+	"image/color"
+	"image/gif"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVideoWriter(t *testing.T) {
+	frames := make([]Frame, 6)
+	for i := range frames {
+		frames[i][0] = uint8(i % 4)
+	}
+	frames[4] = frames[3] // unchanged
+
+	t.Run("gif", func(t *testing.T) {
+		req := require.New(t)
+		var b bytes.Buffer
+		w, err := NewVideoWriter(&b, "bug.GIF", VideoOptions{Scale: 2, Palette: &PaletteGreen})
+		req.NoError(err)
+		for _, f := range frames {
+			req.NoError(w.WriteFrame(f))
+		}
+		req.NoError(w.Close())
+
+		anim, err := gif.DecodeAll(&b)
+		req.NoError(err)
+		// the frames start at 0, 2, 3, 5, 7 and 8 hundredths: frame 2 is too
+		// soon after frame 1, and frame 4 is the same as frame 3
+		req.Len(anim.Image, 4)
+		req.Equal([]int{2, 3, 3, 2}, anim.Delay)
+		img := anim.Image[1]
+		req.Equal(SCREEN_WIDTH*2, img.Bounds().Dx())
+		req.Equal(color.RGBAModel.Convert(PaletteGreen[1]), color.RGBAModel.Convert(img.At(1, 1)))
+		req.Equal(color.RGBAModel.Convert(PaletteGreen[0]), color.RGBAModel.Convert(img.At(2, 0)))
+	})
+
+	t.Run("y4m", func(t *testing.T) {
+		req := require.New(t)
+		var b bytes.Buffer
+		w, err := NewVideoWriter(&b, "bug.y4m", VideoOptions{})
+		req.NoError(err)
+		for _, f := range frames {
+			req.NoError(w.WriteFrame(f))
+		}
+		req.NoError(w.Close())
+
+		r := bufio.NewReader(&b)
+		header, err := r.ReadString('\n')
+		req.NoError(err)
+		req.Equal("YUV4MPEG2 W160 H144 F4194304:70224 Ip A1:1 C444\n", header)
+		size := len(Frame{}) * 3
+		for i := range frames {
+			line, err := r.ReadString('\n')
+			req.NoError(err)
+			req.Equal("FRAME\n", line)
+			data := make([]byte, size)
+			_, err = io.ReadFull(r, data)
+			req.NoError(err)
+			// luma of the first pixel, then of white, and gray has no color
+			req.Equal([]byte{235, 162, 89, 16}[frames[i][0]], data[0], "frame %d", i)
+			req.Equal(byte(235), data[1])
+			req.Equal(byte(128), data[len(Frame{})])
+		}
+		_, err = r.ReadByte()
+		req.ErrorIs(err, io.EOF)
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		_, err := NewVideoWriter(io.Discard, "bug.mp4", VideoOptions{})
+		require.ErrorIs(t, err, ErrVideoFormat)
+	})
+}